@@ -65,17 +65,23 @@ func main() {
 
 func runServer(config *cli.Config) error {
 	// Criar watcher
-	var w *watcher.ImageWatcher
-	var err error
-
-	if config.SlideshowCount > 0 {
-		w, err = watcher.NewWithSlideshowCount(config.Directory, config.SlideshowCount)
-	} else {
-		w, err = watcher.New(config.Directory)
-	}
-
+	w, err := watcher.NewWithSources(config.Directories, watcher.Options{
+		SlideshowCount: config.SlideshowCount,
+		Recursive:      config.Recursive,
+		MaxDepth:       config.MaxDepth,
+		Settle:         config.Settle,
+		Verify:         config.Verify,
+		Include:        config.Include,
+		Exclude:        config.Exclude,
+		Extensions:     config.Extensions,
+		PollInterval:   config.Poll,
+		Order:          watcher.Order(config.Order),
+		Sniff:          config.Sniff,
+		FollowRoot:     config.FollowRoot,
+		FollowSymlinks: config.FollowSymlinks,
+	})
 	if err != nil {
-		return fmt.Errorf("diretório inválido: %s", config.Directory)
+		return err
 	}
 
 	// Verificar atualizações em background
@@ -87,20 +93,40 @@ func runServer(config *cli.Config) error {
 		return fmt.Errorf("erro ao escanear diretório: %w", err)
 	}
 
+	if sources := w.Sources(); len(sources) > 1 {
+		for _, src := range sources {
+			fmt.Printf("%s  %s → %s%s\n", colorDim, src.Name, src.Dir, colorReset)
+		}
+	}
+
 	if count > 0 {
 		fmt.Printf("%sℹ %d imagem(ns) encontrada(s)%s\n", colorBlue, count, colorReset)
 	} else {
 		fmt.Printf("%sℹ Nenhuma imagem encontrada. Aguardando...%s\n", colorBlue, colorReset)
 	}
 
+	// Exibir problemas de monitoramento no terminal
+	w.OnHealthChanged = printHealth
+
 	// Iniciar watcher
 	if err := w.Start(); err != nil {
 		return fmt.Errorf("erro ao iniciar monitoramento: %w", err)
 	}
 	defer w.Stop()
 
+	if w.Polling() && config.Poll == 0 {
+		fmt.Printf("%s⚠ Notificações do sistema indisponíveis, usando polling a cada %s%s\n",
+			colorYellow, watcher.DefaultPollInterval, colorReset)
+	}
+
 	// Iniciar servidor
-	srv := server.New(w, config.Port, config.SlideshowInterval)
+	srv := server.NewWithOptions(w, server.Options{
+		Port:              config.Port,
+		Bind:              config.Bind,
+		SlideshowInterval: config.SlideshowInterval,
+		ThumbCacheSize:    config.ThumbCacheSize,
+		SanitizeSVG:       config.SanitizeSVG,
+	})
 	if err := srv.Start(); err != nil {
 		return err
 	}
@@ -110,6 +136,15 @@ func runServer(config *cli.Config) error {
 	fmt.Println()
 	fmt.Printf("%s%s🖼  sidelook rodando%s\n", colorBold, colorGreen, colorReset)
 	fmt.Printf("%s   %s%s\n", colorDim, srv.URL(), colorReset)
+	if urls := srv.LANURLs(); len(urls) > 0 {
+		fmt.Printf("%s   Na rede local:%s\n", colorDim, colorReset)
+		for _, u := range urls {
+			fmt.Printf("%s   %s%s\n", colorDim, u, colorReset)
+		}
+	}
+	if srv.Token() != "" {
+		fmt.Printf("%s🔒 Acesso protegido por token: compartilhe a URL completa%s\n", colorYellow, colorReset)
+	}
 	fmt.Println()
 
 	// Abrir navegador
@@ -134,6 +169,19 @@ func runServer(config *cli.Config) error {
 	return nil
 }
 
+func printHealth(st watcher.Status) {
+	switch st.Health {
+	case watcher.HealthRescan:
+		fmt.Printf("%s↻ Reescaneando diretórios (%s)%s\n", colorYellow, st.Message, colorReset)
+	case watcher.HealthWaiting:
+		fmt.Printf("%s⏳ Aguardando diretório (%s)%s\n", colorYellow, st.Message, colorReset)
+	case watcher.HealthDegraded:
+		fmt.Printf("%s⚠ Monitoramento degradado: %s%s\n", colorYellow, st.Message, colorReset)
+	default:
+		fmt.Printf("%s✓ Monitoramento normalizado%s\n", colorGreen, colorReset)
+	}
+}
+
 func printUpdateAvailable(current, latest string) {
 	fmt.Println()
 	fmt.Printf("%s━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━%s\n", colorCyan, colorReset)
@@ -2,34 +2,57 @@
 package assets
 
 import (
-	"fmt"
-	"strings"
+	"html/template"
+	"io"
 )
 
-// GenerateHTML gera o HTML completo da página do visualizador
-func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterval int) string {
-	imageDisplay := `<div id="waiting">Aguardando primeira imagem...</div>`
-	if initialImage != "" {
-		imageDisplay = fmt.Sprintf(`<img id="viewer" src="/image/%s" alt="Imagem">`, initialImage)
-	}
+// ViewerState é o estado inicial da página do visualizador, serializado como
+// JSON dentro do script. Caminhos de arquivo só chegam à página por aqui e
+// pelas mensagens WebSocket, nunca concatenados no HTML.
+type ViewerState struct {
+	InitialImage string            `json:"initialImage"`
+	Slideshow    []string          `json:"slideshow"`
+	Interval     int               `json:"interval"` // Em segundos
+	Sources      []string          `json:"sources"`  // Nomes dos diretórios monitorados
+	Versions     map[string]string `json:"versions"` // Caminho -> token de versão (?v=)
+	Metas        any               `json:"metas"`    // Caminho -> metadados
+
+	// Nonce autoriza o script e o estilo da página na Content-Security-Policy
+	// da resposta (não vai para o JavaScript)
+	Nonce string `json:"-"`
+}
 
-	// Serializar imagens do slideshow para JavaScript
-	slideshowJSON := "[]"
-	if len(slideshowImages) > 0 {
-		var parts []string
-		for _, img := range slideshowImages {
-			parts = append(parts, fmt.Sprintf(`"%s"`, img))
-		}
-		slideshowJSON = "[" + strings.Join(parts, ",") + "]"
+// viewerTemplate é a página do visualizador. html/template escapa cada valor
+// conforme o contexto (HTML, atributo, JavaScript), então nomes de arquivo
+// com aspas, barras invertidas ou "</script>" não quebram a página.
+var viewerTemplate = template.Must(template.New("viewer").Parse(viewerHTML))
+
+// WriteHTML escreve a página do visualizador com o estado inicial informado.
+// Com mais de um diretório em Sources, a página exibe a origem de cada imagem.
+func WriteHTML(w io.Writer, state ViewerState) error {
+	// Listas e mapas vazios viram [] e {} no JavaScript, não null
+	if state.Slideshow == nil {
+		state.Slideshow = []string{}
+	}
+	if state.Sources == nil {
+		state.Sources = []string{}
 	}
+	if state.Versions == nil {
+		state.Versions = map[string]string{}
+	}
+	if state.Metas == nil {
+		state.Metas = map[string]any{}
+	}
+	return viewerTemplate.Execute(w, state)
+}
 
-	return fmt.Sprintf(`<!DOCTYPE html>
+const viewerHTML = `<!DOCTYPE html>
 <html lang="pt-BR">
 <head>
   <meta charset="UTF-8">
   <meta name="viewport" content="width=device-width, initial-scale=1.0">
   <title>sidelook</title>
-  <style>
+  <style nonce="{{.Nonce}}">
     * {
       margin: 0;
       padding: 0;
@@ -37,15 +60,15 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
     }
 
     html, body {
-      width: 100%%;
-      height: 100%%;
+      width: 100%;
+      height: 100%;
       background: #000;
       overflow: hidden;
     }
 
     #container {
-      width: 100%%;
-      height: 100%%;
+      width: 100%;
+      height: 100%;
       display: flex;
       justify-content: center;
       align-items: center;
@@ -53,8 +76,8 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
     }
 
     #viewer {
-      max-width: 100%%;
-      max-height: 100%%;
+      max-width: 100%;
+      max-height: 100%;
       object-fit: contain;
       opacity: 1;
       transform: scale(1);
@@ -86,6 +109,136 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
       transition: opacity 0.3s;
     }
 
+    #source {
+      position: fixed;
+      top: 10px;
+      left: 10px;
+      padding: 5px 10px;
+      border-radius: 4px;
+      font-family: monospace;
+      font-size: 12px;
+      background: rgba(0, 0, 0, 0.6);
+      color: #ccc;
+      opacity: 0.7;
+      display: none;
+    }
+
+    #source.visible {
+      display: block;
+    }
+
+    #info {
+      position: fixed;
+      top: 10px;
+      right: 10px;
+      max-width: 40%;
+      padding: 8px 12px;
+      border-radius: 4px;
+      font-family: monospace;
+      font-size: 12px;
+      line-height: 1.5;
+      background: rgba(0, 0, 0, 0.75);
+      color: #ddd;
+      display: none;
+    }
+
+    #info.visible {
+      display: block;
+    }
+
+    #info dt {
+      float: left;
+      clear: left;
+      width: 7em;
+      color: #888;
+    }
+
+    #info dl {
+      margin: 0;
+    }
+
+    #info dd {
+      margin-left: 7em;
+      overflow-wrap: anywhere;
+    }
+
+    #info details {
+      margin-top: 6px;
+      max-height: 50vh;
+      overflow-y: auto;
+    }
+
+    #info summary {
+      cursor: pointer;
+      color: #888;
+    }
+
+    #info details dt {
+      width: 14em;
+    }
+
+    #info details dd {
+      margin-left: 14em;
+    }
+
+    #params {
+      position: fixed;
+      bottom: 10px;
+      left: 10px;
+      max-width: 50%;
+      border-radius: 4px;
+      font-family: monospace;
+      font-size: 12px;
+      background: rgba(0, 0, 0, 0.75);
+      color: #ddd;
+      display: none;
+    }
+
+    #params.visible {
+      display: block;
+    }
+
+    #params-toggle {
+      padding: 5px 10px;
+      border: none;
+      background: none;
+      color: #ccc;
+      font: inherit;
+      cursor: pointer;
+      text-align: left;
+    }
+
+    #params-body {
+      display: none;
+      max-height: 60vh;
+      overflow-y: auto;
+      padding: 0 10px 8px;
+    }
+
+    #params.expanded #params-body {
+      display: block;
+    }
+
+    #params .params-key {
+      display: flex;
+      justify-content: space-between;
+      align-items: center;
+      margin-top: 6px;
+      color: #888;
+    }
+
+    #params .params-key button {
+      font: inherit;
+      padding: 1px 8px;
+      cursor: pointer;
+    }
+
+    #params pre {
+      margin: 4px 0 0;
+      white-space: pre-wrap;
+      overflow-wrap: anywhere;
+    }
+
     #status:hover {
       opacity: 1;
     }
@@ -100,6 +253,11 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
       color: #f87171;
     }
 
+    #status.degraded {
+      background: #4a3a1a;
+      color: #fbbf24;
+    }
+
     :fullscreen #container,
     :-webkit-full-screen #container {
       background: #000;
@@ -107,14 +265,71 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
   </style>
 </head>
 <body>
-  <div id="container" onclick="toggleFullscreen()">
-    %s
+  <div id="container">
+    {{if .InitialImage}}
+    <img id="viewer" alt="Imagem">
+    {{else}}
+    <div id="waiting">Aguardando primeira imagem...</div>
+    {{end}}
+  </div>
+  <div id="source"></div>
+  <div id="info"></div>
+  <div id="params">
+    <button id="params-toggle"></button>
+    <div id="params-body"></div>
   </div>
   <div id="status" class="disconnected">Desconectado</div>
 
-  <script>
+  <script nonce="{{.Nonce}}">
+    // Estado inicial serializado pelo servidor (JSON escapado pelo template)
+    const state = {{.}};
+
     const container = document.getElementById('container');
     const status = document.getElementById('status');
+    const sourceLabel = document.getElementById('source');
+    const sources = state.sources;
+    const info = document.getElementById('info');
+    const params = document.getElementById('params');
+    const paramsToggle = document.getElementById('params-toggle');
+    const paramsBody = document.getElementById('params-body');
+
+    // Com vários diretórios, o primeiro segmento do caminho é a origem
+    function showSource(imagePath) {
+      if (sources.length <= 1 || !imagePath) {
+        sourceLabel.classList.remove('visible');
+        return;
+      }
+      sourceLabel.textContent = imagePath.split('/')[0];
+      sourceLabel.classList.add('visible');
+    }
+
+    // Conexão com o servidor e saúde do watcher (ok, degraded, rescan)
+    let connected = false;
+    let watcherHealth = { status: 'ok', message: '' };
+
+    function renderStatus() {
+      if (!connected) {
+        status.textContent = 'Desconectado';
+        status.className = 'disconnected';
+        status.title = '';
+        return;
+      }
+      if (watcherHealth.status === 'rescan') {
+        status.textContent = 'Reescaneando...';
+        status.className = 'degraded';
+      } else if (watcherHealth.status === 'waiting') {
+        status.textContent = 'Aguardando diretório...';
+        status.className = 'degraded';
+      } else if (watcherHealth.status === 'degraded') {
+        status.textContent = 'Monitoramento degradado';
+        status.className = 'degraded';
+      } else {
+        status.textContent = 'Conectado';
+        status.className = 'connected';
+      }
+      status.title = watcherHealth.message || '';
+    }
+
     let ws;
     let reconnectAttempts = 0;
     const maxReconnectAttempts = 10;
@@ -126,28 +341,40 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
 
       ws.onopen = () => {
         console.log('WebSocket conectado');
-        status.textContent = 'Conectado';
-        status.className = 'connected';
+        connected = true;
+        watcherHealth = { status: 'ok', message: '' };
+        renderStatus();
         reconnectAttempts = 0;
       };
 
       ws.onmessage = (event) => {
         const data = JSON.parse(event.data);
         if (data.type === 'new_image') {
+          setVersion(data.path, data.version);
+          setMeta(data.path, data.meta);
           updateImage(data.path);
         } else if (data.type === 'image_deleted') {
+          setVersion(data.path, data.version);
+          setMeta(data.path, data.meta);
           if (data.path) {
             updateImage(data.path);
           } else {
             showWaiting();
           }
+        } else if (data.type === 'slideshow_update') {
+          Object.assign(versions, data.versions || {});
+          Object.assign(metas, data.metas || {});
+          updateSlideshow(data.images || []);
+        } else if (data.type === 'status') {
+          watcherHealth = { status: data.status, message: data.message };
+          renderStatus();
         }
       };
 
       ws.onclose = () => {
         console.log('WebSocket desconectado');
-        status.textContent = 'Desconectado';
-        status.className = 'disconnected';
+        connected = false;
+        renderStatus();
         scheduleReconnect();
       };
 
@@ -168,6 +395,10 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
       const current = document.getElementById('viewer');
       const waiting = document.getElementById('waiting');
 
+      showSource(null);
+      renderInfo();
+      renderParams();
+
       if (waiting) {
         return; // Já está mostrando
       }
@@ -189,9 +420,317 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
       }
     }
 
+    // URLs levam a versão do arquivo (?v=): uma imagem modificada ganha URL
+    // nova e uma inalterada vem do cache do navegador (ou de um 304)
+    function imageURL(imagePath) {
+      let url = sizedURL(imagePath);
+      if (versions[imagePath]) {
+        url += (url.includes('?') ? '&' : '?') + 'v=' + encodeURIComponent(versions[imagePath]);
+      }
+      return url;
+    }
+
+    function setVersion(imagePath, version) {
+      if (imagePath && version) {
+        versions[imagePath] = version;
+      }
+    }
+
+    function setMeta(imagePath, meta) {
+      if (imagePath && meta) {
+        metas[imagePath] = meta;
+      }
+    }
+
+    // Com as dimensões conhecidas, o elemento ocupa o espaço da imagem antes
+    // de ela carregar (object-fit mantém a proporção dentro da tela)
+    function reserveSize(img, imagePath) {
+      const meta = metas[imagePath];
+      if (meta && meta.width && meta.height) {
+        img.width = meta.width;
+        img.height = meta.height;
+      }
+    }
+
+    function formatBytes(n) {
+      const units = ['B', 'KB', 'MB', 'GB'];
+      let i = 0;
+      while (n >= 1024 && i < units.length - 1) {
+        n /= 1024;
+        i++;
+      }
+      return (i === 0 ? n : n.toFixed(1)) + ' ' + units[i];
+    }
+
+    // Caminho com cada segmento escapado, para uso em URLs
+    function pathURL(imagePath) {
+      return imagePath.split('/').map(encodeURIComponent).join('/');
+    }
+
+    // Tags EXIF/XMP e textos embutidos, buscados na API só quando o painel
+    // correspondente está aberto e guardados por versão do arquivo
+    const exifData = {}; // caminho -> {version, data}
+    const textData = {}; // caminho -> {version, data}
+
+    // Retorna os dados em cache ou null, disparando a busca em
+    // /api/v1/images/<caminho>/<resource> e chamando render ao chegar
+    function loadResource(cache, imagePath, resource, render) {
+      const version = versions[imagePath] || '';
+      const cached = cache[imagePath];
+      if (cached && cached.version === version) {
+        return cached.data;
+      }
+      const entry = {version: version, data: null};
+      cache[imagePath] = entry;
+      fetch('/api/v1/images/' + pathURL(imagePath) + '/' + resource)
+        .then((resp) => resp.ok ? resp.json() : null)
+        .then((data) => {
+          entry.data = data;
+          if (data && imagePath === displayedPath) {
+            render();
+          }
+        })
+        .catch(() => {
+          // Tentar de novo na próxima vez que o painel for desenhado
+          if (cache[imagePath] === entry) {
+            delete cache[imagePath];
+          }
+        });
+      return null;
+    }
+
+    function appendRows(list, rows) {
+      rows.forEach(([label, value]) => {
+        const dt = document.createElement('dt');
+        dt.textContent = label;
+        const dd = document.createElement('dd');
+        dd.textContent = value;
+        list.append(dt, dd);
+      });
+    }
+
+    // Câmera, lente, exposição e data de captura, quando a foto os informa
+    function exifRows(data) {
+      const exif = data.exif || {};
+      const xmp = data.xmp || {};
+      const rows = [];
+      const camera = [exif.Make, exif.Model].filter(Boolean);
+      if (camera.length > 0) {
+        // Muitos fabricantes repetem a marca no modelo
+        rows.push(['Câmera', camera.length === 2 && camera[1].startsWith(camera[0]) ? camera[1] : camera.join(' ')]);
+      }
+      if (exif.LensModel) {
+        rows.push(['Lente', exif.LensModel]);
+      }
+      const exposure = [];
+      if (exif.ExposureTime) {
+        exposure.push(exif.ExposureTime + ' s');
+      }
+      if (exif.FNumber) {
+        exposure.push('f/' + exif.FNumber);
+      }
+      if (exif.ISOSpeedRatings) {
+        exposure.push('ISO ' + exif.ISOSpeedRatings);
+      }
+      if (exif.FocalLength) {
+        exposure.push(exif.FocalLength + ' mm');
+      }
+      if (exposure.length > 0) {
+        rows.push(['Exposição', exposure.join(' · ')]);
+      }
+      if (exif.DateTimeOriginal) {
+        rows.push(['Capturada', exif.DateTimeOriginal]);
+      }
+      if (data.gps) {
+        rows.push(['GPS', data.gps.latitude.toFixed(5) + ', ' + data.gps.longitude.toFixed(5)]);
+      }
+      if (xmp['dc:title']) {
+        rows.push(['Título', xmp['dc:title']]);
+      }
+      if (xmp['dc:creator'] || exif.Artist) {
+        rows.push(['Autor', xmp['dc:creator'] || exif.Artist]);
+      }
+      if (xmp['xmp:Rating']) {
+        rows.push(['Avaliação', xmp['xmp:Rating'] + '/5']);
+      }
+      return rows;
+    }
+
+    // Painel de informações (tecla I) da imagem exibida
+    function renderInfo() {
+      info.replaceChildren();
+      const meta = displayedPath && metas[displayedPath];
+      if (!meta) {
+        return;
+      }
+
+      const rows = [['Arquivo', displayedPath]];
+      if (meta.format) {
+        rows.push(['Formato', meta.format.toUpperCase()]);
+      }
+      if (meta.width && meta.height) {
+        rows.push(['Dimensões', meta.width + ' × ' + meta.height]);
+      }
+      if (meta.color_model) {
+        rows.push(['Cor', meta.color_model + (meta.depth ? ', ' + meta.depth + ' bits' : '')]);
+      }
+      if (meta.frames > 1) {
+        rows.push(['Quadros', String(meta.frames)]);
+      }
+      rows.push(['Tamanho', formatBytes(meta.size)]);
+      rows.push(['Modificado', new Date(meta.mtime).toLocaleString()]);
+
+      const data = info.classList.contains('visible') ? loadResource(exifData, displayedPath, 'exif', renderInfo) : null;
+      if (data) {
+        rows.push(...exifRows(data));
+      }
+
+      const list = document.createElement('dl');
+      appendRows(list, rows);
+      info.append(list);
+
+      // Todas as tags, recolhidas por padrão
+      if (data) {
+        const tags = Object.entries(data.exif || {}).concat(Object.entries(data.xmp || {}));
+        if (tags.length > 0) {
+          tags.sort((a, b) => a[0].localeCompare(b[0]));
+          const details = document.createElement('details');
+          const summary = document.createElement('summary');
+          summary.textContent = 'Todas as tags (' + tags.length + ')';
+          const all = document.createElement('dl');
+          appendRows(all, tags);
+          details.append(summary, all);
+          info.append(details);
+        }
+      }
+    }
+
+    // Textos embutidos (prompt, seed, workflow de imagens de IA): barra
+    // recolhida quando a imagem os tem, expandida com clique ou tecla P
+    function renderParams() {
+      const meta = displayedPath && metas[displayedPath];
+      const keys = (meta && meta.text) || [];
+      params.classList.toggle('visible', keys.length > 0);
+      if (keys.length === 0) {
+        paramsBody.replaceChildren();
+        return;
+      }
+
+      const expanded = params.classList.contains('expanded');
+      paramsToggle.textContent = (expanded ? '▾ ' : '▸ ') + 'Parâmetros (' + keys.join(', ') + ')';
+      paramsBody.replaceChildren();
+      if (!expanded) {
+        return;
+      }
+
+      const data = loadResource(textData, displayedPath, 'text', renderParams);
+      if (!data) {
+        paramsBody.textContent = 'Carregando...';
+        return;
+      }
+      data.text.forEach((entry) => {
+        const header = document.createElement('div');
+        header.className = 'params-key';
+        const label = document.createElement('span');
+        label.textContent = entry.key;
+        const copy = document.createElement('button');
+        copy.textContent = 'Copiar';
+        copy.addEventListener('click', () => copyText(entry.text, copy));
+        header.append(label, copy);
+
+        const pre = document.createElement('pre');
+        pre.textContent = prettyText(entry.text);
+        paramsBody.append(header, pre);
+      });
+    }
+
+    // Workflows e prompts do ComfyUI são JSON compacto: indentar para leitura
+    function prettyText(text) {
+      const trimmed = text.trim();
+      if (trimmed.startsWith('{') || trimmed.startsWith('[')) {
+        try {
+          return JSON.stringify(JSON.parse(trimmed), null, 2);
+        } catch (e) {
+          // Não é JSON: mostrar como está
+        }
+      }
+      return text;
+    }
+
+    // Copia o texto original; sem a API de clipboard (HTTP fora de
+    // localhost), usa uma textarea temporária
+    function copyText(text, button) {
+      const done = (ok) => {
+        button.textContent = ok ? 'Copiado' : 'Falhou';
+        setTimeout(() => { button.textContent = 'Copiar'; }, 1500);
+      };
+      if (navigator.clipboard && window.isSecureContext) {
+        navigator.clipboard.writeText(text).then(() => done(true), () => done(false));
+        return;
+      }
+      const area = document.createElement('textarea');
+      area.value = text;
+      document.body.appendChild(area);
+      area.select();
+      let ok = false;
+      try {
+        ok = document.execCommand('copy');
+      } catch (e) {
+        ok = false;
+      }
+      area.remove();
+      done(ok);
+    }
+
+    function toggleParams() {
+      params.classList.toggle('expanded');
+      renderParams();
+    }
+
+    // Tamanho pedido ao servidor: a tela em pixels físicos, arredondada para
+    // cima em passos de 256px para que tamanhos parecidos usem o mesmo cache
+    function screenSize() {
+      const dpr = window.devicePixelRatio || 1;
+      const step = 256;
+      return {
+        w: Math.ceil(window.innerWidth * dpr / step) * step,
+        h: Math.ceil(window.innerHeight * dpr / step) * step
+      };
+    }
+
+    let requestedSize = screenSize();
+
+    // Imagens que já cabem na tela são pedidas sem ?w=&h= e vêm originais
+    function sizedURL(imagePath) {
+      requestedSize = screenSize();
+      const url = '/image/' + pathURL(imagePath);
+      const meta = metas[imagePath];
+      if (meta && meta.width && meta.height && meta.width <= requestedSize.w && meta.height <= requestedSize.h) {
+        return url;
+      }
+      return url + '?w=' + requestedSize.w + '&h=' + requestedSize.h;
+    }
+
+    // Tela maior (rotação, tela cheia, zoom): pedir a imagem atual de novo
+    let resizeTimer = null;
+    window.addEventListener('resize', () => {
+      clearTimeout(resizeTimer);
+      resizeTimer = setTimeout(() => {
+        const size = screenSize();
+        const viewer = document.getElementById('viewer');
+        if (viewer && displayedPath && (size.w > requestedSize.w || size.h > requestedSize.h)) {
+          viewer.src = imageURL(displayedPath);
+        }
+      }, 300);
+    });
+
     function updateImage(imagePath) {
       const current = document.getElementById('viewer');
       const waiting = document.getElementById('waiting');
+      displayedPath = imagePath;
+      showSource(imagePath);
+      renderInfo();
+      renderParams();
 
       if (waiting) {
         waiting.remove();
@@ -205,7 +744,8 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
 
           const newImg = document.createElement('img');
           newImg.id = 'viewer';
-          newImg.src = '/image/' + imagePath + '?t=' + Date.now();
+          reserveSize(newImg, imagePath);
+          newImg.src = imageURL(imagePath);
           newImg.alt = 'Imagem';
           newImg.classList.add('fade-out');
 
@@ -223,7 +763,8 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
       } else {
         const newImg = document.createElement('img');
         newImg.id = 'viewer';
-        newImg.src = '/image/' + imagePath + '?t=' + Date.now();
+        reserveSize(newImg, imagePath);
+        newImg.src = imageURL(imagePath);
         newImg.alt = 'Imagem';
         container.appendChild(newImg);
       }
@@ -239,17 +780,78 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
       }
     }
 
+    // Sem atributos onclick: a Content-Security-Policy só permite o script com nonce
+    container.addEventListener('click', toggleFullscreen);
+    paramsToggle.addEventListener('click', toggleParams);
+
     document.addEventListener('keydown', (e) => {
       if (e.key === 'f' || e.key === 'F') {
         toggleFullscreen();
+      } else if (e.key === 'i' || e.key === 'I') {
+        info.classList.toggle('visible');
+        renderInfo();
+      } else if (e.key === 'p' || e.key === 'P') {
+        toggleParams();
       }
     });
 
     // Configuração de slideshow
-    const slideshowImages = %s;
-    const slideshowInterval = %d * 1000; // Converter para milissegundos
+    let slideshowImages = state.slideshow;
+    const slideshowInterval = state.interval * 1000; // Converter para milissegundos
     let slideshowTimer = null;
     let currentSlideshowIndex = 0;
+    let displayedPath = slideshowImages.length > 0 ? slideshowImages[0] : null;
+    const versions = state.versions; // caminho -> token de versão
+    const metas = state.metas; // caminho -> metadados (tamanho, dimensões, formato...)
+
+    // Com URLs versionadas, a imagem pré-carregada fica no cache do navegador
+    function preloadImage(imagePath) {
+      const img = new Image();
+      img.src = imageURL(imagePath);
+    }
+
+    // Mesclar a nova lista sem reiniciar a rotação do zero
+    function updateSlideshow(images) {
+      const known = new Set(slideshowImages);
+      images.forEach((imagePath) => {
+        if (!known.has(imagePath) && imagePath !== displayedPath) {
+          preloadImage(imagePath);
+        }
+      });
+
+      // Descartar versões e metadados de imagens que saíram da lista
+      const next = new Set(images);
+      Object.keys(versions).concat(Object.keys(metas), Object.keys(exifData), Object.keys(textData)).forEach((imagePath) => {
+        if (!next.has(imagePath) && imagePath !== displayedPath) {
+          delete versions[imagePath];
+          delete metas[imagePath];
+          delete exifData[imagePath];
+          delete textData[imagePath];
+        }
+      });
+
+      const previousIndex = currentSlideshowIndex;
+      slideshowImages = images;
+
+      // Continuar a partir da imagem exibida; se ela saiu da lista, a próxima
+      // troca mostra a imagem que ocupou a sua posição
+      const idx = slideshowImages.indexOf(displayedPath);
+      if (idx >= 0) {
+        currentSlideshowIndex = idx;
+      } else if (slideshowImages.length > 0) {
+        currentSlideshowIndex = (Math.min(previousIndex, slideshowImages.length) - 1 + slideshowImages.length) % slideshowImages.length;
+      } else {
+        currentSlideshowIndex = 0;
+      }
+
+      if (slideshowImages.length > 1) {
+        if (!slideshowTimer) {
+          startSlideshow();
+        }
+      } else {
+        stopSlideshow();
+      }
+    }
 
     function startSlideshow() {
       if (slideshowImages.length <= 1) {
@@ -259,7 +861,7 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
       stopSlideshow();
 
       slideshowTimer = setInterval(() => {
-        currentSlideshowIndex = (currentSlideshowIndex + 1) %% slideshowImages.length;
+        currentSlideshowIndex = (currentSlideshowIndex + 1) % slideshowImages.length;
         updateImage(slideshowImages[currentSlideshowIndex]);
       }, slideshowInterval);
     }
@@ -276,9 +878,18 @@ func GenerateHTML(initialImage string, slideshowImages []string, slideshowInterv
       startSlideshow();
     }
 
+    const initialImage = state.initialImage;
+    showSource(initialImage);
+    const initialViewer = document.getElementById('viewer');
+    if (initialViewer && initialImage) {
+      displayedPath = initialImage;
+      reserveSize(initialViewer, initialImage);
+      initialViewer.src = imageURL(initialImage);
+      renderInfo();
+      renderParams();
+    }
     connect();
   </script>
 </body>
 </html>
-`, imageDisplay, slideshowJSON, slideshowInterval)
-}
+`
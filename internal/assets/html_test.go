@@ -0,0 +1,102 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hostileNames são nomes de arquivo válidos no Linux que quebrariam uma
+// página montada por concatenação
+var hostileNames = []string{
+	`"quote".png`,
+	`</script><script>alert(1)</script>.png`,
+	`back\slash\"x.png`,
+	`it's & <b>bold</b>.png`,
+	`<!--comment.png`,
+	"line\u2028sep.png",
+	`${alert(1)}.png`,
+}
+
+// pageState extrai e decodifica o estado inicial serializado no script
+func pageState(t *testing.T, page string) ViewerState {
+	t.Helper()
+	const marker = "const state = "
+	i := strings.Index(page, marker)
+	if i < 0 {
+		t.Fatal("página sem o estado inicial")
+	}
+	rest := page[i+len(marker):]
+	end := strings.Index(rest, ";\n")
+	if end < 0 {
+		t.Fatal("estado inicial sem terminação")
+	}
+
+	var state ViewerState
+	if err := json.Unmarshal([]byte(rest[:end]), &state); err != nil {
+		t.Fatalf("estado inicial não é JSON válido: %v\n%s", err, rest[:end])
+	}
+	return state
+}
+
+func TestWriteHTML_HostileNames(t *testing.T) {
+	versions := make(map[string]string)
+	for _, name := range hostileNames {
+		versions[name] = `v"1</script>`
+	}
+	state := ViewerState{
+		InitialImage: hostileNames[1],
+		Slideshow:    hostileNames,
+		Interval:     5,
+		Sources:      []string{`dir"</script>`, "outro"},
+		Versions:     versions,
+		Metas:        map[string]any{hostileNames[1]: map[string]any{"format": "<svg onload=alert(1)>"}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, state); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	page := buf.String()
+
+	// Um único script, sem nenhum trecho dos nomes escapando para o HTML
+	if n := strings.Count(page, "<script"); n != 1 {
+		t.Errorf("página tem %d tags <script>, want 1", n)
+	}
+	if n := strings.Count(page, "</script>"); n != 1 {
+		t.Errorf("página tem %d </script>, want 1", n)
+	}
+	for _, bad := range []string{"alert(1)</script>", "<b>bold", "<!--comment", "<svg", "\u2028"} {
+		if strings.Contains(page, bad) {
+			t.Errorf("página contém %q sem escapar", bad)
+		}
+	}
+
+	// O estado chega intacto ao JavaScript
+	got := pageState(t, page)
+	want := state
+	want.Metas = got.Metas
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("estado = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteHTML_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, ViewerState{Interval: 5}); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	page := buf.String()
+
+	if !strings.Contains(page, `<div id="waiting">`) || strings.Contains(page, `id="viewer"`) {
+		t.Error("sem imagem inicial, a página deveria mostrar a espera")
+	}
+	// Listas e mapas vazios, não null
+	for _, want := range []string{`"slideshow":[]`, `"sources":[]`, `"versions":{}`, `"metas":{}`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("estado sem %s", want)
+		}
+	}
+}
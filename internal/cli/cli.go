@@ -4,19 +4,30 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"net"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/verseles/sidelook/internal/thumb"
 	"github.com/verseles/sidelook/internal/version"
+	"github.com/verseles/sidelook/internal/watcher"
 )
 
 // Config contém a configuração parseada dos argumentos CLI
 type Config struct {
-	// Directory é o diretório a monitorar
-	Directory string
+	// Directories são os diretórios a monitorar
+	Directories []string
 
 	// Port é a porta especificada (0 = auto)
 	Port int
 
+	// Bind é o endereço de escuta (vazio = 127.0.0.1). Fora do loopback, o
+	// acesso exige um token gerado ao iniciar.
+	Bind string
+
 	// Update indica se deve executar atualização
 	Update bool
 
@@ -31,21 +42,137 @@ type Config struct {
 
 	// SlideshowInterval é o intervalo em segundos entre transições (padrão: 3)
 	SlideshowInterval int
+
+	// Recursive indica se subdiretórios também devem ser monitorados
+	Recursive bool
+
+	// MaxDepth limita a profundidade dos subdiretórios (0 = ilimitado)
+	MaxDepth int
+
+	// Settle é o tempo que um arquivo deve ficar sem mudanças antes de ser exibido
+	Settle time.Duration
+
+	// Verify exige que o arquivo esteja completo (trailer válido) antes de ser exibido
+	Verify bool
+
+	// Include são globs de arquivos a exibir (vazio = todos)
+	Include []string
+
+	// Exclude são globs de arquivos a ignorar
+	Exclude []string
+
+	// Extensions são extensões de imagem extras (ex: avif, jxl)
+	Extensions []string
+
+	// Poll é o intervalo de polling (0 = notificações do sistema)
+	Poll time.Duration
+
+	// Order é o critério de "mais recente" (mtime, ctime, arrival, name, natural, exif-date)
+	Order string
+
+	// Sniff identifica imagens pelo conteúdo (magic bytes) em vez da extensão
+	Sniff bool
+
+	// FollowRoot segue um diretório monitorado renomeado dentro da mesma pasta pai
+	FollowRoot bool
+
+	// FollowSymlinks aceita links simbólicos que apontam para fora dos diretórios monitorados
+	FollowSymlinks bool
+
+	// ThumbCacheSize limita o cache de miniaturas em disco, em bytes
+	ThumbCacheSize int64
+
+	// SanitizeSVG remove scripts e atributos de evento dos SVGs servidos
+	SanitizeSVG bool
+}
+
+// stringList é uma flag repetível (--include a --include b)
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// csvList é uma flag repetível que também aceita valores separados por vírgula
+type csvList []string
+
+func (l *csvList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *csvList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// byteSize é uma flag de tamanho em bytes com sufixo opcional (512MB, 1G)
+type byteSize int64
+
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	v = strings.TrimSuffix(v, "B")
+
+	mult := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		mult   int64
+	}{{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}} {
+		if strings.HasSuffix(v, unit.suffix) {
+			v, mult = strings.TrimSuffix(v, unit.suffix), unit.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("tamanho inválido: %q (ex: 256MB, 1GB)", value)
+	}
+	*b = byteSize(n * mult)
+	return nil
 }
 
 // Parse faz o parse dos argumentos de linha de comando
 func Parse(args []string) (*Config, error) {
-	cfg := &Config{}
+	cfg := &Config{ThumbCacheSize: thumb.DefaultMaxBytes}
 
 	fs := flag.NewFlagSet("sidelook", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
 	fs.IntVar(&cfg.Port, "p", 0, "Porta do servidor HTTP (padrão: 8080)")
 	fs.IntVar(&cfg.Port, "port", 0, "Porta do servidor HTTP (padrão: 8080)")
+	fs.StringVar(&cfg.Bind, "bind", "", "Endereço de escuta (ex: 0.0.0.0 ou :: para a rede local, com token)")
 	fs.IntVar(&cfg.SlideshowCount, "s", 0, "Número de imagens no slideshow (0 = desabilitado)")
 	fs.IntVar(&cfg.SlideshowCount, "slideshow", 0, "Número de imagens no slideshow (0 = desabilitado)")
 	fs.IntVar(&cfg.SlideshowInterval, "t", 3, "Intervalo em segundos entre imagens (padrão: 3)")
 	fs.IntVar(&cfg.SlideshowInterval, "time", 3, "Intervalo em segundos entre imagens (padrão: 3)")
+	fs.BoolVar(&cfg.Recursive, "r", false, "Monitorar subdiretórios recursivamente")
+	fs.BoolVar(&cfg.Recursive, "recursive", false, "Monitorar subdiretórios recursivamente")
+	fs.IntVar(&cfg.MaxDepth, "max-depth", 0, "Profundidade máxima de subdiretórios (0 = ilimitado)")
+	fs.DurationVar(&cfg.Settle, "settle", 300*time.Millisecond, "Tempo sem mudanças antes de exibir uma imagem (0 = imediato)")
+	fs.BoolVar(&cfg.Verify, "verify", false, "Exibir apenas imagens completas (PNG IEND, JPEG EOI...)")
+	fs.Var((*stringList)(&cfg.Include), "include", "Exibir apenas arquivos que casam com o glob (repetível)")
+	fs.Var((*stringList)(&cfg.Exclude), "exclude", "Ignorar arquivos que casam com o glob (repetível)")
+	fs.Var((*csvList)(&cfg.Extensions), "ext", "Extensões de imagem extras, separadas por vírgula (ex: avif,jxl)")
+	fs.DurationVar(&cfg.Poll, "poll", 0, "Usar polling com o intervalo informado (para NFS, SMB, sshfs)")
+	fs.StringVar(&cfg.Order, "order", "mtime", "Critério de mais recente: mtime, ctime, arrival, name, natural, exif-date")
+	fs.BoolVar(&cfg.Sniff, "sniff", false, "Identificar imagens pelo conteúdo em vez da extensão")
+	fs.BoolVar(&cfg.FollowRoot, "follow-root", false, "Seguir o diretório monitorado se ele for renomeado")
+	fs.BoolVar(&cfg.FollowSymlinks, "follow-symlinks", false, "Exibir links simbólicos que apontam para fora do diretório")
+	fs.Var((*byteSize)(&cfg.ThumbCacheSize), "thumb-cache", "Tamanho máximo do cache de miniaturas em disco (padrão: 256MB)")
+	fs.BoolVar(&cfg.SanitizeSVG, "sanitize-svg", false, "Remover scripts e atributos de evento dos SVGs servidos")
 	fs.BoolVar(&cfg.Update, "u", false, "Atualizar para a versão mais recente")
 	fs.BoolVar(&cfg.Update, "update", false, "Atualizar para a versão mais recente")
 	fs.BoolVar(&cfg.ShowVersion, "v", false, "Exibir versão atual")
@@ -62,11 +189,11 @@ func Parse(args []string) (*Config, error) {
 		return nil, err
 	}
 
-	// Diretório é o primeiro argumento posicional
+	// Diretórios são os argumentos posicionais
 	if fs.NArg() > 0 {
-		cfg.Directory = fs.Arg(0)
+		cfg.Directories = fs.Args()
 	} else {
-		cfg.Directory = "."
+		cfg.Directories = []string{"."}
 	}
 
 	// Validar porta
@@ -74,6 +201,14 @@ func Parse(args []string) (*Config, error) {
 		return nil, fmt.Errorf("porta inválida: %d. Use um número entre 1 e 65535", cfg.Port)
 	}
 
+	// Validar endereço (IPv6 pode vir entre colchetes)
+	if cfg.Bind != "" {
+		cfg.Bind = strings.TrimSuffix(strings.TrimPrefix(cfg.Bind, "["), "]")
+		if cfg.Bind != "localhost" && net.ParseIP(cfg.Bind) == nil {
+			return nil, fmt.Errorf("endereço inválido: %s. Use um IP (ex: 0.0.0.0, ::, 192.168.0.10) ou localhost", cfg.Bind)
+		}
+	}
+
 	// Validar slideshow
 	if cfg.SlideshowCount < 0 {
 		return nil, fmt.Errorf("número de imagens no slideshow inválido: %d. Use um número >= 0", cfg.SlideshowCount)
@@ -82,6 +217,36 @@ func Parse(args []string) (*Config, error) {
 		return nil, fmt.Errorf("intervalo de slideshow inválido: %d. Use um número >= 1", cfg.SlideshowInterval)
 	}
 
+	// Validar profundidade (--max-depth implica --recursive)
+	if cfg.MaxDepth < 0 {
+		return nil, fmt.Errorf("profundidade máxima inválida: %d. Use um número >= 0", cfg.MaxDepth)
+	}
+	if cfg.MaxDepth > 0 {
+		cfg.Recursive = true
+	}
+
+	// Validar janela de estabilização
+	if cfg.Settle < 0 {
+		return nil, fmt.Errorf("tempo de estabilização inválido: %s. Use um valor >= 0", cfg.Settle)
+	}
+
+	// Validar polling
+	if cfg.Poll < 0 {
+		return nil, fmt.Errorf("intervalo de polling inválido: %s. Use um valor >= 0", cfg.Poll)
+	}
+
+	// Validar ordenação
+	if _, err := watcher.ParseOrder(cfg.Order); err != nil {
+		return nil, err
+	}
+
+	// Validar globs
+	for _, pattern := range append(append([]string{}, cfg.Include...), cfg.Exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("padrão inválido: %q", pattern)
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -89,12 +254,30 @@ func Parse(args []string) (*Config, error) {
 func Usage() string {
 	return fmt.Sprintf(`sidelook %s - Visualizador de imagens em tempo real
 
-Uso: sidelook [opções] [diretório]
+Uso: sidelook [opções] [diretório...]
 
 Opções:
   -p, --port <número>       Porta do servidor HTTP (padrão: 8080)
+      --bind <endereço>     Endereço de escuta (padrão: 127.0.0.1); 0.0.0.0 ou ::
+                            compartilham na rede local, com token de acesso
   -s, --slideshow <número>  Número de imagens no slideshow (0 = desabilitado)
   -t, --time <segundos>     Intervalo entre imagens no slideshow (padrão: 3)
+  -r, --recursive           Monitorar subdiretórios recursivamente
+      --max-depth <número>  Profundidade máxima de subdiretórios (0 = ilimitado)
+      --settle <duração>    Tempo sem mudanças antes de exibir (padrão: 300ms)
+      --verify              Exibir apenas imagens completas (PNG IEND, JPEG EOI)
+      --include <glob>      Exibir apenas arquivos que casam com o glob (repetível)
+      --exclude <glob>      Ignorar arquivos que casam com o glob (repetível)
+      --ext <lista>         Extensões extras, separadas por vírgula (ex: avif,jxl)
+      --poll <duração>      Usar polling em vez de notificações (ex: 2s, para NFS/SMB)
+      --order <critério>    Critério de mais recente: mtime, ctime, arrival, name,
+                            natural, exif-date (padrão: mtime)
+      --sniff               Identificar imagens pelo conteúdo em vez da extensão
+      --follow-root         Seguir o diretório monitorado se ele for renomeado
+      --follow-symlinks     Exibir links simbólicos que apontam para fora do diretório
+      --thumb-cache <tamanho>
+                            Limite do cache de miniaturas em disco (padrão: 256MB)
+      --sanitize-svg        Remover scripts e atributos de evento dos SVGs servidos
   -u, --update              Atualizar para a versão mais recente
   -v, --version             Exibir versão atual
   -h, --help                Exibir esta ajuda
@@ -102,10 +285,22 @@ Opções:
 Exemplos:
   sidelook                       # Monitora diretório atual (última imagem)
   sidelook ~/Downloads           # Monitora pasta Downloads
+  sidelook ./renders ./screenshots ~/Downloads  # Várias pastas em uma só linha do tempo
   sidelook -p 3000               # Usa porta 3000
+  sidelook --bind 0.0.0.0        # Compartilha na rede local (com token)
   sidelook -s 4                  # Slideshow com 4 últimas imagens (3s cada)
   sidelook -s 4 -t 2             # Slideshow com 4 imagens (2s cada)
   sidelook --slideshow 10 --time 5  # Slideshow com 10 imagens (5s cada)
+  sidelook -r out                # Inclui subpastas (ex: out/2026-10-16/run-3)
+  sidelook -r --max-depth 2 out  # Subpastas até 2 níveis abaixo de out
+  sidelook --settle 1s --verify  # Aguarda renders grandes terminarem de gravar
+  sidelook --include '*_final.png'  # Apenas renders finais
+  sidelook --exclude '*_mask.png' --ext avif,jxl  # Sem máscaras, com AVIF e JPEG XL
+  sidelook --poll 2s /mnt/nfs/renders  # Compartilhamento de rede
+  sidelook --order natural frames/  # frame_2 antes de frame_10
+  sidelook --sniff out           # Aceita saídas sem extensão (ex: out/frame_0001)
+  sidelook --follow-root out     # Continua monitorando após mv out out-v1
+  sidelook --thumb-cache 1GB     # Mais espaço para miniaturas
   sidelook --update              # Atualiza para versão mais recente
 
 `, version.Version)
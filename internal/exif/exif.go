@@ -0,0 +1,326 @@
+// Package exif lê metadados EXIF e XMP de imagens JPEG, TIFF, PNG e WebP
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound indica que o arquivo não contém metadados EXIF
+var ErrNotFound = errors.New("exif: metadados não encontrados")
+
+// errInvalid indica um bloco TIFF/EXIF malformado
+var errInvalid = errors.New("exif: estrutura inválida")
+
+// IDs de tags usadas pelo pacote
+const (
+	TagImageWidth        uint16 = 0x0100
+	TagImageLength       uint16 = 0x0101
+	TagBitsPerSample     uint16 = 0x0102
+	TagPhotometric       uint16 = 0x0106
+	TagSamplesPerPixel   uint16 = 0x0115
+	TagDateTime          uint16 = 0x0132
+	TagExifIFD           uint16 = 0x8769
+	TagGPSIFD            uint16 = 0x8825
+	TagDateTimeOriginal  uint16 = 0x9003
+	TagDateTimeDigitized uint16 = 0x9004
+	TagOffsetTimeOrig    uint16 = 0x9011
+)
+
+// Limites de segurança contra arquivos malformados
+const (
+	maxEntries = 1024
+	maxTagSize = 1 << 20
+)
+
+// Tipos de dados TIFF e seus tamanhos em bytes
+var typeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// tag é uma entrada de IFD com os dados brutos já lidos
+type tag struct {
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+// Exif contém as tags lidas do IFD0 e dos sub-IFDs Exif e GPS
+type Exif struct {
+	order binary.ByteOrder
+	ifd0  map[uint16]tag
+	exif  map[uint16]tag
+	gps   map[uint16]tag
+}
+
+// ReadFile lê os metadados EXIF do arquivo em path
+func ReadFile(path string) (*Exif, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Decode(f, info.Size())
+}
+
+// Decode lê os metadados EXIF de r, detectando o formato pelo conteúdo
+func Decode(r io.ReaderAt, size int64) (*Exif, error) {
+	header := make([]byte, 12)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8}):
+		return decodeJPEG(r, size)
+	case bytes.HasPrefix(header, []byte("II*\x00")), bytes.HasPrefix(header, []byte("MM\x00*")):
+		return ParseTIFF(r)
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return decodePNG(r, size)
+	case len(header) == 12 && bytes.HasPrefix(header, []byte("RIFF")) && string(header[8:12]) == "WEBP":
+		return decodeWebP(r, size)
+	}
+	return nil, ErrNotFound
+}
+
+// decodeJPEG procura o segmento APP1 "Exif\0\0"
+func decodeJPEG(r io.ReaderAt, size int64) (*Exif, error) {
+	offset := int64(2)
+	marker := make([]byte, 4)
+
+	for offset+4 <= size {
+		if _, err := r.ReadAt(marker, offset); err != nil {
+			return nil, ErrNotFound
+		}
+		if marker[0] != 0xFF {
+			return nil, errInvalid
+		}
+		// Início dos dados da imagem (SOS) ou fim (EOI): não há mais metadados
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			break
+		}
+		length := int64(binary.BigEndian.Uint16(marker[2:4]))
+		if length < 2 {
+			return nil, errInvalid
+		}
+
+		if marker[1] == 0xE1 && length > 8 {
+			payload := make([]byte, length-2)
+			if _, err := r.ReadAt(payload, offset+4); err != nil {
+				return nil, errInvalid
+			}
+			if bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				return ParseTIFF(bytes.NewReader(payload[6:]))
+			}
+		}
+		offset += 2 + length
+	}
+	return nil, ErrNotFound
+}
+
+// decodePNG procura o chunk eXIf
+func decodePNG(r io.ReaderAt, size int64) (*Exif, error) {
+	offset := int64(8)
+	header := make([]byte, 8)
+
+	for offset+8 <= size {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return nil, ErrNotFound
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		typ := string(header[4:8])
+
+		if typ == "eXIf" {
+			return ParseTIFF(io.NewSectionReader(r, offset+8, length))
+		}
+		if typ == "IEND" {
+			break
+		}
+		offset += 12 + length
+	}
+	return nil, ErrNotFound
+}
+
+// decodeWebP procura o chunk EXIF no contêiner RIFF
+func decodeWebP(r io.ReaderAt, size int64) (*Exif, error) {
+	offset := int64(12)
+	header := make([]byte, 8)
+
+	for offset+8 <= size {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return nil, ErrNotFound
+		}
+		length := int64(binary.LittleEndian.Uint32(header[4:8]))
+
+		if string(header[0:4]) == "EXIF" {
+			section := io.NewSectionReader(r, offset+8, length)
+			// Alguns encoders mantêm o prefixo "Exif\0\0" do JPEG
+			prefix := make([]byte, 6)
+			if _, err := section.ReadAt(prefix, 0); err == nil && bytes.Equal(prefix, []byte("Exif\x00\x00")) {
+				section = io.NewSectionReader(r, offset+14, length-6)
+			}
+			return ParseTIFF(section)
+		}
+		// Chunks têm tamanho par (byte de preenchimento)
+		offset += 8 + length + length%2
+	}
+	return nil, ErrNotFound
+}
+
+// ParseTIFF interpreta um bloco TIFF (cabeçalho II/MM seguido de IFDs)
+func ParseTIFF(r io.ReaderAt) (*Exif, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, errInvalid
+	}
+
+	e := &Exif{}
+	switch string(header[0:2]) {
+	case "II":
+		e.order = binary.LittleEndian
+	case "MM":
+		e.order = binary.BigEndian
+	default:
+		return nil, errInvalid
+	}
+	if e.order.Uint16(header[2:4]) != 42 {
+		return nil, errInvalid
+	}
+
+	var err error
+	e.ifd0, err = e.readIFD(r, int64(e.order.Uint32(header[4:8])))
+	if err != nil {
+		return nil, err
+	}
+
+	if off, ok := e.uintFrom(e.ifd0, TagExifIFD); ok {
+		e.exif, _ = e.readIFD(r, int64(off))
+	}
+	if off, ok := e.uintFrom(e.ifd0, TagGPSIFD); ok {
+		e.gps, _ = e.readIFD(r, int64(off))
+	}
+
+	return e, nil
+}
+
+// readIFD lê todas as entradas de um IFD
+func (e *Exif) readIFD(r io.ReaderAt, offset int64) (map[uint16]tag, error) {
+	countBuf := make([]byte, 2)
+	if _, err := r.ReadAt(countBuf, offset); err != nil {
+		return nil, errInvalid
+	}
+	count := int(e.order.Uint16(countBuf))
+	if count > maxEntries {
+		return nil, errInvalid
+	}
+
+	entries := make([]byte, count*12)
+	if _, err := r.ReadAt(entries, offset+2); err != nil {
+		return nil, errInvalid
+	}
+
+	tags := make(map[uint16]tag, count)
+	for i := 0; i < count; i++ {
+		entry := entries[i*12 : i*12+12]
+		id := e.order.Uint16(entry[0:2])
+		typ := e.order.Uint16(entry[2:4])
+		n := e.order.Uint32(entry[4:8])
+
+		unit, ok := typeSizes[typ]
+		if !ok {
+			continue
+		}
+		total := uint64(unit) * uint64(n)
+		if total > maxTagSize {
+			continue
+		}
+
+		var data []byte
+		if total <= 4 {
+			data = append([]byte(nil), entry[8:8+total]...)
+		} else {
+			data = make([]byte, total)
+			if _, err := r.ReadAt(data, int64(e.order.Uint32(entry[8:12]))); err != nil {
+				continue
+			}
+		}
+		tags[id] = tag{typ: typ, count: n, data: data}
+	}
+	return tags, nil
+}
+
+// uintFrom lê o primeiro valor inteiro (SHORT ou LONG) de uma tag
+func (e *Exif) uintFrom(tags map[uint16]tag, id uint16) (uint32, bool) {
+	t, ok := tags[id]
+	if !ok || len(t.data) == 0 {
+		return 0, false
+	}
+	switch t.typ {
+	case 3:
+		return uint32(e.order.Uint16(t.data)), true
+	case 4:
+		return e.order.Uint32(t.data), true
+	case 1, 7:
+		return uint32(t.data[0]), true
+	}
+	return 0, false
+}
+
+// Uint retorna o primeiro valor inteiro (SHORT ou LONG) de uma tag
+func (e *Exif) Uint(id uint16) (uint32, bool) {
+	if v, ok := e.uintFrom(e.exif, id); ok {
+		return v, true
+	}
+	return e.uintFrom(e.ifd0, id)
+}
+
+// lookup procura a tag no IFD Exif e depois no IFD0
+func (e *Exif) lookup(id uint16) (tag, bool) {
+	if t, ok := e.exif[id]; ok {
+		return t, true
+	}
+	t, ok := e.ifd0[id]
+	return t, ok
+}
+
+// String retorna o valor de uma tag ASCII
+func (e *Exif) String(id uint16) (string, bool) {
+	t, ok := e.lookup(id)
+	if !ok || t.typ != 2 {
+		return "", false
+	}
+	return strings.TrimRight(string(t.data), "\x00 "), true
+}
+
+// DateTime retorna a data de captura (DateTimeOriginal, com fallback para
+// DateTimeDigitized e DateTime). Sem OffsetTimeOriginal, usa o fuso local.
+func (e *Exif) DateTime() (time.Time, bool) {
+	for _, id := range []uint16{TagDateTimeOriginal, TagDateTimeDigitized, TagDateTime} {
+		value, ok := e.String(id)
+		if !ok || value == "" {
+			continue
+		}
+
+		loc := time.Local
+		if offset, ok := e.String(TagOffsetTimeOrig); ok {
+			if t, err := time.Parse("-07:00", offset); err == nil {
+				_, secs := t.Zone()
+				loc = time.FixedZone(offset, secs)
+			}
+		}
+
+		if t, err := time.ParseInLocation("2006:01:02 15:04:05", value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
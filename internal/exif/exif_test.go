@@ -0,0 +1,147 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+	"time"
+)
+
+// buildTIFF monta um bloco TIFF little-endian com IFD0 apontando para um IFD
+// Exif que contém DateTimeOriginal e OffsetTimeOriginal
+func buildTIFF(date, offset string) []byte {
+	le := binary.LittleEndian
+	var b bytes.Buffer
+
+	// Cabeçalho + IFD0 (1 entrada: ponteiro para o IFD Exif)
+	b.WriteString("II")
+	binary.Write(&b, le, uint16(42))
+	binary.Write(&b, le, uint32(8))
+	binary.Write(&b, le, uint16(1))
+	binary.Write(&b, le, TagExifIFD)
+	binary.Write(&b, le, uint16(4))
+	binary.Write(&b, le, uint32(1))
+	binary.Write(&b, le, uint32(26)) // 8 + 2 + 12 + 4
+	binary.Write(&b, le, uint32(0))
+
+	// IFD Exif com 2 entradas ASCII (dados após o IFD)
+	dateData := append([]byte(date), 0)
+	offsetData := append([]byte(offset), 0)
+	dataStart := uint32(26 + 2 + 2*12 + 4)
+
+	binary.Write(&b, le, uint16(2))
+	binary.Write(&b, le, TagDateTimeOriginal)
+	binary.Write(&b, le, uint16(2))
+	binary.Write(&b, le, uint32(len(dateData)))
+	binary.Write(&b, le, dataStart)
+	binary.Write(&b, le, TagOffsetTimeOrig)
+	binary.Write(&b, le, uint16(2))
+	binary.Write(&b, le, uint32(len(offsetData)))
+	binary.Write(&b, le, dataStart+uint32(len(dateData)))
+	binary.Write(&b, le, uint32(0))
+
+	b.Write(dateData)
+	b.Write(offsetData)
+	return b.Bytes()
+}
+
+func buildJPEG(tiff []byte) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0xFF, 0xD8})
+	// APP0 (JFIF) antes do APP1, como na maioria das câmeras
+	b.Write([]byte{0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00})
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	b.Write([]byte{0xFF, 0xE1})
+	binary.Write(&b, binary.BigEndian, uint16(len(payload)+2))
+	b.Write(payload)
+	b.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9})
+	return b.Bytes()
+}
+
+func buildPNG(tiff []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	chunk := func(typ string, data []byte) {
+		binary.Write(&b, binary.BigEndian, uint32(len(data)))
+		b.WriteString(typ)
+		b.Write(data)
+		binary.Write(&b, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+	}
+	chunk("IHDR", make([]byte, 13))
+	chunk("eXIf", tiff)
+	chunk("IEND", nil)
+	return b.Bytes()
+}
+
+func buildWebP(tiff []byte) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	body.WriteString("EXIF")
+	binary.Write(&body, binary.LittleEndian, uint32(len(tiff)))
+	body.Write(tiff)
+	if len(tiff)%2 == 1 {
+		body.WriteByte(0)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(body.Len()))
+	b.Write(body.Bytes())
+	return b.Bytes()
+}
+
+func TestDecode_DateTime(t *testing.T) {
+	tiff := buildTIFF("2026:10:16 14:30:05", "-03:00")
+	want := time.Date(2026, 10, 16, 14, 30, 5, 0, time.FixedZone("", -3*3600))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"tiff", tiff},
+		{"jpeg", buildJPEG(tiff)},
+		{"png", buildPNG(tiff)},
+		{"webp", buildWebP(tiff)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Decode(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			got, ok := e.DateTime()
+			if !ok {
+				t.Fatal("DateTime() ok = false")
+			}
+			if !got.Equal(want) {
+				t.Errorf("DateTime() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecode_NotFound(t *testing.T) {
+	tests := map[string][]byte{
+		"jpeg sem exif": {0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9},
+		"texto":         []byte("hello world"),
+		"vazio":         {},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(data), int64(len(data))); err == nil {
+				t.Error("Decode() deveria retornar erro")
+			}
+		})
+	}
+}
+
+func TestParseTIFF_Malformed(t *testing.T) {
+	// IFD com contagem absurda e offsets fora do bloco não devem causar pânico
+	data := []byte("II*\x00\x08\x00\x00\x00\xff\xff")
+	if _, err := ParseTIFF(bytes.NewReader(data)); err == nil {
+		t.Error("ParseTIFF() deveria rejeitar IFD malformado")
+	}
+}
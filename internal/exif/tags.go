@@ -0,0 +1,282 @@
+package exif
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Tags de orientação e de localização usadas pelo pacote
+const (
+	TagOrientation     uint16 = 0x0112
+	TagGPSLatitudeRef  uint16 = 0x0001
+	TagGPSLatitude     uint16 = 0x0002
+	TagGPSLongitudeRef uint16 = 0x0003
+	TagGPSLongitude    uint16 = 0x0004
+)
+
+// maxListedValues limita o número de valores de uma tag em Fields (tabelas
+// como StripOffsets não interessam a quem lê os metadados)
+const maxListedValues = 64
+
+// tagNames são os nomes das tags do IFD0 e do IFD Exif
+var tagNames = map[uint16]string{
+	0x0100: "ImageWidth",
+	0x0101: "ImageLength",
+	0x0102: "BitsPerSample",
+	0x0103: "Compression",
+	0x0106: "PhotometricInterpretation",
+	0x010E: "ImageDescription",
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0112: "Orientation",
+	0x0115: "SamplesPerPixel",
+	0x011A: "XResolution",
+	0x011B: "YResolution",
+	0x0128: "ResolutionUnit",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x013B: "Artist",
+	0x8298: "Copyright",
+	0x829A: "ExposureTime",
+	0x829D: "FNumber",
+	0x8822: "ExposureProgram",
+	0x8827: "ISOSpeedRatings",
+	0x9000: "ExifVersion",
+	0x9003: "DateTimeOriginal",
+	0x9004: "DateTimeDigitized",
+	0x9010: "OffsetTime",
+	0x9011: "OffsetTimeOriginal",
+	0x9012: "OffsetTimeDigitized",
+	0x9201: "ShutterSpeedValue",
+	0x9202: "ApertureValue",
+	0x9203: "BrightnessValue",
+	0x9204: "ExposureBiasValue",
+	0x9205: "MaxApertureValue",
+	0x9207: "MeteringMode",
+	0x9208: "LightSource",
+	0x9209: "Flash",
+	0x920A: "FocalLength",
+	0x9286: "UserComment",
+	0x9290: "SubSecTime",
+	0x9291: "SubSecTimeOriginal",
+	0x9292: "SubSecTimeDigitized",
+	0xA001: "ColorSpace",
+	0xA002: "PixelXDimension",
+	0xA003: "PixelYDimension",
+	0xA402: "ExposureMode",
+	0xA403: "WhiteBalance",
+	0xA404: "DigitalZoomRatio",
+	0xA405: "FocalLengthIn35mmFilm",
+	0xA406: "SceneCaptureType",
+	0xA420: "ImageUniqueID",
+	0xA430: "CameraOwnerName",
+	0xA431: "BodySerialNumber",
+	0xA432: "LensSpecification",
+	0xA433: "LensMake",
+	0xA434: "LensModel",
+	0xA435: "LensSerialNumber",
+}
+
+// gpsTagNames são os nomes das tags do IFD GPS
+var gpsTagNames = map[uint16]string{
+	0x0000: "GPSVersionID",
+	0x0001: "GPSLatitudeRef",
+	0x0002: "GPSLatitude",
+	0x0003: "GPSLongitudeRef",
+	0x0004: "GPSLongitude",
+	0x0005: "GPSAltitudeRef",
+	0x0006: "GPSAltitude",
+	0x0007: "GPSTimeStamp",
+	0x000C: "GPSSpeedRef",
+	0x000D: "GPSSpeed",
+	0x0010: "GPSImgDirectionRef",
+	0x0011: "GPSImgDirection",
+	0x0012: "GPSMapDatum",
+	0x001D: "GPSDateStamp",
+}
+
+// skippedTags são ponteiros e blocos binários que não fazem sentido como texto
+var skippedTags = map[uint16]bool{
+	0x0111: true, // StripOffsets
+	0x0117: true, // StripByteCounts
+	0x0144: true, // TileOffsets
+	0x0145: true, // TileByteCounts
+	0x02BC: true, // XMP (lido por DecodeXMP)
+	0x83BB: true, // IPTC
+	0x8769: true, // Ponteiro para o IFD Exif
+	0x8773: true, // Perfil ICC
+	0x8825: true, // Ponteiro para o IFD GPS
+	0x927C: true, // MakerNote (formato do fabricante)
+	0xA005: true, // Ponteiro para o IFD de interoperabilidade
+}
+
+// Orientation retorna a orientação EXIF (1 a 8; 1 = normal, também quando a
+// tag está ausente ou é inválida)
+func (e *Exif) Orientation() int {
+	v, ok := e.uintFrom(e.ifd0, TagOrientation)
+	if !ok || v < 1 || v > 8 {
+		return 1
+	}
+	return int(v)
+}
+
+// Fields retorna as tags legíveis dos IFDs 0, Exif e GPS, pelo nome (tags
+// desconhecidas aparecem como "Tag0x1234"), com os valores formatados
+func (e *Exif) Fields() map[string]string {
+	fields := make(map[string]string)
+	add := func(tags map[uint16]tag, names map[uint16]string) {
+		for id, t := range tags {
+			if skippedTags[id] {
+				continue
+			}
+			value, ok := e.format(id, t)
+			if !ok {
+				continue
+			}
+			name := names[id]
+			if name == "" {
+				name = fmt.Sprintf("Tag0x%04X", id)
+			}
+			fields[name] = value
+		}
+	}
+	add(e.ifd0, tagNames)
+	add(e.exif, tagNames)
+	add(e.gps, gpsTagNames)
+	return fields
+}
+
+// GPS retorna latitude e longitude em graus decimais (negativas ao sul e a
+// oeste)
+func (e *Exif) GPS() (lat, lon float64, ok bool) {
+	lat, okLat := e.degrees(TagGPSLatitude, TagGPSLatitudeRef, "S")
+	lon, okLon := e.degrees(TagGPSLongitude, TagGPSLongitudeRef, "W")
+	return lat, lon, okLat && okLon
+}
+
+// degrees converte graus, minutos e segundos (3 RATIONAL) de uma tag GPS
+func (e *Exif) degrees(id, refID uint16, negative string) (float64, bool) {
+	t, ok := e.gps[id]
+	if !ok || t.typ != 5 || t.count < 3 || len(t.data) < 24 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		num, den := e.order.Uint32(t.data[i*8:]), e.order.Uint32(t.data[i*8+4:])
+		if den == 0 {
+			return 0, false
+		}
+		parts[i] = float64(num) / float64(den)
+	}
+	v := parts[0] + parts[1]/60 + parts[2]/3600
+
+	if ref, ok := e.gps[refID]; ok && strings.HasPrefix(string(ref.data), negative) {
+		v = -v
+	}
+	return v, true
+}
+
+// format converte o valor de uma tag em texto. Retorna false para valores
+// binários ou longos demais.
+func (e *Exif) format(id uint16, t tag) (string, bool) {
+	if t.typ == 2 {
+		return strings.TrimRight(string(t.data), "\x00 "), true
+	}
+	if id == 0x9286 {
+		return e.userComment(t.data)
+	}
+	if t.typ == 7 || t.typ == 1 || t.typ == 6 {
+		if s := strings.TrimRight(string(t.data), "\x00 "); printable(s) && s != "" {
+			return s, true
+		}
+		if t.typ == 7 {
+			return "", false
+		}
+	}
+	if t.count > maxListedValues {
+		return "", false
+	}
+
+	var values []string
+	unit := int(typeSizes[t.typ])
+	for i := 0; i+unit <= len(t.data) && len(values) < int(t.count); i += unit {
+		b := t.data[i:]
+		switch t.typ {
+		case 1:
+			values = append(values, strconv.Itoa(int(b[0])))
+		case 6:
+			values = append(values, strconv.Itoa(int(int8(b[0]))))
+		case 3:
+			values = append(values, strconv.Itoa(int(e.order.Uint16(b))))
+		case 8:
+			values = append(values, strconv.Itoa(int(int16(e.order.Uint16(b)))))
+		case 4:
+			values = append(values, strconv.FormatUint(uint64(e.order.Uint32(b)), 10))
+		case 9:
+			values = append(values, strconv.Itoa(int(int32(e.order.Uint32(b)))))
+		case 5:
+			values = append(values, rational(id, int64(e.order.Uint32(b)), int64(e.order.Uint32(b[4:]))))
+		case 10:
+			values = append(values, rational(id, int64(int32(e.order.Uint32(b))), int64(int32(e.order.Uint32(b[4:])))))
+		case 11:
+			values = append(values, strconv.FormatFloat(float64(math.Float32frombits(e.order.Uint32(b))), 'g', 6, 32))
+		case 12:
+			values = append(values, strconv.FormatFloat(math.Float64frombits(e.order.Uint64(b)), 'g', 6, 64))
+		}
+	}
+	if len(values) == 0 {
+		return "", false
+	}
+	return strings.Join(values, ", "), true
+}
+
+// rational formata uma fração: tempos de exposição como "1/125", o resto
+// em decimal
+func rational(id uint16, num, den int64) string {
+	if den == 0 {
+		return "0"
+	}
+	if id == 0x829A && num > 0 && num < den {
+		return "1/" + strconv.FormatFloat(float64(den)/float64(num), 'f', 0, 64)
+	}
+	if num%den == 0 {
+		return strconv.FormatInt(num/den, 10)
+	}
+	return strconv.FormatFloat(float64(num)/float64(den), 'g', 6, 64)
+}
+
+// userComment decodifica UserComment: 8 bytes de código de caracteres
+// seguidos do texto (ASCII, ou UTF-16 na ordem de bytes do bloco TIFF)
+func (e *Exif) userComment(data []byte) (string, bool) {
+	if len(data) < 8 {
+		return "", false
+	}
+	text := data[8:]
+	switch string(data[:8]) {
+	case "ASCII\x00\x00\x00", "\x00\x00\x00\x00\x00\x00\x00\x00":
+		s := strings.TrimRight(string(text), "\x00 ")
+		return s, s != "" && printable(s)
+	case "UNICODE\x00":
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			units = append(units, e.order.Uint16(text[i:]))
+		}
+		s := strings.TrimRight(string(utf16.Decode(units)), "\x00 ")
+		return s, s != "" && printable(s)
+	}
+	return "", false
+}
+
+// printable indica se s é texto sem caracteres de controle
+func printable(s string) bool {
+	for _, r := range s {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' || r == 0x7F || r == utf8.RuneError {
+			return false
+		}
+	}
+	return true
+}
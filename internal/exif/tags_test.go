@@ -0,0 +1,180 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// entry é uma tag a gravar em buildIFDs
+type entry struct {
+	id    uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func ascii(id uint16, s string) entry {
+	return entry{id, 2, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+func short(id uint16, values ...uint16) entry {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(data[i*2:], v)
+	}
+	return entry{id, 3, uint32(len(values)), data}
+}
+
+func rationals(id uint16, values ...uint32) entry {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], v)
+	}
+	return entry{id, 5, uint32(len(values) / 2), data}
+}
+
+// buildIFDs monta um bloco TIFF little-endian com IFD0 e, se informados, os
+// IFDs Exif e GPS (os ponteiros são acrescentados ao IFD0)
+func buildIFDs(ifd0, exifIFD, gps []entry) []byte {
+	le := binary.LittleEndian
+	size := func(entries []entry) int {
+		if len(entries) == 0 {
+			return 0
+		}
+		return 2 + 12*len(entries) + 4
+	}
+
+	if len(exifIFD) > 0 {
+		ifd0 = append(ifd0, entry{TagExifIFD, 4, 1, nil})
+	}
+	if len(gps) > 0 {
+		ifd0 = append(ifd0, entry{TagGPSIFD, 4, 1, nil})
+	}
+
+	// IFDs em sequência a partir do offset 8, dados no final
+	exifOffset := 8 + size(ifd0)
+	gpsOffset := exifOffset + size(exifIFD)
+	dataOffset := gpsOffset + size(gps)
+
+	var b, data bytes.Buffer
+	b.WriteString("II")
+	binary.Write(&b, le, uint16(42))
+	binary.Write(&b, le, uint32(8))
+
+	write := func(entries []entry) {
+		binary.Write(&b, le, uint16(len(entries)))
+		for _, e := range entries {
+			binary.Write(&b, le, e.id)
+			binary.Write(&b, le, e.typ)
+			binary.Write(&b, le, e.count)
+			switch {
+			case e.id == TagExifIFD:
+				binary.Write(&b, le, uint32(exifOffset))
+			case e.id == TagGPSIFD && e.typ == 4 && e.data == nil:
+				binary.Write(&b, le, uint32(gpsOffset))
+			case len(e.data) <= 4:
+				b.Write(append(append([]byte(nil), e.data...), make([]byte, 4-len(e.data))...))
+			default:
+				binary.Write(&b, le, uint32(dataOffset+data.Len()))
+				data.Write(e.data)
+			}
+		}
+		binary.Write(&b, le, uint32(0))
+	}
+	write(ifd0)
+	if len(exifIFD) > 0 {
+		write(exifIFD)
+	}
+	if len(gps) > 0 {
+		write(gps)
+	}
+	b.Write(data.Bytes())
+	return b.Bytes()
+}
+
+func TestOrientation(t *testing.T) {
+	for _, tt := range []struct {
+		entries []entry
+		want    int
+	}{
+		{[]entry{short(TagOrientation, 6)}, 6},
+		{[]entry{short(TagOrientation, 9)}, 1},
+		{[]entry{ascii(0x010F, "Canon")}, 1},
+	} {
+		e, err := ParseTIFF(bytes.NewReader(buildIFDs(tt.entries, nil, nil)))
+		if err != nil {
+			t.Fatalf("ParseTIFF() error = %v", err)
+		}
+		if got := e.Orientation(); got != tt.want {
+			t.Errorf("Orientation() = %d, want %d", got, tt.want)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	userComment := append([]byte("ASCII\x00\x00\x00"), "pôr do sol"...)
+	double := make([]byte, 8)
+	binary.LittleEndian.PutUint64(double, math.Float64bits(1.5))
+
+	data := buildIFDs(
+		[]entry{ascii(0x010F, "Apple"), ascii(0x0110, "iPhone 15 Pro"), short(TagOrientation, 6), short(0x0111, 8, 16)},
+		[]entry{
+			rationals(0x829A, 1, 125),
+			rationals(0x829D, 18, 10),
+			short(0x8827, 400),
+			rationals(0x920A, 6860, 1000),
+			ascii(0xA434, "iPhone 15 Pro back camera 6.86mm f/1.78"),
+			{0x9000, 7, 4, []byte("0232")},
+			{0x9286, 7, uint32(len(userComment)), userComment},
+			{0x927C, 7, 6, []byte("Apple\x00")},
+			{0xC000, 12, 1, double},
+		},
+		[]entry{
+			ascii(TagGPSLatitudeRef, "S"),
+			rationals(TagGPSLatitude, 23, 1, 33, 1, 1800, 100),
+			ascii(TagGPSLongitudeRef, "W"),
+			rationals(TagGPSLongitude, 46, 1, 37, 1, 3000, 100),
+			{0x0000, 1, 4, []byte{2, 2, 0, 0}},
+		},
+	)
+
+	e, err := ParseTIFF(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseTIFF() error = %v", err)
+	}
+
+	fields := e.Fields()
+	want := map[string]string{
+		"Make":            "Apple",
+		"Model":           "iPhone 15 Pro",
+		"Orientation":     "6",
+		"ExposureTime":    "1/125",
+		"FNumber":         "1.8",
+		"ISOSpeedRatings": "400",
+		"FocalLength":     "6.86",
+		"LensModel":       "iPhone 15 Pro back camera 6.86mm f/1.78",
+		"ExifVersion":     "0232",
+		"UserComment":     "pôr do sol",
+		"Tag0xC000":       "1.5",
+		"GPSLatitudeRef":  "S",
+		"GPSLatitude":     "23, 33, 18",
+		"GPSVersionID":    "2, 2, 0, 0",
+	}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("Fields()[%s] = %q, want %q", name, fields[name], value)
+		}
+	}
+	for _, name := range []string{"StripOffsets", "Tag0x0111", "Tag0x927C", "Tag0x8769", "Tag0x8825"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("Fields() não deveria listar %s", name)
+		}
+	}
+
+	lat, lon, ok := e.GPS()
+	if !ok || math.Abs(lat-(-23.555)) > 1e-6 || math.Abs(lon-(-46.625)) > 1e-6 {
+		t.Errorf("GPS() = %f, %f, %v, want -23.555, -46.625", lat, lon, ok)
+	}
+}
@@ -0,0 +1,276 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxXMPSize limita o pacote XMP lido (arquivos reais têm poucos KB)
+const maxXMPSize = 1 << 20
+
+// tagXMP é a tag TIFF que guarda o pacote XMP
+const tagXMP uint16 = 0x02BC
+
+var (
+	xmpJPEGPrefix = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	xmpPNGKeyword = []byte("XML:com.adobe.xmp\x00")
+)
+
+// ReadXMP lê as propriedades XMP do arquivo em path
+func ReadXMP(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return DecodeXMP(f, info.Size())
+}
+
+// DecodeXMP lê as propriedades XMP de r (JPEG, TIFF, PNG ou WebP). As chaves
+// usam o prefixo do arquivo ("dc:title", "xmp:Rating"); estruturas aninhadas
+// viram caminhos ("xmpMM:History/stEvt:action") e listas são unidas por ", ".
+func DecodeXMP(r io.ReaderAt, size int64) (map[string]string, error) {
+	packet, err := findXMP(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return parseXMP(packet)
+}
+
+// findXMP localiza o pacote XMP no contêiner de cada formato
+func findXMP(r io.ReaderAt, size int64) ([]byte, error) {
+	header := make([]byte, 12)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8}):
+		return findXMPJPEG(r, size)
+	case bytes.HasPrefix(header, []byte("II*\x00")), bytes.HasPrefix(header, []byte("MM\x00*")):
+		e, err := ParseTIFF(r)
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := e.ifd0[tagXMP]; ok && len(t.data) > 0 {
+			return t.data, nil
+		}
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return findXMPPNG(r, size)
+	case len(header) == 12 && bytes.HasPrefix(header, []byte("RIFF")) && string(header[8:12]) == "WEBP":
+		return findXMPWebP(r, size)
+	}
+	return nil, ErrNotFound
+}
+
+// findXMPJPEG procura o segmento APP1 com o namespace XMP
+func findXMPJPEG(r io.ReaderAt, size int64) ([]byte, error) {
+	offset := int64(2)
+	marker := make([]byte, 4)
+
+	for offset+4 <= size {
+		if _, err := r.ReadAt(marker, offset); err != nil || marker[0] != 0xFF {
+			break
+		}
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			break
+		}
+		length := int64(binary.BigEndian.Uint16(marker[2:4]))
+		if length < 2 {
+			return nil, errInvalid
+		}
+
+		if marker[1] == 0xE1 && length-2 > int64(len(xmpJPEGPrefix)) {
+			payload := make([]byte, length-2)
+			if _, err := r.ReadAt(payload, offset+4); err != nil {
+				return nil, errInvalid
+			}
+			if bytes.HasPrefix(payload, xmpJPEGPrefix) {
+				return payload[len(xmpJPEGPrefix):], nil
+			}
+		}
+		offset += 2 + length
+	}
+	return nil, ErrNotFound
+}
+
+// findXMPPNG procura o chunk iTXt "XML:com.adobe.xmp" (sem compressão)
+func findXMPPNG(r io.ReaderAt, size int64) ([]byte, error) {
+	offset := int64(8)
+	header := make([]byte, 8)
+
+	for offset+8 <= size {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			break
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		typ := string(header[4:8])
+
+		if typ == "iTXt" && length > int64(len(xmpPNGKeyword)) && length <= maxXMPSize {
+			data := make([]byte, length)
+			if _, err := r.ReadAt(data, offset+8); err != nil {
+				return nil, errInvalid
+			}
+			if bytes.HasPrefix(data, xmpPNGKeyword) {
+				// Flag e método de compressão, idioma e palavra-chave traduzida
+				rest := data[len(xmpPNGKeyword):]
+				if len(rest) < 2 || rest[0] != 0 {
+					return nil, errInvalid
+				}
+				rest = rest[2:]
+				for i := 0; i < 2; i++ {
+					end := bytes.IndexByte(rest, 0)
+					if end < 0 {
+						return nil, errInvalid
+					}
+					rest = rest[end+1:]
+				}
+				return rest, nil
+			}
+		}
+		if typ == "IEND" {
+			break
+		}
+		offset += 12 + length
+	}
+	return nil, ErrNotFound
+}
+
+// findXMPWebP procura o chunk "XMP " no contêiner RIFF
+func findXMPWebP(r io.ReaderAt, size int64) ([]byte, error) {
+	offset := int64(12)
+	header := make([]byte, 8)
+
+	for offset+8 <= size {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			break
+		}
+		length := int64(binary.LittleEndian.Uint32(header[4:8]))
+		if string(header[0:4]) == "XMP " {
+			if length > maxXMPSize {
+				return nil, errInvalid
+			}
+			data := make([]byte, length)
+			if _, err := r.ReadAt(data, offset+8); err != nil {
+				return nil, errInvalid
+			}
+			return data, nil
+		}
+		offset += 8 + length + length%2
+	}
+	return nil, ErrNotFound
+}
+
+// parseXMP extrai as propriedades simples de um pacote RDF/XML. Usa os nomes
+// como escritos no arquivo (prefixo:nome), sem resolver namespaces.
+func parseXMP(packet []byte) (map[string]string, error) {
+	if len(packet) > maxXMPSize {
+		packet = packet[:maxXMPSize]
+	}
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	dec.Strict = false
+
+	values := make(map[string][]string)
+	add := func(path, value string) {
+		value = strings.TrimSpace(value)
+		if path != "" && value != "" {
+			values[path] = append(values[path], value)
+		}
+	}
+
+	// Pilha de propriedades abertas (containers RDF não entram no caminho)
+	var stack []string
+	path := func() string {
+		var parts []string
+		for _, p := range stack {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		return strings.Join(parts, "/")
+	}
+
+	inRDF := false
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(values) == 0 {
+				return nil, errInvalid
+			}
+			break // Pacote truncado: ficar com o que foi lido
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := rawName(t.Name)
+			switch {
+			case name == "rdf:RDF":
+				inRDF = true
+				stack = append(stack, "")
+				continue
+			case !inRDF || name == "rdf:Description" || name == "rdf:li" ||
+				name == "rdf:Alt" || name == "rdf:Seq" || name == "rdf:Bag":
+				stack = append(stack, "")
+			default:
+				stack = append(stack, name)
+			}
+			if !inRDF {
+				continue
+			}
+			// Atributos são propriedades (forma abreviada do RDF)
+			prefix := path()
+			for _, attr := range t.Attr {
+				attrName := rawName(attr.Name)
+				switch attr.Name.Space {
+				case "", "xmlns", "rdf", "xml":
+					continue
+				}
+				if prefix != "" {
+					attrName = prefix + "/" + attrName
+				}
+				add(attrName, attr.Value)
+			}
+
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			if rawName(t.Name) == "rdf:RDF" {
+				inRDF = false
+			}
+
+		case xml.CharData:
+			if inRDF {
+				add(path(), string(t))
+			}
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, ErrNotFound
+	}
+	props := make(map[string]string, len(values))
+	for p, v := range values {
+		props[p] = strings.Join(v, ", ")
+	}
+	return props, nil
+}
+
+// rawName junta prefixo e nome local como aparecem no XML
+func rawName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
@@ -0,0 +1,98 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+const testXMP = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/"
+    xmlns:stEvt="http://ns.adobe.com/xap/1.0/sType/ResourceEvent#"
+    xmp:Rating="4"
+    xmp:CreatorTool="Darktable">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Pôr do sol</rdf:li></rdf:Alt></dc:title>
+   <dc:subject><rdf:Bag><rdf:li>praia</rdf:li><rdf:li>verão</rdf:li></rdf:Bag></dc:subject>
+   <xmpMM:History><rdf:Seq>
+    <rdf:li rdf:parseType="Resource"><stEvt:action>saved</stEvt:action></rdf:li>
+   </rdf:Seq></xmpMM:History>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+func TestDecodeXMP(t *testing.T) {
+	packet := []byte(testXMP)
+
+	var jpeg bytes.Buffer
+	jpeg.Write([]byte{0xFF, 0xD8})
+	payload := append(append([]byte(nil), xmpJPEGPrefix...), packet...)
+	jpeg.Write([]byte{0xFF, 0xE1})
+	binary.Write(&jpeg, binary.BigEndian, uint16(len(payload)+2))
+	jpeg.Write(payload)
+	jpeg.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9})
+
+	var png bytes.Buffer
+	png.WriteString("\x89PNG\r\n\x1a\n")
+	itxt := append(append([]byte(nil), xmpPNGKeyword...), 0, 0, 0, 0)
+	itxt = append(itxt, packet...)
+	for _, c := range []struct {
+		typ  string
+		data []byte
+	}{{"IHDR", make([]byte, 13)}, {"iTXt", itxt}, {"IEND", nil}} {
+		binary.Write(&png, binary.BigEndian, uint32(len(c.data)))
+		png.WriteString(c.typ)
+		png.Write(c.data)
+		binary.Write(&png, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(c.typ), c.data...)))
+	}
+
+	var body bytes.Buffer
+	body.WriteString("WEBPXMP ")
+	binary.Write(&body, binary.LittleEndian, uint32(len(packet)))
+	body.Write(packet)
+	webp := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(body.Len()))...)
+	webp = append(webp, body.Bytes()...)
+
+	tiff := buildIFDs([]entry{{tagXMP, 1, uint32(len(packet)), packet}}, nil, nil)
+
+	want := map[string]string{
+		"xmp:Rating":                 "4",
+		"xmp:CreatorTool":            "Darktable",
+		"dc:title":                   "Pôr do sol",
+		"dc:subject":                 "praia, verão",
+		"xmpMM:History/stEvt:action": "saved",
+	}
+
+	for name, data := range map[string][]byte{"jpeg": jpeg.Bytes(), "png": png.Bytes(), "webp": webp, "tiff": tiff} {
+		t.Run(name, func(t *testing.T) {
+			props, err := DecodeXMP(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("DecodeXMP() error = %v", err)
+			}
+			if len(props) != len(want) {
+				t.Errorf("DecodeXMP() = %v, want %v", props, want)
+			}
+			for k, v := range want {
+				if props[k] != v {
+					t.Errorf("DecodeXMP()[%s] = %q, want %q", k, props[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeXMP_NotFound(t *testing.T) {
+	data := buildJPEG(buildTIFF("2026:10:16 14:30:05", ""))
+	if _, err := DecodeXMP(bytes.NewReader(data), int64(len(data))); err != ErrNotFound {
+		t.Errorf("JPEG sem XMP: error = %v, want ErrNotFound", err)
+	}
+	if _, err := parseXMP([]byte("<x:xmpmeta><rdf:RDF></rdf:RDF></x:xmpmeta>")); err != ErrNotFound {
+		t.Errorf("XMP vazio: error = %v, want ErrNotFound", err)
+	}
+}
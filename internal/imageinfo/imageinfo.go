@@ -0,0 +1,653 @@
+// Package imageinfo lê dimensões, modelo de cor, profundidade, número de
+// quadros, orientação e as chaves dos textos embutidos de imagens a partir
+// do cabeçalho, sem decodificar os pixels
+package imageinfo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/verseles/sidelook/internal/exif"
+	"github.com/verseles/sidelook/internal/imagetype"
+	"github.com/verseles/sidelook/internal/textmeta"
+)
+
+// ErrUnknown indica que o formato não foi reconhecido pelo conteúdo
+var ErrUnknown = errors.New("imageinfo: formato desconhecido")
+
+// errInvalid indica um cabeçalho malformado
+var errInvalid = errors.New("imageinfo: cabeçalho inválido")
+
+// Limites de segurança contra arquivos malformados
+const (
+	maxChunks = 1 << 16
+	maxSVGTag = 64 << 10
+)
+
+// ColorModel descreve os canais de cor da imagem
+type ColorModel string
+
+const (
+	Gray      ColorModel = "gray"
+	GrayAlpha ColorModel = "gray-alpha"
+	RGB       ColorModel = "rgb"
+	RGBA      ColorModel = "rgba"
+	Paletted  ColorModel = "paletted"
+	CMYK      ColorModel = "cmyk"
+	YCbCr     ColorModel = "ycbcr"
+	YCbCrA    ColorModel = "ycbcr-alpha"
+)
+
+// Info descreve uma imagem. Campos que o formato não informa (ou que o
+// pacote não sabe ler) ficam zerados: Width e Height de SVG sem tamanho,
+// ColorModel de SVG e JPEG XL, Frames de AVIF animado...
+type Info struct {
+	Format     imagetype.Format
+	Width      int
+	Height     int
+	ColorModel ColorModel
+	Depth      int // Bits por canal
+	Frames     int // 1 para imagens estáticas
+
+	// Orientation é a orientação EXIF (1 a 8; 0 quando o formato não a
+	// informa). Width e Height são as dimensões gravadas, antes de aplicá-la.
+	Orientation int
+
+	// Text são as chaves dos textos embutidos (chunks de texto do PNG,
+	// comentários e campos EXIF de texto), como "parameters" ou "workflow"
+	// em imagens de IA generativa. Os textos são lidos por textmeta.
+	Text []string
+}
+
+// DisplaySize retorna as dimensões como a imagem é exibida, já com a
+// orientação EXIF aplicada (rotações de 90° trocam largura e altura)
+func (i Info) DisplaySize() (width, height int) {
+	if i.Orientation >= 5 && i.Orientation <= 8 {
+		return i.Height, i.Width
+	}
+	return i.Width, i.Height
+}
+
+// Animated indica se a imagem tem mais de um quadro
+func (i Info) Animated() bool {
+	return i.Frames > 1
+}
+
+// ReadFile lê o cabeçalho da imagem em path
+func ReadFile(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+	return Decode(f, info.Size())
+}
+
+// Decode lê o cabeçalho de r, detectando o formato pelo conteúdo. Com erro
+// de leitura depois de identificar o formato, retorna o Info parcial.
+func Decode(r io.ReaderAt, size int64) (Info, error) {
+	head := make([]byte, imagetype.HeaderSize)
+	n, err := r.ReadAt(head, 0)
+	if n == 0 && err != nil && err != io.EOF {
+		return Info{}, err
+	}
+	head = head[:n]
+
+	info := Info{Format: imagetype.Detect(head)}
+	switch info.Format {
+	case imagetype.PNG:
+		err = decodePNG(r, size, head, &info)
+	case imagetype.JPEG:
+		err = decodeJPEG(r, size, &info)
+	case imagetype.GIF:
+		err = decodeGIF(r, size, head, &info)
+	case imagetype.WebP:
+		err = decodeWebP(r, size, &info)
+	case imagetype.BMP:
+		err = decodeBMP(head, &info)
+	case imagetype.TIFF:
+		err = decodeTIFF(r, &info)
+	case imagetype.ICO:
+		err = decodeICO(head, &info)
+	case imagetype.AVIF:
+		err = decodeAVIF(r, size, &info)
+	case imagetype.SVG:
+		err = decodeSVG(r, &info)
+	case imagetype.JXL:
+		info.Frames = 1
+		err = nil
+	default:
+		return Info{}, ErrUnknown
+	}
+
+	switch info.Format {
+	case imagetype.JPEG, imagetype.PNG, imagetype.WebP, imagetype.TIFF:
+		info.Orientation = 1
+		if e, xerr := exif.Decode(r, size); xerr == nil {
+			info.Orientation = e.Orientation()
+		}
+	}
+	switch info.Format {
+	case imagetype.JPEG, imagetype.PNG, imagetype.WebP:
+		if keys, _ := textmeta.Keys(r, size); len(keys) > 0 {
+			info.Text = keys
+		}
+	}
+	return info, err
+}
+
+// decodePNG lê o IHDR e procura o acTL (APNG) antes dos dados da imagem
+func decodePNG(r io.ReaderAt, size int64, head []byte, info *Info) error {
+	if len(head) < 26 || string(head[12:16]) != "IHDR" {
+		return errInvalid
+	}
+	info.Width = int(binary.BigEndian.Uint32(head[16:20]))
+	info.Height = int(binary.BigEndian.Uint32(head[20:24]))
+	info.Depth = int(head[24])
+	switch head[25] {
+	case 0:
+		info.ColorModel = Gray
+	case 2:
+		info.ColorModel = RGB
+	case 3:
+		info.ColorModel = Paletted
+	case 4:
+		info.ColorModel = GrayAlpha
+	case 6:
+		info.ColorModel = RGBA
+	}
+	info.Frames = 1
+
+	// O acTL precisa vir antes do primeiro IDAT
+	offset := int64(8)
+	header := make([]byte, 12)
+	for i := 0; i < maxChunks && offset+8 <= size; i++ {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return nil
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		switch string(header[4:8]) {
+		case "acTL":
+			if frames := int(binary.BigEndian.Uint32(header[8:12])); frames > 0 {
+				info.Frames = frames
+			}
+			return nil
+		case "IDAT", "IEND":
+			return nil
+		}
+		offset += 12 + length
+	}
+	return nil
+}
+
+// decodeJPEG procura o primeiro marcador SOF (início do quadro)
+func decodeJPEG(r io.ReaderAt, size int64, info *Info) error {
+	offset := int64(2)
+	marker := make([]byte, 10)
+
+	for offset+4 <= size {
+		if _, err := r.ReadAt(marker[:4], offset); err != nil {
+			return errInvalid
+		}
+		if marker[0] != 0xFF {
+			return errInvalid
+		}
+		// Bytes de preenchimento e marcadores sem tamanho
+		if marker[1] == 0xFF {
+			offset++
+			continue
+		}
+		if marker[1] == 0x01 || (marker[1] >= 0xD0 && marker[1] <= 0xD7) {
+			offset += 2
+			continue
+		}
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			break
+		}
+		length := int64(binary.BigEndian.Uint16(marker[2:4]))
+		if length < 2 {
+			return errInvalid
+		}
+
+		// SOF0..SOF15, exceto DHT (C4), JPG (C8) e DAC (CC)
+		if m := marker[1]; m >= 0xC0 && m <= 0xCF && m != 0xC4 && m != 0xC8 && m != 0xCC {
+			if _, err := r.ReadAt(marker, offset); err != nil {
+				return errInvalid
+			}
+			info.Depth = int(marker[4])
+			info.Height = int(binary.BigEndian.Uint16(marker[5:7]))
+			info.Width = int(binary.BigEndian.Uint16(marker[7:9]))
+			switch marker[9] {
+			case 1:
+				info.ColorModel = Gray
+			case 3:
+				info.ColorModel = YCbCr
+			case 4:
+				info.ColorModel = CMYK
+			}
+			info.Frames = 1
+			return nil
+		}
+		offset += 2 + length
+	}
+	return errInvalid
+}
+
+// decodeGIF lê a tela lógica e conta os quadros percorrendo os blocos do
+// arquivo, sem descomprimir os dados LZW
+func decodeGIF(r io.ReaderAt, size int64, head []byte, info *Info) error {
+	if len(head) < 13 {
+		return errInvalid
+	}
+	info.Width = int(binary.LittleEndian.Uint16(head[6:8]))
+	info.Height = int(binary.LittleEndian.Uint16(head[8:10]))
+	info.ColorModel = Paletted
+	info.Depth = 8
+
+	br := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	br.Discard(13)
+	if flags := head[10]; flags&0x80 != 0 {
+		br.Discard(3 << (flags&7 + 1))
+	}
+
+	frames := 0
+blocks:
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			break // Arquivo truncado: contar os quadros lidos até aqui
+		}
+		switch b {
+		case 0x21: // Extensão: rótulo e sub-blocos
+			if _, err := br.ReadByte(); err != nil || skipSubBlocks(br) != nil {
+				break blocks
+			}
+		case 0x2C: // Descritor de imagem
+			var desc [9]byte
+			if _, err := io.ReadFull(br, desc[:]); err != nil {
+				break blocks
+			}
+			if desc[8]&0x80 != 0 {
+				br.Discard(3 << (desc[8]&7 + 1))
+			}
+			frames++
+			// Tamanho mínimo do código LZW e dados
+			if _, err := br.ReadByte(); err != nil || skipSubBlocks(br) != nil {
+				break blocks
+			}
+		default:
+			break blocks // Trailer (0x3B) ou bloco inválido
+		}
+	}
+
+	info.Frames = max(frames, 1)
+	return nil
+}
+
+// skipSubBlocks pula uma sequência de sub-blocos GIF até o terminador
+func skipSubBlocks(br *bufio.Reader) error {
+	for {
+		n, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if _, err := br.Discard(int(n)); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeWebP lê o primeiro chunk (VP8, VP8L ou VP8X) e, em animações, conta
+// os chunks ANMF
+func decodeWebP(r io.ReaderAt, size int64, info *Info) error {
+	// Cabeçalho do chunk e até 10 bytes de dados (VP8L usa só 5)
+	chunk := make([]byte, 18)
+	n, _ := r.ReadAt(chunk, 12)
+	if n < 13 {
+		return errInvalid
+	}
+	if string(chunk[0:4]) != "VP8L" && n < len(chunk) {
+		return errInvalid
+	}
+	data := chunk[8:]
+	info.Depth = 8
+	info.Frames = 1
+
+	switch string(chunk[0:4]) {
+	case "VP8 ":
+		if data[3] != 0x9D || data[4] != 0x01 || data[5] != 0x2A {
+			return errInvalid
+		}
+		info.Width = int(binary.LittleEndian.Uint16(data[6:8]) & 0x3FFF)
+		info.Height = int(binary.LittleEndian.Uint16(data[8:10]) & 0x3FFF)
+		info.ColorModel = YCbCr
+
+	case "VP8L":
+		if data[0] != 0x2F {
+			return errInvalid
+		}
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		info.Width = int(bits&0x3FFF) + 1
+		info.Height = int(bits>>14&0x3FFF) + 1
+		info.ColorModel = RGB
+		if bits>>28&1 != 0 {
+			info.ColorModel = RGBA
+		}
+
+	case "VP8X":
+		flags := data[0]
+		info.Width = int(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16) + 1
+		info.Height = int(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16) + 1
+		info.ColorModel = YCbCr
+		if flags&0x10 != 0 {
+			info.ColorModel = YCbCrA
+		}
+		if flags&0x02 != 0 {
+			info.Frames = countWebPFrames(r, size)
+		} else {
+			// O chunk da imagem diz se ela é sem perdas
+			if lossless, alpha := webPBitstream(r, size); lossless {
+				info.ColorModel = RGB
+				if alpha || flags&0x10 != 0 {
+					info.ColorModel = RGBA
+				}
+			}
+		}
+
+	default:
+		return errInvalid
+	}
+	return nil
+}
+
+// webPBitstream procura o chunk da imagem de um WebP estendido e informa se
+// ele é sem perdas (VP8L) e se declara transparência
+func webPBitstream(r io.ReaderAt, size int64) (lossless, alpha bool) {
+	header := make([]byte, 13)
+	offset := int64(12)
+	for i := 0; i < maxChunks && offset+8 <= size; i++ {
+		n, _ := r.ReadAt(header, offset)
+		if n < 8 {
+			return false, false
+		}
+		length := int64(binary.LittleEndian.Uint32(header[4:8]))
+		switch string(header[0:4]) {
+		case "VP8L":
+			if n < 13 {
+				return true, false
+			}
+			return true, binary.LittleEndian.Uint32(header[9:13])>>28&1 != 0
+		case "VP8 ":
+			return false, false
+		}
+		offset += 8 + length + length%2
+	}
+	return false, false
+}
+
+// countWebPFrames conta os chunks ANMF de um WebP animado
+func countWebPFrames(r io.ReaderAt, size int64) int {
+	header := make([]byte, 8)
+	offset := int64(12)
+	frames := 0
+	for i := 0; i < maxChunks && offset+8 <= size; i++ {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			break
+		}
+		if string(header[0:4]) == "ANMF" {
+			frames++
+		}
+		length := int64(binary.LittleEndian.Uint32(header[4:8]))
+		offset += 8 + length + length%2
+	}
+	return max(frames, 1)
+}
+
+// decodeBMP lê o cabeçalho DIB (BITMAPCOREHEADER ou BITMAPINFOHEADER e
+// sucessores)
+func decodeBMP(head []byte, info *Info) error {
+	if len(head) < 30 {
+		return errInvalid
+	}
+	var bpp int
+	if binary.LittleEndian.Uint32(head[14:18]) == 12 {
+		info.Width = int(binary.LittleEndian.Uint16(head[18:20]))
+		info.Height = int(binary.LittleEndian.Uint16(head[20:22]))
+		bpp = int(binary.LittleEndian.Uint16(head[24:26]))
+	} else {
+		info.Width = int(int32(binary.LittleEndian.Uint32(head[18:22])))
+		// Altura negativa indica linhas de cima para baixo
+		info.Height = int(int32(binary.LittleEndian.Uint32(head[22:26])))
+		if info.Height < 0 {
+			info.Height = -info.Height
+		}
+		bpp = int(binary.LittleEndian.Uint16(head[28:30]))
+	}
+
+	switch {
+	case bpp <= 8:
+		info.ColorModel = Paletted
+		info.Depth = bpp
+	case bpp == 32:
+		info.ColorModel = RGBA
+		info.Depth = 8
+	default:
+		info.ColorModel = RGB
+		info.Depth = 8
+	}
+	info.Frames = 1
+	return nil
+}
+
+// decodeTIFF lê as tags do primeiro IFD
+func decodeTIFF(r io.ReaderAt, info *Info) error {
+	e, err := exif.ParseTIFF(r)
+	if err != nil {
+		return errInvalid // BigTIFF ou IFD malformado
+	}
+	width, _ := e.Uint(exif.TagImageWidth)
+	height, _ := e.Uint(exif.TagImageLength)
+	info.Width, info.Height = int(width), int(height)
+
+	depth, ok := e.Uint(exif.TagBitsPerSample)
+	if !ok {
+		depth = 1
+	}
+	info.Depth = int(depth)
+	samples, ok := e.Uint(exif.TagSamplesPerPixel)
+	if !ok {
+		samples = 1
+	}
+
+	photometric, _ := e.Uint(exif.TagPhotometric)
+	switch photometric {
+	case 0, 1:
+		info.ColorModel = Gray
+		if samples > 1 {
+			info.ColorModel = GrayAlpha
+		}
+	case 2:
+		info.ColorModel = RGB
+		if samples > 3 {
+			info.ColorModel = RGBA
+		}
+	case 3:
+		info.ColorModel = Paletted
+	case 5:
+		info.ColorModel = CMYK
+	case 6:
+		info.ColorModel = YCbCr
+	}
+	info.Frames = 1
+	return nil
+}
+
+// decodeICO usa a maior imagem do diretório do ícone
+func decodeICO(head []byte, info *Info) error {
+	count := int(binary.LittleEndian.Uint16(head[4:6]))
+	for i := 0; i < count && 6+16*(i+1) <= len(head); i++ {
+		entry := head[6+16*i:]
+		// 0 significa 256 pixels
+		w, h := int(entry[0]), int(entry[1])
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+		if w*h > info.Width*info.Height {
+			info.Width, info.Height = w, h
+			info.Depth = int(binary.LittleEndian.Uint16(entry[6:8]))
+		}
+	}
+	if info.Width == 0 {
+		return errInvalid
+	}
+	info.ColorModel = RGBA
+	if info.Depth > 0 && info.Depth <= 8 {
+		info.ColorModel = Paletted
+	} else {
+		info.Depth = 8
+	}
+	info.Frames = 1
+	return nil
+}
+
+// decodeAVIF procura as propriedades ispe (dimensões) e pixi (canais e
+// profundidade) em meta/iprp/ipco
+func decodeAVIF(r io.ReaderAt, size int64, info *Info) error {
+	path := []string{"meta", "iprp", "ipco"}
+	start, end := int64(0), size
+	header := make([]byte, 16)
+
+	for level := 0; level <= len(path); level++ {
+		found := false
+		for i, offset := 0, start; i < maxChunks && offset+8 <= end; i++ {
+			if _, err := r.ReadAt(header[:8], offset); err != nil {
+				return errInvalid
+			}
+			length := int64(binary.BigEndian.Uint32(header[0:4]))
+			typ := string(header[4:8])
+			if length == 0 {
+				length = end - offset
+			}
+			if length < 8 {
+				return errInvalid
+			}
+
+			if level == len(path) {
+				// Propriedades de ipco (as primeiras se aplicam à imagem principal)
+				switch {
+				case typ == "ispe" && info.Width == 0 && length >= 20:
+					if _, err := r.ReadAt(header, offset+8); err == nil {
+						info.Width = int(binary.BigEndian.Uint32(header[4:8]))
+						info.Height = int(binary.BigEndian.Uint32(header[8:12]))
+					}
+				case typ == "pixi" && info.Depth == 0 && length >= 14:
+					if _, err := r.ReadAt(header[:6], offset+8); err == nil {
+						info.Depth = int(header[5])
+						info.ColorModel = YCbCr
+						if header[4] == 1 {
+							info.ColorModel = Gray
+						}
+					}
+				}
+			} else if typ == path[level] {
+				start, end = offset+8, offset+length
+				if typ == "meta" {
+					start += 4 // Versão e flags
+				}
+				found = true
+				break
+			}
+			offset += length
+		}
+		if level < len(path) && !found {
+			return errInvalid
+		}
+	}
+
+	if info.Width == 0 {
+		return errInvalid
+	}
+	return nil
+}
+
+// decodeSVG lê width e height do elemento raiz, ou o viewBox se eles
+// estiverem ausentes ou usarem unidades relativas
+func decodeSVG(r io.ReaderAt, info *Info) error {
+	dec := xml.NewDecoder(io.NewSectionReader(r, 0, maxSVGTag))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return errInvalid
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if el.Name.Local != "svg" {
+			return errInvalid
+		}
+
+		var viewBox []string
+		for _, attr := range el.Attr {
+			switch attr.Name.Local {
+			case "width":
+				info.Width = svgLength(attr.Value)
+			case "height":
+				info.Height = svgLength(attr.Value)
+			case "viewBox":
+				viewBox = strings.FieldsFunc(attr.Value, func(r rune) bool { return r == ',' || r == ' ' })
+			}
+		}
+		if (info.Width == 0 || info.Height == 0) && len(viewBox) == 4 {
+			w, h := svgLength(viewBox[2]), svgLength(viewBox[3])
+			switch {
+			case info.Width == 0 && info.Height == 0:
+				info.Width, info.Height = w, h
+			case info.Width == 0 && h > 0:
+				info.Width = info.Height * w / h
+			case info.Height == 0 && w > 0:
+				info.Height = info.Width * h / w
+			}
+		}
+		info.Frames = 1
+		return nil
+	}
+}
+
+// svgLength converte um comprimento SVG absoluto em pixels (0 se relativo)
+func svgLength(value string) int {
+	value = strings.TrimSpace(value)
+	scale := 1.0
+	for _, unit := range []struct {
+		suffix string
+		px     float64
+	}{{"px", 1}, {"pt", 4.0 / 3}, {"pc", 16}, {"in", 96}, {"cm", 96 / 2.54}, {"mm", 96 / 25.4}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, scale = strings.TrimSuffix(value, unit.suffix), unit.px
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 || n > 1<<20 {
+		return 0
+	}
+	return int(n*scale + 0.5)
+}
@@ -0,0 +1,274 @@
+package imageinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/verseles/sidelook/internal/imagetype"
+)
+
+func encodePNG(img image.Image) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func encodeJPEG(img image.Image) []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, nil)
+	return buf.Bytes()
+}
+
+func encodeGIF(frames int) []byte {
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 30, 20), palette.Plan9))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	gif.EncodeAll(&buf, anim)
+	return buf.Bytes()
+}
+
+// insertAcTL insere um chunk acTL declarando frames quadros (sem CRC
+// válido: o pacote não verifica)
+func insertAcTL(data []byte, frames uint32) []byte {
+	chunk := make([]byte, 20)
+	binary.BigEndian.PutUint32(chunk[0:4], 8)
+	copy(chunk[4:8], "acTL")
+	binary.BigEndian.PutUint32(chunk[8:12], frames)
+	out := append([]byte(nil), data[:33]...) // Assinatura + IHDR
+	out = append(out, chunk...)
+	return append(out, data[33:]...)
+}
+
+// insertText insere chunks tEXt (sem CRC válido, que não é verificado) depois
+// do IHDR
+func insertText(data []byte, keys ...string) []byte {
+	out := append([]byte(nil), data[:33]...)
+	for _, k := range keys {
+		text := k + "\x00{}"
+		out = binary.BigEndian.AppendUint32(out, uint32(len(text)))
+		out = append(out, "tEXt"+text...)
+		out = append(out, 0, 0, 0, 0)
+	}
+	return append(out, data[33:]...)
+}
+
+// riff monta um contêiner WebP com os chunks informados
+func riff(chunks ...[]byte) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, c := range chunks {
+		body.Write(c)
+	}
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(body.Len()))
+	b.Write(body.Bytes())
+	return b.Bytes()
+}
+
+func chunk(typ string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(typ)
+	binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	if len(data)%2 == 1 {
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+func vp8(w, h int) []byte {
+	data := []byte{0x10, 0x02, 0x00, 0x9D, 0x01, 0x2A, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(data[6:8], uint16(w))
+	binary.LittleEndian.PutUint16(data[8:10], uint16(h))
+	return chunk("VP8 ", data)
+}
+
+func vp8l(w, h int, alpha bool) []byte {
+	bits := uint32(w-1) | uint32(h-1)<<14
+	if alpha {
+		bits |= 1 << 28
+	}
+	data := []byte{0x2F, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(data[1:5], bits)
+	return chunk("VP8L", data)
+}
+
+func vp8x(w, h int, flags byte) []byte {
+	data := make([]byte, 10)
+	data[0] = flags
+	w, h = w-1, h-1
+	data[4], data[5], data[6] = byte(w), byte(w>>8), byte(w>>16)
+	data[7], data[8], data[9] = byte(h), byte(h>>8), byte(h>>16)
+	return chunk("VP8X", data)
+}
+
+func bmp(w, h int32, bpp uint16) []byte {
+	b := make([]byte, 54)
+	copy(b, "BM")
+	binary.LittleEndian.PutUint32(b[14:18], 40)
+	binary.LittleEndian.PutUint32(b[18:22], uint32(w))
+	binary.LittleEndian.PutUint32(b[22:26], uint32(h))
+	binary.LittleEndian.PutUint16(b[26:28], 1)
+	binary.LittleEndian.PutUint16(b[28:30], bpp)
+	return b
+}
+
+// tiff monta um TIFF little-endian com as tags informadas (todas SHORT)
+func tiff(tags map[uint16]uint16) []byte {
+	le := binary.LittleEndian
+	var b bytes.Buffer
+	b.WriteString("II")
+	binary.Write(&b, le, uint16(42))
+	binary.Write(&b, le, uint32(8))
+	binary.Write(&b, le, uint16(len(tags)))
+	for _, id := range []uint16{256, 257, 258, 262, 274, 277} {
+		if v, ok := tags[id]; ok {
+			binary.Write(&b, le, id)
+			binary.Write(&b, le, uint16(3))
+			binary.Write(&b, le, uint32(1))
+			binary.Write(&b, le, v)
+			binary.Write(&b, le, uint16(0))
+		}
+	}
+	binary.Write(&b, le, uint32(0))
+	return b.Bytes()
+}
+
+func ico(sizes ...byte) []byte {
+	b := []byte{0, 0, 1, 0, byte(len(sizes)), 0}
+	for _, s := range sizes {
+		entry := make([]byte, 16)
+		entry[0], entry[1] = s, s
+		binary.LittleEndian.PutUint16(entry[6:8], 32)
+		b = append(b, entry...)
+	}
+	return b
+}
+
+// avif monta ftyp + meta/iprp/ipco com ispe e pixi
+func avif(w, h uint32, channels byte) []byte {
+	box := func(typ string, payload ...[]byte) []byte {
+		body := bytes.Join(payload, nil)
+		b := make([]byte, 8, 8+len(body))
+		binary.BigEndian.PutUint32(b[0:4], uint32(8+len(body)))
+		copy(b[4:8], typ)
+		return append(b, body...)
+	}
+	ispe := make([]byte, 12)
+	binary.BigEndian.PutUint32(ispe[4:8], w)
+	binary.BigEndian.PutUint32(ispe[8:12], h)
+	pixi := []byte{0, 0, 0, 0, channels}
+	for i := byte(0); i < channels; i++ {
+		pixi = append(pixi, 10)
+	}
+
+	ftyp := box("ftyp", []byte("avif\x00\x00\x00\x00avifmif1"))
+	ipco := box("ipco", box("ispe", ispe), box("pixi", pixi))
+	meta := box("meta", []byte{0, 0, 0, 0}, box("hdlr", make([]byte, 20)), box("iprp", ipco))
+	return append(ftyp, meta...)
+}
+
+func TestDecode(t *testing.T) {
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	nrgba.Set(0, 0, color.NRGBA{A: 10})
+	gray16 := image.NewGray16(image.Rect(0, 0, 7, 5))
+	paletted := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Black, color.White})
+
+	tests := []struct {
+		name string
+		data []byte
+		want Info
+	}{
+		{"png rgba", encodePNG(nrgba), Info{imagetype.PNG, 40, 30, RGBA, 8, 1, 1, nil}},
+		{"png gray16", encodePNG(gray16), Info{imagetype.PNG, 7, 5, Gray, 16, 1, 1, nil}},
+		{"png paletted", encodePNG(paletted), Info{imagetype.PNG, 8, 8, Paletted, 1, 1, 1, nil}},
+		{"png com textos", insertText(encodePNG(nrgba), "prompt", "workflow"), Info{imagetype.PNG, 40, 30, RGBA, 8, 1, 1, []string{"prompt", "workflow"}}},
+		{"apng", insertAcTL(encodePNG(nrgba), 12), Info{imagetype.PNG, 40, 30, RGBA, 8, 12, 1, nil}},
+		{"jpeg", encodeJPEG(image.NewRGBA(image.Rect(0, 0, 33, 17))), Info{imagetype.JPEG, 33, 17, YCbCr, 8, 1, 1, nil}},
+		{"jpeg gray", encodeJPEG(image.NewGray(image.Rect(0, 0, 9, 4))), Info{imagetype.JPEG, 9, 4, Gray, 8, 1, 1, nil}},
+		{"gif", encodeGIF(1), Info{imagetype.GIF, 30, 20, Paletted, 8, 1, 0, nil}},
+		{"gif animado", encodeGIF(5), Info{imagetype.GIF, 30, 20, Paletted, 8, 5, 0, nil}},
+		{"webp lossy", riff(vp8(640, 480)), Info{imagetype.WebP, 640, 480, YCbCr, 8, 1, 1, nil}},
+		{"webp lossless", riff(vp8l(300, 200, true)), Info{imagetype.WebP, 300, 200, RGBA, 8, 1, 1, nil}},
+		{"webp estendido", riff(vp8x(5000, 70000, 0x10), chunk("ALPH", []byte{0}), vp8(5000, 7000)), Info{imagetype.WebP, 5000, 70000, YCbCrA, 8, 1, 1, nil}},
+		{"webp estendido lossless", riff(vp8x(20, 10, 0), vp8l(20, 10, false)), Info{imagetype.WebP, 20, 10, RGB, 8, 1, 1, nil}},
+		{"webp animado", riff(vp8x(64, 64, 0x12), chunk("ANIM", make([]byte, 6)), chunk("ANMF", make([]byte, 17)), chunk("ANMF", make([]byte, 16)), chunk("ANMF", make([]byte, 16))), Info{imagetype.WebP, 64, 64, YCbCrA, 8, 3, 1, nil}},
+		{"bmp", bmp(120, -80, 24), Info{imagetype.BMP, 120, 80, RGB, 8, 1, 0, nil}},
+		{"bmp 8 bits", bmp(16, 16, 8), Info{imagetype.BMP, 16, 16, Paletted, 8, 1, 0, nil}},
+		{"tiff rgb", tiff(map[uint16]uint16{256: 1024, 257: 768, 258: 16, 262: 2, 277: 3}), Info{imagetype.TIFF, 1024, 768, RGB, 16, 1, 1, nil}},
+		{"tiff gray", tiff(map[uint16]uint16{256: 10, 257: 20, 262: 1}), Info{imagetype.TIFF, 10, 20, Gray, 1, 1, 1, nil}},
+		{"tiff girada", tiff(map[uint16]uint16{256: 40, 257: 30, 262: 1, 274: 6}), Info{imagetype.TIFF, 40, 30, Gray, 1, 1, 6, nil}},
+		{"ico", ico(16, 0, 48), Info{imagetype.ICO, 256, 256, RGBA, 8, 1, 0, nil}},
+		{"avif", avif(1920, 1080, 3), Info{imagetype.AVIF, 1920, 1080, YCbCr, 10, 0, 0, nil}},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="120px" height="2in"/>`), Info{imagetype.SVG, 120, 192, "", 0, 1, 0, nil}},
+		{"svg viewBox", []byte(`<?xml version="1.0"?><!-- x --><svg viewBox="0 0 300 150" width="100%"></svg>`), Info{imagetype.SVG, 300, 150, "", 0, 1, 0, nil}},
+		{"svg só largura", []byte(`<svg width="600" viewBox="0,0,300,150"></svg>`), Info{imagetype.SVG, 600, 300, "", 0, 1, 0, nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfo_DisplaySize(t *testing.T) {
+	for _, tt := range []struct {
+		orientation  int
+		wantW, wantH int
+	}{{0, 40, 30}, {1, 40, 30}, {3, 40, 30}, {6, 30, 40}, {8, 30, 40}} {
+		info := Info{Width: 40, Height: 30, Orientation: tt.orientation}
+		if w, h := info.DisplaySize(); w != tt.wantW || h != tt.wantH {
+			t.Errorf("DisplaySize(orientação %d) = %dx%d, want %dx%d", tt.orientation, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	if _, err := Decode(bytes.NewReader([]byte("texto")), 5); err != ErrUnknown {
+		t.Errorf("texto: error = %v, want ErrUnknown", err)
+	}
+
+	// Formato reconhecido, cabeçalho truncado: erro com o formato preenchido
+	info, err := Decode(bytes.NewReader([]byte("\x89PNG\r\n\x1a\n")), 8)
+	if err == nil || info.Format != imagetype.PNG {
+		t.Errorf("PNG truncado = %+v, %v, want formato e erro", info, err)
+	}
+	info, err = Decode(bytes.NewReader([]byte{0xFF, 0xD8, 0xFF, 0xDA, 0, 2}), 6)
+	if err == nil || info.Format != imagetype.JPEG {
+		t.Errorf("JPEG sem SOF = %+v, %v, want formato e erro", info, err)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anim.gif")
+	os.WriteFile(path, encodeGIF(3), 0644)
+
+	info, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !info.Animated() || info.Frames != 3 {
+		t.Errorf("ReadFile() = %+v, want 3 quadros", info)
+	}
+}
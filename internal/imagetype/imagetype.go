@@ -0,0 +1,202 @@
+// Package imagetype identifica formatos de imagem pelos primeiros bytes do
+// arquivo (magic bytes), independentemente da extensão
+package imagetype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// HeaderSize é quantos bytes do início do arquivo Detect precisa. SVGs
+// exportados por editores costumam ter comentários e DOCTYPE antes de <svg>.
+const HeaderSize = 4096
+
+// Format é um formato de imagem reconhecido ("" = desconhecido)
+type Format string
+
+const (
+	PNG  Format = "png"
+	JPEG Format = "jpeg"
+	GIF  Format = "gif"
+	WebP Format = "webp"
+	BMP  Format = "bmp"
+	TIFF Format = "tiff"
+	SVG  Format = "svg"
+	AVIF Format = "avif"
+	JXL  Format = "jxl"
+	ICO  Format = "ico"
+)
+
+// mimeTypes associa cada formato ao seu tipo MIME
+var mimeTypes = map[Format]string{
+	PNG:  "image/png",
+	JPEG: "image/jpeg",
+	GIF:  "image/gif",
+	WebP: "image/webp",
+	BMP:  "image/bmp",
+	TIFF: "image/tiff",
+	SVG:  "image/svg+xml",
+	AVIF: "image/avif",
+	JXL:  "image/jxl",
+	ICO:  "image/x-icon",
+}
+
+// extensions associa extensões de arquivo aos formatos detectáveis
+var extensions = map[string]Format{
+	".png":  PNG,
+	".jpg":  JPEG,
+	".jpeg": JPEG,
+	".gif":  GIF,
+	".webp": WebP,
+	".bmp":  BMP,
+	".tif":  TIFF,
+	".tiff": TIFF,
+	".svg":  SVG,
+	".avif": AVIF,
+	".jxl":  JXL,
+	".ico":  ICO,
+}
+
+// MIME retorna o tipo MIME do formato ("" se desconhecido)
+func (f Format) MIME() string {
+	return mimeTypes[f]
+}
+
+// Extension retorna a extensão canônica do formato (".png", ".jpg"...)
+func (f Format) Extension() string {
+	switch f {
+	case "":
+		return ""
+	case JPEG:
+		return ".jpg"
+	case TIFF:
+		return ".tif"
+	}
+	return "." + string(f)
+}
+
+// FromExtension retorna o formato associado a uma extensão (".png", ".JPG"),
+// ou "" se a extensão não corresponder a um formato detectável
+func FromExtension(ext string) Format {
+	return extensions[strings.ToLower(ext)]
+}
+
+// Assinaturas fixas no início do arquivo
+var (
+	sigPNG          = []byte("\x89PNG\r\n\x1a\n")
+	sigJPEG         = []byte{0xFF, 0xD8, 0xFF}
+	sigGIF87        = []byte("GIF87a")
+	sigGIF89        = []byte("GIF89a")
+	sigTIFFLE       = []byte("II*\x00")
+	sigTIFFBE       = []byte("MM\x00*")
+	sigBigTIFFLE    = []byte("II+\x00")
+	sigBigTIFFBE    = []byte("MM\x00+")
+	sigJXLCode      = []byte{0xFF, 0x0A}
+	sigJXLContainer = []byte{0x00, 0x00, 0x00, 0x0C, 'J', 'X', 'L', ' ', 0x0D, 0x0A, 0x87, 0x0A}
+	sigICO          = []byte{0x00, 0x00, 0x01, 0x00}
+	utf8BOM         = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// Detect identifica o formato a partir dos primeiros bytes do arquivo
+// (idealmente HeaderSize). Retorna "" se o conteúdo não for uma imagem conhecida.
+func Detect(head []byte) Format {
+	switch {
+	case bytes.HasPrefix(head, sigPNG):
+		return PNG
+	case bytes.HasPrefix(head, sigJPEG):
+		return JPEG
+	case bytes.HasPrefix(head, sigGIF87), bytes.HasPrefix(head, sigGIF89):
+		return GIF
+	case len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return WebP
+	case bytes.HasPrefix(head, sigTIFFLE), bytes.HasPrefix(head, sigTIFFBE),
+		bytes.HasPrefix(head, sigBigTIFFLE), bytes.HasPrefix(head, sigBigTIFFBE):
+		return TIFF
+	case bytes.HasPrefix(head, sigJXLCode), bytes.HasPrefix(head, sigJXLContainer):
+		return JXL
+	case isBMP(head):
+		return BMP
+	case isICO(head):
+		return ICO
+	case isAVIF(head):
+		return AVIF
+	case isSVG(head):
+		return SVG
+	}
+	return ""
+}
+
+// DetectFile lê o início do arquivo e identifica o formato
+func DetectFile(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, HeaderSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return Detect(head[:n]), nil
+}
+
+// isBMP confere a assinatura "BM" e o tamanho do cabeçalho DIB, já que "BM"
+// sozinho é comum em arquivos de texto
+func isBMP(head []byte) bool {
+	if len(head) < 18 || head[0] != 'B' || head[1] != 'M' {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(head[14:18]) {
+	case 12, 40, 52, 56, 64, 108, 124:
+		return true
+	}
+	return false
+}
+
+// isICO confere a assinatura e exige ao menos uma imagem no diretório
+func isICO(head []byte) bool {
+	return len(head) >= 6 && bytes.HasPrefix(head, sigICO) &&
+		binary.LittleEndian.Uint16(head[4:6]) > 0
+}
+
+// isAVIF procura as marcas "avif" ou "avis" no box ftyp do ISOBMFF
+func isAVIF(head []byte) bool {
+	if len(head) < 16 || string(head[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(binary.BigEndian.Uint32(head[0:4]))
+	if size < 16 || size > len(head) {
+		size = len(head)
+	}
+
+	// Marca principal (8-12) e compatíveis (16 em diante); 12-16 é a versão
+	for i := 8; i+4 <= size; i += 4 {
+		if i == 12 {
+			continue
+		}
+		if brand := string(head[i : i+4]); brand == "avif" || brand == "avis" {
+			return true
+		}
+	}
+	return false
+}
+
+// isSVG reconhece documentos XML cujo elemento raiz é <svg>
+func isSVG(head []byte) bool {
+	text := bytes.TrimPrefix(head, utf8BOM)
+	text = bytes.TrimLeft(text, " \t\r\n")
+	lower := bytes.ToLower(text)
+
+	for _, prefix := range []string{"<svg", "<?xml", "<!--", "<!doctype svg"} {
+		if bytes.HasPrefix(lower, []byte(prefix)) {
+			return bytes.Contains(lower, []byte("<svg"))
+		}
+	}
+	return false
+}
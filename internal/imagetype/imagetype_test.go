@@ -0,0 +1,83 @@
+package imagetype
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want Format
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), PNG},
+		{"jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F'}, JPEG},
+		{"gif87", []byte("GIF87a\x01\x00"), GIF},
+		{"gif89", []byte("GIF89a\x01\x00"), GIF},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), WebP},
+		{"riff wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), ""},
+		{"bmp", append([]byte("BM\x46\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00"), 40, 0, 0, 0), BMP},
+		{"texto com BM", []byte("BMW é uma marca de carros alemã"), ""},
+		{"tiff le", []byte("II*\x00\x08\x00\x00\x00"), TIFF},
+		{"tiff be", []byte("MM\x00*\x00\x00\x00\x08"), TIFF},
+		{"bigtiff", []byte("II+\x00\x08\x00\x00\x00"), TIFF},
+		{"jxl codestream", []byte{0xFF, 0x0A, 0xFA, 0x1F}, JXL},
+		{"jxl container", []byte{0x00, 0x00, 0x00, 0x0C, 'J', 'X', 'L', ' ', 0x0D, 0x0A, 0x87, 0x0A}, JXL},
+		{"ico", []byte{0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x10, 0x10}, ICO},
+		{"ico vazio", []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x00}, ""},
+		{"avif", []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf"), AVIF},
+		{"avif compatível", []byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00avifmiaf"), AVIF},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"), ""},
+		{"mp4", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2"), ""},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), SVG},
+		{"svg com prólogo", []byte("\xEF\xBB\xBF\n<?xml version=\"1.0\"?>\n<!-- Inkscape -->\n<svg>"), SVG},
+		{"svg doctype", []byte(`<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN"><svg/>`), SVG},
+		{"xml qualquer", []byte(`<?xml version="1.0"?><rss></rss>`), ""},
+		{"html", []byte(`<!doctype html><html></html>`), ""},
+		{"vazio", nil, ""},
+		{"texto", []byte("hello world"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.head); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// JPEG com extensão .png
+	path := filepath.Join(dir, "fake.png")
+	os.WriteFile(path, []byte{0xFF, 0xD8, 0xFF, 0xDB}, 0644)
+	got, err := DetectFile(path)
+	if err != nil || got != JPEG {
+		t.Errorf("DetectFile() = %q, %v, want jpeg", got, err)
+	}
+	if got.MIME() != "image/jpeg" {
+		t.Errorf("MIME() = %q, want image/jpeg", got.MIME())
+	}
+
+	if _, err := DetectFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("DetectFile() de arquivo inexistente deveria falhar")
+	}
+}
+
+func TestFromExtension(t *testing.T) {
+	if got := FromExtension(".JPG"); got != JPEG {
+		t.Errorf("FromExtension(.JPG) = %q, want jpeg", got)
+	}
+	for _, f := range []Format{PNG, JPEG, GIF, WebP, BMP, TIFF, SVG, AVIF, JXL, ICO} {
+		if got := FromExtension(f.Extension()); got != f {
+			t.Errorf("FromExtension(%s) = %q, want %q", f.Extension(), got, f)
+		}
+	}
+	if got := FromExtension(".heic"); got != "" {
+		t.Errorf("FromExtension(.heic) = %q, want vazio", got)
+	}
+}
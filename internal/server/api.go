@@ -0,0 +1,441 @@
+// internal/server/api.go
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/verseles/sidelook/internal/exif"
+	"github.com/verseles/sidelook/internal/textmeta"
+	"github.com/verseles/sidelook/internal/version"
+	"github.com/verseles/sidelook/internal/watcher"
+)
+
+const (
+	// defaultPageSize e maxPageSize limitam a listagem de /api/v1/images
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// apiImage é uma imagem nas respostas da API
+type apiImage struct {
+	Path    string `json:"path"`
+	Source  string `json:"source,omitempty"`
+	URL     string `json:"url"`
+	Thumb   string `json:"thumb"`
+	Version string `json:"version"`
+	metadata
+}
+
+// apiImageDetail é a resposta de /api/v1/images/{path}
+type apiImageDetail struct {
+	apiImage
+	Current   bool `json:"current"`
+	Slideshow bool `json:"slideshow"`
+}
+
+// apiImageExif é a resposta de /api/v1/images/{path}/exif. Exif e XMP
+// trazem as tags pelo nome, já formatadas como texto.
+type apiImageExif struct {
+	Path        string            `json:"path"`
+	Version     string            `json:"version"`
+	Orientation int               `json:"orientation"`
+	Exif        map[string]string `json:"exif"`
+	XMP         map[string]string `json:"xmp"`
+	GPS         *apiGPS           `json:"gps,omitempty"`
+}
+
+// apiGPS é a localização em graus decimais (negativa ao sul e a oeste)
+type apiGPS struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// apiImageText é a resposta de /api/v1/images/{path}/text
+type apiImageText struct {
+	Path    string           `json:"path"`
+	Version string           `json:"version"`
+	Text    []textmeta.Entry `json:"text"`
+}
+
+// apiImageList é a resposta de /api/v1/images
+type apiImageList struct {
+	Total  int        `json:"total"`
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+	Images []apiImage `json:"images"`
+}
+
+// apiSource é um diretório monitorado em /api/v1/status
+type apiSource struct {
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+}
+
+// apiStatus é a resposta de /api/v1/status
+type apiStatus struct {
+	Version     string      `json:"version"`
+	Directories []apiSource `json:"directories"`
+	Images      int         `json:"images"`
+	Clients     int         `json:"clients"`
+	StartedAt   time.Time   `json:"started_at"`
+	Uptime      float64     `json:"uptime"` // Em segundos
+	Health      string      `json:"health"`
+	Message     string      `json:"message,omitempty"`
+	Polling     bool        `json:"polling"`
+}
+
+// registerAPIRoutes registra os handlers da API JSON
+func (s *Server) registerAPIRoutes() {
+	s.mux.HandleFunc("/api/v1/images", s.apiGet(s.handleAPIImages))
+	s.mux.HandleFunc("/api/v1/images/", s.apiGet(s.handleAPIImage))
+	s.mux.HandleFunc("/api/v1/current", s.apiGet(s.handleAPICurrent))
+	s.mux.HandleFunc("/api/v1/slideshow", s.apiGet(s.handleAPISlideshow))
+	s.mux.HandleFunc("/api/v1/status", s.apiGet(s.handleAPIStatus))
+}
+
+// apiGet aceita apenas GET (e HEAD) no handler
+func (s *Server) apiGet(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeAPIError(w, http.StatusMethodNotAllowed, "método não permitido")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// writeJSON escreve v como resposta JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// writeAPIError escreve um erro no formato {"error": "..."}
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// handleAPIImages lista as imagens conhecidas com filtros, ordenação e paginação:
+//
+//	q=texto          caminho contém o texto (sem diferenciar maiúsculas)
+//	source=nome      apenas imagens desse diretório de origem
+//	ext=png,jpg      apenas essas extensões
+//	since=, until=   mtime dentro do intervalo (RFC 3339)
+//	sort=            recent (padrão), name, mtime ou size
+//	order=           asc ou desc (padrão: desc, exceto name)
+//	offset=, limit=  paginação (limit padrão 100, máximo 1000)
+func (s *Server) handleAPIImages(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	offset, err := queryInt(q, "offset", 0, 0, -1)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := queryInt(q, "limit", defaultPageSize, 1, maxPageSize)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var since, until time.Time
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &since}, {"until", &until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeAPIError(w, http.StatusBadRequest, p.name+" deve estar no formato RFC 3339 (ex: 2026-10-16T12:00:00Z)")
+				return
+			}
+			*p.dst = t
+		}
+	}
+
+	sortBy := q.Get("sort")
+	switch sortBy {
+	case "":
+		sortBy = "recent"
+	case "recent", "name", "mtime", "size":
+	default:
+		writeAPIError(w, http.StatusBadRequest, "sort deve ser recent, name, mtime ou size")
+		return
+	}
+	desc := sortBy != "name"
+	switch q.Get("order") {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		writeAPIError(w, http.StatusBadRequest, "order deve ser asc ou desc")
+		return
+	}
+
+	exts := map[string]bool{}
+	for _, e := range strings.Split(q.Get("ext"), ",") {
+		if e = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(e), ".")); e != "" {
+			exts["."+e] = true
+		}
+	}
+	text := strings.ToLower(q.Get("q"))
+	source := q.Get("source")
+
+	// Filtrar (as imagens já vêm da mais recente para a mais antiga)
+	type candidate struct {
+		img *watcher.ImageInfo
+		rel string
+	}
+	var matches []candidate
+	for _, img := range s.watcher.Images() {
+		rel := s.watcher.Relative(img.Path)
+		switch {
+		case text != "" && !strings.Contains(strings.ToLower(rel), text),
+			source != "" && s.watcher.SourceName(rel) != source,
+			len(exts) > 0 && !exts[strings.ToLower(path.Ext(rel))],
+			!since.IsZero() && img.ModTime.Before(since),
+			!until.IsZero() && img.ModTime.After(until):
+			continue
+		}
+		matches = append(matches, candidate{img: img, rel: rel})
+	}
+
+	// Ordenar (recent já está na ordem do watcher)
+	switch sortBy {
+	case "name":
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].rel < matches[j].rel })
+	case "mtime":
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].img.ModTime.After(matches[j].img.ModTime)
+		})
+	case "size":
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].img.Size > matches[j].img.Size })
+	}
+	if (sortBy == "name") == desc {
+		for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+			matches[i], matches[j] = matches[j], matches[i]
+		}
+	}
+
+	list := apiImageList{Total: len(matches), Offset: offset, Limit: limit, Images: []apiImage{}}
+	for i := offset; i < len(matches) && i < offset+limit; i++ {
+		if desc, ok := s.describeImage(matches[i].rel); ok {
+			list.Images = append(list.Images, desc)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleAPIImage retorna os metadados de uma imagem (/api/v1/images/{path})
+// ou, com os sufixos /exif e /text, suas tags EXIF e XMP e seus textos
+// embutidos
+func (s *Server) handleAPIImage(w http.ResponseWriter, r *http.Request) {
+	rel := strings.TrimPrefix(r.URL.Path, "/api/v1/images/")
+	desc, ok := s.describeImage(rel)
+	if !ok {
+		// Sub-recursos da imagem: /exif e /text
+		if i := strings.LastIndex(rel, "/"); i >= 0 {
+			switch rel[i+1:] {
+			case "exif":
+				s.handleAPIImageExif(w, rel[:i])
+				return
+			case "text":
+				s.handleAPIImageText(w, rel[:i])
+				return
+			}
+		}
+		writeAPIError(w, http.StatusNotFound, "imagem não encontrada")
+		return
+	}
+
+	rel = desc.Path
+	detail := apiImageDetail{
+		apiImage: desc,
+		Current:  rel == s.watcher.CurrentImageRelative(),
+	}
+	for _, p := range s.watcher.RecentImagesRelative() {
+		if p == rel {
+			detail.Slideshow = true
+			break
+		}
+	}
+
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// handleAPIImageExif retorna as tags EXIF e XMP de uma imagem. Formatos sem
+// esses metadados (ou arquivos sem eles) respondem com os mapas vazios.
+func (s *Server) handleAPIImageExif(w http.ResponseWriter, rel string) {
+	img, ok := s.watcher.Image(rel)
+	desc, described := s.describeImage(rel)
+	if !ok || !described {
+		writeAPIError(w, http.StatusNotFound, "imagem não encontrada")
+		return
+	}
+
+	resp := apiImageExif{
+		Path:        desc.Path,
+		Version:     desc.Version,
+		Orientation: 1,
+		Exif:        map[string]string{},
+		XMP:         map[string]string{},
+	}
+	if e, err := exif.ReadFile(img.Path); err == nil {
+		resp.Exif = e.Fields()
+		resp.Orientation = e.Orientation()
+		if lat, lon, ok := e.GPS(); ok {
+			resp.GPS = &apiGPS{Latitude: lat, Longitude: lon}
+		}
+	}
+	if props, err := exif.ReadXMP(img.Path); err == nil {
+		resp.XMP = props
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAPIImageText retorna os textos embutidos em uma imagem (prompt, seed e
+// workflow de imagens de IA generativa...), na ordem do arquivo
+func (s *Server) handleAPIImageText(w http.ResponseWriter, rel string) {
+	img, ok := s.watcher.Image(rel)
+	desc, described := s.describeImage(rel)
+	if !ok || !described {
+		writeAPIError(w, http.StatusNotFound, "imagem não encontrada")
+		return
+	}
+
+	entries, err := textmeta.ReadFile(img.Path)
+	if entries == nil {
+		if os.IsNotExist(err) {
+			writeAPIError(w, http.StatusNotFound, "imagem não encontrada")
+			return
+		}
+		entries = []textmeta.Entry{}
+	}
+	writeJSON(w, http.StatusOK, apiImageText{Path: desc.Path, Version: desc.Version, Text: entries})
+}
+
+// handleAPICurrent retorna a imagem exibida ({"image": null} se nenhuma)
+func (s *Server) handleAPICurrent(w http.ResponseWriter, r *http.Request) {
+	resp := struct {
+		Image *apiImage `json:"image"`
+	}{}
+	if desc, ok := s.describeImage(s.watcher.CurrentImageRelative()); ok {
+		resp.Image = &desc
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAPISlideshow retorna a configuração e a lista atual do slideshow
+func (s *Server) handleAPISlideshow(w http.ResponseWriter, r *http.Request) {
+	resp := struct {
+		Enabled  bool       `json:"enabled"`
+		Interval int        `json:"interval"` // Em segundos
+		Images   []apiImage `json:"images"`
+	}{Interval: s.slideshowInterval, Images: []apiImage{}}
+
+	for _, rel := range s.watcher.RecentImagesRelative() {
+		resp.Enabled = true
+		if desc, ok := s.describeImage(rel); ok {
+			resp.Images = append(resp.Images, desc)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAPIStatus retorna o estado do servidor e do monitoramento
+func (s *Server) handleAPIStatus(w http.ResponseWriter, r *http.Request) {
+	s.clientsMu.RLock()
+	clients := len(s.clients)
+	s.clientsMu.RUnlock()
+
+	st := s.watcher.Status()
+	resp := apiStatus{
+		Version:     version.Version,
+		Directories: []apiSource{},
+		Images:      s.watcher.ImageCount(),
+		Clients:     clients,
+		StartedAt:   s.started,
+		Uptime:      time.Since(s.started).Round(time.Second).Seconds(),
+		Health:      string(st.Health),
+		Message:     st.Message,
+		Polling:     s.watcher.Polling(),
+	}
+	for _, src := range s.watcher.Sources() {
+		resp.Directories = append(resp.Directories, apiSource{Name: src.Name, Dir: src.Dir})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// describeImage monta a descrição de uma imagem pelo caminho relativo. Tamanho
+// e data vêm do disco, para que a versão bata com a servida em /image/.
+func (s *Server) describeImage(rel string) (apiImage, bool) {
+	img, ok := s.watcher.Describe(rel)
+	if !ok {
+		return apiImage{}, false
+	}
+	info, err := os.Stat(img.Path)
+	if err != nil {
+		return apiImage{}, false // Removida depois da listagem
+	}
+
+	rel = s.watcher.Relative(img.Path)
+	v := versionOf(info)
+	escaped := escapePath(rel)
+	desc := apiImage{
+		Path:     rel,
+		Source:   s.watcher.SourceName(rel),
+		URL:      "/image/" + escaped + "?v=" + url.QueryEscape(v),
+		Thumb:    "/thumb/" + escaped + "?v=" + url.QueryEscape(v),
+		Version:  v,
+		metadata: metaOf(img),
+	}
+	desc.Size, desc.ModTime = info.Size(), info.ModTime()
+	return desc, true
+}
+
+// escapePath escapa cada segmento de um caminho relativo para uso em URLs
+func escapePath(rel string) string {
+	parts := strings.Split(rel, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// queryInt lê um parâmetro inteiro entre lo e hi (hi < 0 = sem limite)
+func queryInt(q url.Values, name string, def, lo, hi int) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < lo || (hi >= 0 && n > hi) {
+		if hi < 0 {
+			return 0, fmt.Errorf("%s deve ser um número >= %d", name, lo)
+		}
+		return 0, fmt.Errorf("%s deve estar entre %d e %d", name, lo, hi)
+	}
+	return n, nil
+}
@@ -0,0 +1,348 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/verseles/sidelook/internal/textmeta"
+	"github.com/verseles/sidelook/internal/watcher"
+)
+
+// apiFixture cria imagens com mtimes crescentes (a última é a mais recente)
+func apiFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+
+	files := []struct {
+		name string
+		w, h int
+	}{
+		{"b_small.png", 10, 5},
+		{"sub/a_big.png", 300, 200},
+		{"c_mid.png", 100, 50},
+		{"notes dec.png", 20, 20},
+	}
+	for i, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		out, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Ruído para que o tamanho do arquivo acompanhe as dimensões
+		img := image.NewGray(image.Rect(0, 0, f.w, f.h))
+		for j := range img.Pix {
+			img.Pix[j] = byte(j * 7919 >> 3)
+		}
+		png.Encode(out, img)
+		out.Close()
+
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(path, mtime, mtime)
+	}
+	return dir
+}
+
+func getJSON(t *testing.T, s *Server, url string, wantStatus int, v any) {
+	t.Helper()
+	rec := get(s, url, nil)
+	if rec.Code != wantStatus {
+		t.Fatalf("GET %s = %d, want %d (%s)", url, rec.Code, wantStatus, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("GET %s Content-Type = %q", url, ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s: JSON inválido: %v", url, err)
+	}
+}
+
+func listPaths(list apiImageList) []string {
+	var paths []string
+	for _, img := range list.Images {
+		paths = append(paths, img.Path)
+	}
+	return paths
+}
+
+func TestAPIImages(t *testing.T) {
+	s := newTestServer(t, apiFixture(t), watcher.Options{Recursive: true}, Options{})
+
+	tests := []struct {
+		query string
+		total int
+		want  []string
+	}{
+		{"", 4, []string{"notes dec.png", "c_mid.png", "sub/a_big.png", "b_small.png"}},
+		{"?sort=name", 4, []string{"b_small.png", "c_mid.png", "notes dec.png", "sub/a_big.png"}},
+		{"?sort=name&order=desc", 4, []string{"sub/a_big.png", "notes dec.png", "c_mid.png", "b_small.png"}},
+		{"?sort=mtime&order=asc", 4, []string{"b_small.png", "sub/a_big.png", "c_mid.png", "notes dec.png"}},
+		{"?sort=size&limit=2", 4, []string{"sub/a_big.png", "c_mid.png"}},
+		{"?limit=2&offset=3", 4, []string{"b_small.png"}},
+		{"?offset=10", 4, nil},
+		{"?q=SUB/", 1, []string{"sub/a_big.png"}},
+		{"?q=_&ext=.PNG", 3, []string{"c_mid.png", "sub/a_big.png", "b_small.png"}},
+		{"?ext=jpg", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var list apiImageList
+			getJSON(t, s, "/api/v1/images"+tt.query, http.StatusOK, &list)
+			if list.Total != tt.total {
+				t.Errorf("total = %d, want %d", list.Total, tt.total)
+			}
+			got := listPaths(list)
+			if len(got) != len(tt.want) {
+				t.Fatalf("images = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("images = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	var list apiImageList
+	getJSON(t, s, "/api/v1/images?q=notes", http.StatusOK, &list)
+	img := list.Images[0]
+	if img.Width != 20 || img.Height != 20 || img.Format != "png" || img.ColorModel != "gray" || img.Frames != 1 || img.Size == 0 || img.Version == "" {
+		t.Errorf("metadados = %+v", img)
+	}
+	if want := "/image/notes%20dec.png?v=" + img.Version; img.URL != want {
+		t.Errorf("url = %q, want %q", img.URL, want)
+	}
+	if rec := get(s, img.URL, nil); rec.Code != http.StatusOK {
+		t.Errorf("GET url = %d, want 200", rec.Code)
+	}
+}
+
+func TestAPIImages_TimeFilter(t *testing.T) {
+	dir := apiFixture(t)
+	s := newTestServer(t, dir, watcher.Options{Recursive: true}, Options{})
+
+	info, _ := os.Stat(filepath.Join(dir, "c_mid.png"))
+	since := info.ModTime().UTC().Format(time.RFC3339)
+
+	var list apiImageList
+	getJSON(t, s, "/api/v1/images?since="+since, http.StatusOK, &list)
+	if list.Total != 2 {
+		t.Errorf("since: total = %d (%v), want 2", list.Total, listPaths(list))
+	}
+}
+
+func TestAPIImages_BadRequest(t *testing.T) {
+	s := newTestServer(t, apiFixture(t), watcher.Options{Recursive: true}, Options{})
+
+	for _, query := range []string{"?limit=0", "?limit=5000", "?offset=-1", "?sort=color", "?order=up", "?since=ontem"} {
+		var resp map[string]string
+		getJSON(t, s, "/api/v1/images"+query, http.StatusBadRequest, &resp)
+		if resp["error"] == "" {
+			t.Errorf("%s: resposta sem error", query)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/images", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST = %d, Allow %q, want 405", rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+func TestAPIImage(t *testing.T) {
+	s := newTestServer(t, apiFixture(t), watcher.Options{Recursive: true, SlideshowCount: 2}, Options{})
+
+	var detail apiImageDetail
+	getJSON(t, s, "/api/v1/images/notes%20dec.png", http.StatusOK, &detail)
+	if !detail.Current || !detail.Slideshow || detail.Path != "notes dec.png" {
+		t.Errorf("detalhe = %+v, want atual e no slideshow", detail)
+	}
+
+	getJSON(t, s, "/api/v1/images/sub/a_big.png", http.StatusOK, &detail)
+	if detail.Current || detail.Slideshow || detail.Width != 300 {
+		t.Errorf("detalhe = %+v", detail)
+	}
+
+	var resp map[string]string
+	for _, path := range []string{"missing.png", "sub/missing.png", "sub"} {
+		getJSON(t, s, "/api/v1/images/"+path, http.StatusNotFound, &resp)
+	}
+}
+
+// exifJPEG monta um JPEG w×h com EXIF (Make e Orientation) e um pacote XMP
+func exifJPEG(t *testing.T, w, h, orientation int) []byte {
+	t.Helper()
+	var img bytes.Buffer
+	if err := jpeg.Encode(&img, image.NewGray(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	le := binary.LittleEndian
+	tiff := le.AppendUint32([]byte("II*\x00"), 8)
+	tiff = le.AppendUint16(tiff, 2)
+	tiff = append(tiff, 0x0F, 0x01, 2, 0, 6, 0, 0, 0, 38, 0, 0, 0) // Make, texto no offset 38
+	tiff = append(tiff, 0x12, 0x01, 3, 0, 1, 0, 0, 0, byte(orientation), 0, 0, 0)
+	tiff = le.AppendUint32(tiff, 0)
+	tiff = append(tiff, "Canon\x00"...)
+
+	segment := func(payload string) []byte {
+		b := []byte{0xFF, 0xE1}
+		b = binary.BigEndian.AppendUint16(b, uint16(len(payload)+2))
+		return append(b, payload...)
+	}
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="5"/></rdf:RDF></x:xmpmeta>`
+
+	out := []byte{0xFF, 0xD8}
+	out = append(out, segment("Exif\x00\x00"+string(tiff))...)
+	out = append(out, segment("http://ns.adobe.com/xap/1.0/\x00"+xmp)...)
+	return append(out, img.Bytes()[2:]...)
+}
+
+func TestAPIImageExif(t *testing.T) {
+	dir := apiFixture(t)
+	os.WriteFile(filepath.Join(dir, "phone.jpg"), exifJPEG(t, 40, 30, 6), 0644)
+	s := newTestServer(t, dir, watcher.Options{Recursive: true}, Options{})
+
+	var resp apiImageExif
+	getJSON(t, s, "/api/v1/images/phone.jpg/exif", http.StatusOK, &resp)
+	if resp.Path != "phone.jpg" || resp.Orientation != 6 || resp.Version == "" {
+		t.Errorf("exif = %+v", resp)
+	}
+	if resp.Exif["Make"] != "Canon" || resp.Exif["Orientation"] != "6" {
+		t.Errorf("exif.Exif = %v, want Make e Orientation", resp.Exif)
+	}
+	if resp.XMP["xmp:Rating"] != "5" {
+		t.Errorf("exif.XMP = %v, want xmp:Rating", resp.XMP)
+	}
+	if resp.GPS != nil {
+		t.Errorf("exif.GPS = %+v, want ausente", resp.GPS)
+	}
+
+	// Dimensões nos metadados já vêm como a imagem é exibida
+	var detail apiImageDetail
+	getJSON(t, s, "/api/v1/images/phone.jpg", http.StatusOK, &detail)
+	if detail.Width != 30 || detail.Height != 40 || detail.Orientation != 6 {
+		t.Errorf("detalhe = %dx%d orientação %d, want 30x40 orientação 6", detail.Width, detail.Height, detail.Orientation)
+	}
+
+	// Sem EXIF: mapas vazios, não null
+	rec := get(s, "/api/v1/images/sub/a_big.png/exif", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PNG sem EXIF = %d, want 200", rec.Code)
+	}
+	var raw map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &raw)
+	if exif, ok := raw["exif"].(map[string]any); !ok || len(exif) != 0 || raw["orientation"] != 1.0 {
+		t.Errorf("PNG sem EXIF = %s", rec.Body.String())
+	}
+
+	var errResp map[string]string
+	for _, path := range []string{"missing.png/exif", "sub/exif", "exif"} {
+		getJSON(t, s, "/api/v1/images/"+path, http.StatusNotFound, &errResp)
+	}
+}
+
+func TestAPIImageText(t *testing.T) {
+	dir := apiFixture(t)
+
+	// PNG do ComfyUI: prompt e workflow em chunks tEXt antes do IEND
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4)))
+	data := img.Bytes()
+	iend := len(data) - 12
+	out := append([]byte(nil), data[:iend]...)
+	for _, text := range []string{"prompt\x00{\"3\": {\"inputs\": {\"seed\": 42}}}", "workflow\x00{\"nodes\": []}"} {
+		out = binary.BigEndian.AppendUint32(out, uint32(len(text)))
+		out = append(out, "tEXt"+text...)
+		out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE([]byte("tEXt"+text)))
+	}
+	out = append(out, data[iend:]...)
+	os.WriteFile(filepath.Join(dir, "ComfyUI_00001_.png"), out, 0644)
+	s := newTestServer(t, dir, watcher.Options{Recursive: true}, Options{})
+
+	var resp apiImageText
+	getJSON(t, s, "/api/v1/images/ComfyUI_00001_.png/text", http.StatusOK, &resp)
+	want := []textmeta.Entry{{Key: "prompt", Text: `{"3": {"inputs": {"seed": 42}}}`}, {Key: "workflow", Text: `{"nodes": []}`}}
+	if resp.Path != "ComfyUI_00001_.png" || resp.Version == "" || !reflect.DeepEqual(resp.Text, want) {
+		t.Errorf("text = %+v, want %v", resp, want)
+	}
+
+	// Os metadados só trazem as chaves
+	var detail apiImageDetail
+	getJSON(t, s, "/api/v1/images/ComfyUI_00001_.png", http.StatusOK, &detail)
+	if !reflect.DeepEqual(detail.Text, []string{"prompt", "workflow"}) {
+		t.Errorf("detalhe.Text = %v, want [prompt workflow]", detail.Text)
+	}
+
+	// Sem textos: lista vazia, não null
+	if body := get(s, "/api/v1/images/c_mid.png/text", nil).Body.String(); !strings.Contains(body, `"text": []`) {
+		t.Errorf("PNG sem textos = %s", body)
+	}
+
+	var errResp map[string]string
+	getJSON(t, s, "/api/v1/images/missing.png/text", http.StatusNotFound, &errResp)
+}
+
+func TestAPICurrentAndSlideshow(t *testing.T) {
+	s := newTestServer(t, apiFixture(t), watcher.Options{Recursive: true, SlideshowCount: 2}, Options{SlideshowInterval: 5})
+
+	var current struct {
+		Image *apiImage `json:"image"`
+	}
+	getJSON(t, s, "/api/v1/current", http.StatusOK, &current)
+	if current.Image == nil || current.Image.Path != "notes dec.png" {
+		t.Errorf("current = %+v", current.Image)
+	}
+
+	var slideshow struct {
+		Enabled  bool       `json:"enabled"`
+		Interval int        `json:"interval"`
+		Images   []apiImage `json:"images"`
+	}
+	getJSON(t, s, "/api/v1/slideshow", http.StatusOK, &slideshow)
+	if !slideshow.Enabled || slideshow.Interval != 5 || len(slideshow.Images) != 2 || slideshow.Images[1].Path != "c_mid.png" {
+		t.Errorf("slideshow = %+v", slideshow)
+	}
+
+	// Diretório vazio
+	empty := newTestServer(t, t.TempDir(), watcher.Options{Recursive: true}, Options{})
+	current.Image = &apiImage{}
+	getJSON(t, empty, "/api/v1/current", http.StatusOK, &current)
+	if current.Image != nil {
+		t.Errorf("current sem imagens = %+v, want null", current.Image)
+	}
+}
+
+func TestAPIStatus(t *testing.T) {
+	dir := apiFixture(t)
+	s := newTestServer(t, dir, watcher.Options{Recursive: true}, Options{})
+
+	var st apiStatus
+	getJSON(t, s, "/api/v1/status", http.StatusOK, &st)
+	if st.Images != 4 || st.Clients != 0 || st.Health != "ok" || st.Version == "" {
+		t.Errorf("status = %+v", st)
+	}
+	abs, _ := filepath.Abs(dir)
+	if len(st.Directories) != 1 || st.Directories[0].Dir != abs {
+		t.Errorf("directories = %+v, want %s", st.Directories, abs)
+	}
+	if st.StartedAt.IsZero() || st.Uptime < 0 {
+		t.Errorf("started_at = %v, uptime = %v", st.StartedAt, st.Uptime)
+	}
+}
@@ -0,0 +1,137 @@
+// internal/server/auth.go
+package server
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// isLoopback indica se o endereço de escuta (ou nome de host) só aceita
+// conexões da própria máquina
+func isLoopback(host string) bool {
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// tokenCookie é o cookie que guarda o token depois do primeiro acesso com
+// ?token=. Leva a porta no nome porque cookies não distinguem portas e
+// várias instâncias podem rodar na mesma máquina.
+func (s *Server) tokenCookie() string {
+	return "sidelook_token_" + strconv.Itoa(s.port)
+}
+
+// validToken compara o token recebido em tempo constante
+func (s *Server) validToken(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// requireToken exige o token de acesso em todas as rotas quando há um
+// (servidor fora do loopback). O token vale como ?token= ou cookie; o
+// primeiro acesso com ?token= grava o cookie, usado depois pelas imagens,
+// pela API e pelo WebSocket da página.
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.validToken(r.URL.Query().Get("token")) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     s.tokenCookie(),
+				Value:    s.token,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+			next.ServeHTTP(w, r)
+			return
+		}
+		if c, err := r.Cookie(s.tokenCookie()); err == nil && s.validToken(c.Value) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// checkOrigin aceita o WebSocket só da própria página: o Origin, quando
+// enviado, deve ser o mesmo host da requisição. Sem token, esse host também
+// deve ser local, o que barra sites que apontam o próprio domínio para
+// 127.0.0.1 (DNS rebinding).
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // Clientes fora do navegador
+	}
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Host, r.Host) {
+		return false
+	}
+	return s.token != "" || isLoopback(u.Hostname())
+}
+
+// LANURLs retorna as URLs (com o token) pelas quais outros dispositivos da
+// rede local acessam o servidor. Vazio quando o servidor só escuta no
+// loopback.
+func (s *Server) LANURLs() []string {
+	addrs, _ := net.InterfaceAddrs()
+	return lanURLs(s.bind, s.port, s.token, addrs)
+}
+
+// lanURLs monta as URLs para o endereço de escuta bind: com 0.0.0.0, os
+// IPv4 das interfaces; com ::, também os IPv6; com um IP específico, só ele.
+// Endereços de loopback e link-local (que exigem a interface na URL) ficam
+// de fora.
+func lanURLs(bind string, port int, token string, addrs []net.Addr) []string {
+	if isLoopback(bind) {
+		return nil
+	}
+
+	var ips []net.IP
+	switch ip := net.ParseIP(bind); {
+	case ip == nil:
+		return nil
+	case ip.IsUnspecified():
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			if ipnet.IP.To4() == nil && ip.To4() != nil {
+				continue // 0.0.0.0 escuta só IPv4
+			}
+			ips = append(ips, ipnet.IP)
+		}
+		// IPv4 primeiro, que é o que se digita na TV
+		sort.SliceStable(ips, func(i, j int) bool {
+			return ips[i].To4() != nil && ips[j].To4() == nil
+		})
+	default:
+		ips = []net.IP{ip}
+	}
+
+	urls := make([]string, len(ips))
+	for i, ip := range ips {
+		urls[i] = serverURL(ip.String(), port, token)
+	}
+	return urls
+}
+
+// serverURL monta a URL da página para host e porta, com o token se houver
+func serverURL(host string, port int, token string) string {
+	u := "http://" + net.JoinHostPort(host, strconv.Itoa(port))
+	if token != "" {
+		u += "/?token=" + url.QueryEscape(token)
+	}
+	return u
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/verseles/sidelook/internal/watcher"
+)
+
+func TestRequireToken(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "render.png"), []byte("\x89PNG\r\n\x1a\nfake"), 0644)
+	s := newTestServer(t, dir, watcher.Options{}, Options{Bind: "0.0.0.0", Token: "segredo"})
+	cookie := http.Header{"Cookie": {s.tokenCookie() + "=segredo"}}
+
+	tests := []struct {
+		name   string
+		url    string
+		header http.Header
+		want   int
+	}{
+		{"página sem token", "/", nil, http.StatusUnauthorized},
+		{"imagem sem token", "/image/render.png", nil, http.StatusUnauthorized},
+		{"miniatura sem token", "/thumb/render.png", nil, http.StatusUnauthorized},
+		{"API sem token", "/api/v1/status", nil, http.StatusUnauthorized},
+		{"WebSocket sem token", "/ws", nil, http.StatusUnauthorized},
+		{"token errado", "/?token=segred", nil, http.StatusUnauthorized},
+		{"cookie errado", "/", http.Header{"Cookie": {s.tokenCookie() + "=outro"}}, http.StatusUnauthorized},
+		{"cookie de outra porta", "/", http.Header{"Cookie": {"sidelook_token_1=segredo"}}, http.StatusUnauthorized},
+		{"página com token", "/?token=segredo", nil, http.StatusOK},
+		{"imagem com token", "/image/render.png?token=segredo", nil, http.StatusOK},
+		{"API com token", "/api/v1/status?token=segredo", nil, http.StatusOK},
+		{"página com cookie", "/", cookie, http.StatusOK},
+		{"imagem com cookie", "/image/render.png?v=1", cookie, http.StatusOK},
+		{"API com cookie", "/api/v1/images", cookie, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := get(s, tt.url, tt.header)
+		if rec.Code != tt.want {
+			t.Errorf("%s: GET %s = %d, want %d", tt.name, tt.url, rec.Code, tt.want)
+		}
+		// Mesmo recusadas, as respostas levam os cabeçalhos de segurança
+		if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Errorf("%s: sem cabeçalhos de segurança", tt.name)
+		}
+	}
+
+	// O primeiro acesso com ?token= grava o cookie usado pelo resto da página
+	rec := get(s, "/?token=segredo", nil)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != s.tokenCookie() || cookies[0].Value != "segredo" || !cookies[0].HttpOnly {
+		t.Errorf("cookies = %+v", cookies)
+	}
+
+	// No loopback, sem token, o acesso é livre
+	local := newTestServer(t, dir, watcher.Options{}, Options{})
+	if rec := get(local, "/image/render.png", nil); rec.Code != http.StatusOK {
+		t.Errorf("loopback: GET /image/render.png = %d, want 200", rec.Code)
+	}
+}
+
+func TestStart_GeneratesToken(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		bind      string
+		wantToken bool
+	}{
+		{"", false},
+		{"127.0.0.1", false},
+		{"0.0.0.0", true},
+	} {
+		s := newTestServer(t, dir, watcher.Options{}, Options{Bind: tt.bind, Port: 18700})
+		if err := s.Start(); err != nil {
+			t.Fatalf("bind %q: Start() error = %v", tt.bind, err)
+		}
+		if got := s.Token() != ""; got != tt.wantToken {
+			t.Errorf("bind %q: token = %q, want token: %v", tt.bind, s.Token(), tt.wantToken)
+		}
+
+		resp, err := http.Get(s.URL())
+		if err != nil {
+			t.Fatalf("bind %q: GET %s: %v", tt.bind, s.URL(), err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("bind %q: GET %s = %d", tt.bind, s.URL(), resp.StatusCode)
+		}
+		s.Stop()
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	local := &Server{}
+	shared := &Server{token: "segredo"}
+
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		local  bool // Sem token (loopback)
+		shared bool // Com token (rede local)
+	}{
+		{"sem Origin", "localhost:8080", "", true, true},
+		{"mesma origem", "localhost:8080", "http://localhost:8080", true, true},
+		{"mesma origem IPv4", "127.0.0.1:8080", "http://127.0.0.1:8080", true, true},
+		{"mesma origem IPv6", "[::1]:8080", "http://[::1]:8080", true, true},
+		{"outra porta", "localhost:8080", "http://localhost:3000", false, false},
+		{"outro site", "localhost:8080", "https://evil.example", false, false},
+		{"DNS rebinding", "evil.example:8080", "http://evil.example:8080", false, true},
+		{"IP da rede", "192.168.0.10:8080", "http://192.168.0.10:8080", false, true},
+		{"Origin inválido", "localhost:8080", "http://%zz", false, false},
+		{"Origin null", "localhost:8080", "null", false, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		req.Host = tt.host
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := local.checkOrigin(req); got != tt.local {
+			t.Errorf("%s: checkOrigin() sem token = %v, want %v", tt.name, got, tt.local)
+		}
+		if got := shared.checkOrigin(req); got != tt.shared {
+			t.Errorf("%s: checkOrigin() com token = %v, want %v", tt.name, got, tt.shared)
+		}
+	}
+}
+
+func TestLANURLs(t *testing.T) {
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+		&net.IPNet{IP: net.ParseIP("::1"), Mask: net.CIDRMask(128, 128)},
+		&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+		&net.IPNet{IP: net.ParseIP("2001:db8::10"), Mask: net.CIDRMask(64, 128)},
+		&net.IPNet{IP: net.ParseIP("192.168.0.10").To4(), Mask: net.CIDRMask(24, 32)},
+		&net.IPNet{IP: net.ParseIP("10.0.0.5").To4(), Mask: net.CIDRMask(8, 32)},
+	}
+
+	tests := []struct {
+		bind string
+		want []string
+	}{
+		{"127.0.0.1", nil},
+		{"localhost", nil},
+		{"::1", nil},
+		{"0.0.0.0", []string{
+			"http://192.168.0.10:8080/?token=t",
+			"http://10.0.0.5:8080/?token=t",
+		}},
+		{"::", []string{
+			"http://192.168.0.10:8080/?token=t",
+			"http://10.0.0.5:8080/?token=t",
+			"http://[2001:db8::10]:8080/?token=t",
+		}},
+		{"192.168.0.10", []string{"http://192.168.0.10:8080/?token=t"}},
+		{"2001:db8::10", []string{"http://[2001:db8::10]:8080/?token=t"}},
+	}
+	for _, tt := range tests {
+		if got := lanURLs(tt.bind, 8080, "t", addrs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lanURLs(%q) = %v, want %v", tt.bind, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// internal/server/cache.go
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// versionOf retorna o token de versão de um arquivo (mtime e tamanho). O
+// cliente o coloca nas URLs (?v=), então um arquivo modificado ganha uma
+// URL nova e um arquivo inalterado é servido pelo cache do navegador.
+func versionOf(info os.FileInfo) string {
+	return strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36)
+}
+
+// imageVersion retorna o token de versão de uma imagem pelo caminho relativo
+// ("" se o arquivo não existir mais)
+func (s *Server) imageVersion(rel string) string {
+	if rel == "" {
+		return ""
+	}
+	fullPath, _, ok := s.watcher.Resolve(rel)
+	if !ok {
+		return ""
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return ""
+	}
+	return versionOf(info)
+}
+
+// imageVersions retorna os tokens de versão das imagens informadas
+func (s *Server) imageVersions(paths []string) map[string]string {
+	versions := make(map[string]string, len(paths))
+	for _, p := range paths {
+		if v := s.imageVersion(p); v != "" {
+			versions[p] = v
+		}
+	}
+	return versions
+}
+
+// setCacheHeaders define ETag e Cache-Control de uma representação do
+// arquivo. variant distingue as representações da mesma URL (tamanho,
+// formato...), já que o ETag forte precisa mudar junto com os bytes. O
+// If-None-Match e o If-Modified-Since são tratados por http.ServeContent.
+func setCacheHeaders(w http.ResponseWriter, r *http.Request, fullPath string, info os.FileInfo, variant string) {
+	h := sha256.New()
+	h.Write([]byte(fullPath))
+	h.Write([]byte{0})
+	h.Write([]byte(versionOf(info)))
+	h.Write([]byte{0})
+	h.Write([]byte(variant))
+	w.Header().Set("ETag", `"`+hex.EncodeToString(h.Sum(nil)[:16])+`"`)
+
+	// URL com a versão atual nunca muda de conteúdo; sem versão (ou com uma
+	// versão antiga) o navegador precisa revalidar a cada uso
+	if v := r.URL.Query().Get("v"); v != "" && v == versionOf(info) {
+		w.Header().Set("Cache-Control", "max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"image"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/verseles/sidelook/internal/watcher"
+)
+
+func TestImageCaching(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "render.png")
+	os.WriteFile(path, []byte("\x89PNG\r\n\x1a\nfake"), 0644)
+	s := newTestServer(t, dir, watcher.Options{}, Options{})
+
+	rec := get(s, "/image/render.png", nil)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET = %d, ETag %q", rec.Code, etag)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control sem versão = %q, want no-cache", cc)
+	}
+
+	// Revalidação sem mudanças
+	rec = get(s, "/image/render.png", http.Header{"If-None-Match": {etag}})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match = %d, want 304", rec.Code)
+	}
+	lastMod := get(s, "/image/render.png", nil).Header().Get("Last-Modified")
+	rec = get(s, "/image/render.png", http.Header{"If-Modified-Since": {lastMod}})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since = %d, want 304", rec.Code)
+	}
+
+	// URL com a versão atual pode ficar em cache indefinidamente
+	version := s.imageVersion("render.png")
+	rec = get(s, "/image/render.png?v="+version, nil)
+	if cc := rec.Header().Get("Cache-Control"); cc != "max-age=31536000, immutable" {
+		t.Errorf("Cache-Control versionado = %q, want immutable", cc)
+	}
+	if rec.Header().Get("ETag") != etag {
+		t.Error("ETag não deveria depender do parâmetro v")
+	}
+
+	// Arquivo modificado: nova versão, novo ETag, versão antiga não é imutável
+	os.WriteFile(path, []byte("\x89PNG\r\n\x1a\nchanged!"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	if s.imageVersion("render.png") == version {
+		t.Error("versão deveria mudar com o arquivo")
+	}
+	rec = get(s, "/image/render.png?v="+version, http.Header{"If-None-Match": {etag}})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("após modificação = %d, ETag %q, want 200 com ETag novo", rec.Code, rec.Header().Get("ETag"))
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control com versão antiga = %q, want no-cache", cc)
+	}
+}
+
+func TestImageCaching_Variants(t *testing.T) {
+	dir := t.TempDir()
+	f, _ := os.Create(filepath.Join(dir, "render.png"))
+	png.Encode(f, image.NewGray(image.Rect(0, 0, 600, 400)))
+	f.Close()
+	os.WriteFile(filepath.Join(dir, "photo.jpg"), []byte{0xFF, 0xD8, 0xFF, 0xD9}, 0644)
+	s := newTestServer(t, dir, watcher.Options{}, Options{})
+
+	etags := map[string]bool{}
+	for _, url := range []string{"/image/render.png", "/image/render.png?w=100", "/image/render.png?w=200", "/thumb/render.png"} {
+		rec := get(s, url, nil)
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("GET %s = %d, ETag %q", url, rec.Code, etag)
+		}
+		if etags[etag] {
+			t.Errorf("GET %s repetiu o ETag de outra representação", url)
+		}
+		etags[etag] = true
+	}
+
+	// JPEG inválido não gera miniatura: o original é servido com o mesmo ETag
+	original := get(s, "/image/photo.jpg", nil).Header().Get("ETag")
+	if thumb := get(s, "/thumb/photo.jpg", nil).Header().Get("ETag"); thumb != original {
+		t.Errorf("ETag do fallback = %q, want %q", thumb, original)
+	}
+}
@@ -2,14 +2,20 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/verseles/sidelook/internal/assets"
+	"github.com/verseles/sidelook/internal/thumb"
+	"github.com/verseles/sidelook/internal/transcode"
 	"github.com/verseles/sidelook/internal/watcher"
 )
 
@@ -18,6 +24,8 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("/", s.handleIndex)
 	s.mux.HandleFunc("/ws", s.handleWebSocket)
 	s.mux.HandleFunc("/image/", s.handleImage)
+	s.mux.HandleFunc("/thumb/", s.handleThumb)
+	s.registerAPIRoutes()
 }
 
 // handleIndex serve a página HTML principal
@@ -29,78 +37,276 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 	initialImage := s.watcher.CurrentImageRelative()
 	slideshowImages := s.watcher.RecentImagesRelative()
-	html := assets.GenerateHTML(initialImage, slideshowImages, s.slideshowInterval)
+
+	var sources []string
+	for _, src := range s.watcher.Sources() {
+		sources = append(sources, src.Name)
+	}
+
+	initial := append([]string{initialImage}, slideshowImages...)
+	versions := s.imageVersions(initial)
+	metas := s.imageMetas(initial)
+
+	nonce, err := newNonce()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var page bytes.Buffer
+	err = assets.WriteHTML(&page, assets.ViewerState{
+		InitialImage: initialImage,
+		Slideshow:    slideshowImages,
+		Interval:     s.slideshowInterval,
+		Sources:      sources,
+		Versions:     versions,
+		Metas:        metas,
+		Nonce:        nonce,
+	})
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(html))
+	w.Header().Set("Content-Security-Policy", pagePolicy(nonce))
+	w.Write(page.Bytes())
 }
 
 // handleImage serve arquivos de imagem
 func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
-	// Extrair caminho da imagem (remover /image/ prefix)
-	imagePath := strings.TrimPrefix(r.URL.Path, "/image/")
-	if imagePath == "" {
-		http.NotFound(w, r)
+	fullPath, info, ok := s.resolveImage(w, r, "/image/")
+	if !ok {
 		return
 	}
 
-	// Construir caminho completo
-	fullPath := filepath.Join(s.watcher.Dir(), imagePath)
+	// ?raw=1 serve os bytes originais, sem redimensionar nem converter
+	if r.URL.Query().Get("raw") == "1" {
+		s.serveOriginal(w, r, fullPath, info)
+		return
+	}
 
-	// Segurança: verificar se o caminho está dentro do diretório monitorado
-	absDir, err := filepath.Abs(s.watcher.Dir())
+	// Versão redimensionada (?w=, ?h=, ?fit=) ou em outro formato (?format=)
+	spec, ok, err := resizeSpec(r)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		// O formato escolhido depende do Accept (ver transcode.Negotiate)
+		w.Header().Set("Vary", "Accept")
+		if res, err := s.transcoder.Resize(fullPath, info, spec); err == nil && res != nil {
+			s.serveResult(w, r, fullPath, info, res, fmt.Sprintf("resize %+v", spec))
+			return
+		}
+		// Já no tamanho pedido ou sem decodificador: seguir sem redimensionar
+	}
+
+	// Formatos que o navegador não exibe (TIFF, PNG de 16 bits) são
+	// convertidos para PNG
+	res, err := s.transcoder.Convert(fullPath, info)
+	if errors.Is(err, transcode.ErrTooLarge) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err == nil && res != nil {
+		s.serveResult(w, r, fullPath, info, res, "convert")
 		return
 	}
-	absPath, err := filepath.Abs(fullPath)
+
+	// Sem conversão (ou falha ao decodificar): servir o original
+	s.serveOriginal(w, r, fullPath, info)
+}
+
+// resizeSpec lê os parâmetros de redimensionamento da requisição. Retorna
+// false quando nenhum foi informado (imagem no tamanho original).
+func resizeSpec(r *http.Request) (transcode.Spec, bool, error) {
+	q := r.URL.Query()
+	var spec transcode.Spec
+
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"w", &spec.Width}, {"h", &spec.Height}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > transcode.MaxDimension {
+			return spec, false, fmt.Errorf("%s deve estar entre 1 e %d", p.name, transcode.MaxDimension)
+		}
+		*p.dst = n
+	}
+
+	switch q.Get("fit") {
+	case "", "contain":
+	case "cover":
+		spec.Cover = true
+	default:
+		return spec, false, fmt.Errorf("fit deve ser contain ou cover")
+	}
+
+	format, valid := transcode.ParseFormat(q.Get("format"))
+	if !valid {
+		return spec, false, fmt.Errorf("format deve ser png ou jpeg")
+	}
+
+	if spec.Width == 0 && spec.Height == 0 && format == "" {
+		return spec, false, nil
+	}
+
+	// Sem format, o cabeçalho Accept pode restringir o formato a PNG ou JPEG
+	spec.Format = transcode.Negotiate(format, r.Header.Get("Accept"))
+	return spec, true, nil
+}
+
+// serveResult serve uma imagem convertida, com o mtime do arquivo original
+func (s *Server) serveResult(w http.ResponseWriter, r *http.Request, fullPath string, info os.FileInfo, res *transcode.Result, variant string) {
+	w.Header().Set("Content-Type", res.ContentType)
+	setCacheHeaders(w, r, fullPath, info, variant)
+	http.ServeContent(w, r, "", info.ModTime(), bytes.NewReader(res.Data))
+}
+
+// handleThumb serve miniaturas (/thumb/<caminho>?size=256), geradas sob
+// demanda e guardadas no cache em disco
+func (s *Server) handleThumb(w http.ResponseWriter, r *http.Request) {
+	fullPath, info, ok := s.resolveImage(w, r, "/thumb/")
+	if !ok {
+		return
+	}
+
+	size := thumb.DefaultSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < thumb.MinSize || n > thumb.MaxSize {
+			http.Error(w, fmt.Sprintf("size deve estar entre %d e %d", thumb.MinSize, thumb.MaxSize),
+				http.StatusBadRequest)
+			return
+		}
+		size = n
+	}
+
+	t, err := s.thumbs.Get(fullPath, info, size)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		// Sem decodificador (WebP, SVG...) ou imagem inválida: o navegador
+		// redimensiona o original
+		s.serveOriginal(w, r, fullPath, info)
 		return
 	}
-	if !strings.HasPrefix(absPath, absDir) {
+
+	w.Header().Set("Content-Type", t.ContentType)
+	setCacheHeaders(w, r, fullPath, info, "thumb "+strconv.Itoa(size))
+	http.ServeContent(w, r, "", info.ModTime(), bytes.NewReader(t.Data))
+}
+
+// resolveImage valida o caminho da requisição (sem prefix) e retorna o
+// arquivo correspondente. Em caso de falha a resposta já foi escrita.
+func (s *Server) resolveImage(w http.ResponseWriter, r *http.Request, prefix string) (string, os.FileInfo, bool) {
+	// Extrair caminho da imagem (remover prefixo da rota)
+	imagePath := strings.TrimPrefix(r.URL.Path, prefix)
+	if imagePath == "" {
+		http.NotFound(w, r)
+		return "", nil, false
+	}
+
+	// Construir caminho completo (com vários diretórios, o primeiro segmento
+	// indica o diretório de origem)
+	fullPath, root, ok := s.watcher.Resolve(imagePath)
+	if !ok {
+		http.NotFound(w, r)
+		return "", nil, false
+	}
+
+	// Segurança: o caminho deve ficar dentro do seu diretório de origem, sem
+	// "..", prefixos parecidos (/data/img-private) ou links simbólicos para fora
+	if !s.watcher.Confined(fullPath, root) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
+		return "", nil, false
 	}
 
-	// Verificar se é uma imagem válida
-	if !watcher.IsImageFile(fullPath) {
+	// Verificar se é uma imagem válida (e não ignorada)
+	if !s.watcher.Accepts(fullPath) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
+		return "", nil, false
 	}
 
 	// Verificar se arquivo existe
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+	info, err := os.Stat(fullPath)
+	switch {
+	case os.IsNotExist(err):
 		http.NotFound(w, r)
-		return
+		return "", nil, false
+	case os.IsPermission(err):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return "", nil, false
+	case err != nil:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return "", nil, false
 	}
 
-	// Determinar content type
+	return fullPath, info, true
+}
+
+// serveOriginal serve os bytes do arquivo sem conversão (com SanitizeSVG,
+// SVGs passam antes pelo svgsafe)
+func (s *Server) serveOriginal(w http.ResponseWriter, r *http.Request, fullPath string, info os.FileInfo) {
+	// Determinar content type (pelo conteúdo com --sniff, senão pela extensão)
 	ext := filepath.Ext(fullPath)
-	contentType := mime.TypeByExtension(ext)
+	contentType := s.watcher.ContentType(fullPath)
+	if contentType == "" {
+		contentType = mime.TypeByExtension(ext)
+	}
+	if contentType == "" && ext != "" {
+		// Extensões extras (--ext) sem tipo registrado no sistema
+		contentType = "image/" + strings.ToLower(strings.TrimPrefix(ext, "."))
+	}
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
 
+	// SVG aberto direto é um documento: sem scripts e em origem isolada
+	if strings.HasPrefix(contentType, "image/svg+xml") {
+		w.Header().Set("Content-Security-Policy", svgPolicy)
+		if s.sanitizeSVG && r.URL.Query().Get("raw") != "1" {
+			res, err := s.transcoder.Sanitize(fullPath, info)
+			if err != nil {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			s.serveResult(w, r, fullPath, info, res, "svgsafe")
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	setCacheHeaders(w, r, fullPath, info, "")
 
 	http.ServeFile(w, r, fullPath)
 }
 
 // wsMessage é a estrutura de mensagem WebSocket
 type wsMessage struct {
-	Type string `json:"type"`
-	Path string `json:"path,omitempty"`
-}
+	Type    string   `json:"type"`
+	Path    string   `json:"path,omitempty"`
+	Images  []string `json:"images,omitempty"`
+	Status  string   `json:"status,omitempty"`
+	Message string   `json:"message,omitempty"`
 
-// broadcastNewImage envia notificação de nova imagem para todos os clientes
-func (s *Server) broadcastNewImage(path string) {
-	msg := wsMessage{
-		Type: "new_image",
-		Path: path,
-	}
+	// Version e Versions são os tokens de versão (?v=) de Path e de Images
+	Version  string            `json:"version,omitempty"`
+	Versions map[string]string `json:"versions,omitempty"`
 
+	// Meta e Metas são os metadados (tamanho, dimensões, formato...) de Path
+	// e de Images
+	Meta  *metadata            `json:"meta,omitempty"`
+	Metas map[string]*metadata `json:"metas,omitempty"`
+}
+
+// broadcast envia a mensagem para todos os clientes conectados, na ordem das
+// chamadas. Um cliente com a fila cheia é desconectado: travar o watcher ou
+// pular mensagens deixaria a página fora de sincronia.
+func (s *Server) broadcast(msg wsMessage) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return
@@ -110,30 +316,56 @@ func (s *Server) broadcastNewImage(path string) {
 	defer s.clientsMu.RUnlock()
 
 	for client := range s.clients {
-		go func(c *wsClient) {
-			c.send <- data
-		}(client)
+		select {
+		case client.send <- data:
+		default:
+			// readPump remove o cliente ao perceber a conexão fechada
+			client.conn.Close()
+		}
 	}
 }
 
+// broadcastNewImage envia notificação de nova imagem para todos os clientes
+func (s *Server) broadcastNewImage(path string) {
+	s.broadcast(wsMessage{
+		Type:    "new_image",
+		Path:    path,
+		Version: s.imageVersion(path),
+		Meta:    s.imageMeta(path),
+	})
+}
+
 // broadcastImageDeleted envia notificação quando a imagem atual é deletada
 func (s *Server) broadcastImageDeleted(path string) {
-	msg := wsMessage{
-		Type: "image_deleted",
-		Path: path,
-	}
-
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return
-	}
+	s.broadcast(wsMessage{
+		Type:    "image_deleted",
+		Path:    path,
+		Version: s.imageVersion(path),
+		Meta:    s.imageMeta(path),
+	})
+}
 
-	s.clientsMu.RLock()
-	defer s.clientsMu.RUnlock()
+// broadcastSlideshowUpdate envia a lista completa e ordenada do slideshow
+// (lista vazia é omitida do JSON e tratada como [] pelo cliente)
+func (s *Server) broadcastSlideshowUpdate(paths []string) {
+	s.broadcast(wsMessage{
+		Type:     "slideshow_update",
+		Images:   paths,
+		Versions: s.imageVersions(paths),
+		Metas:    s.imageMetas(paths),
+	})
+}
 
-	for client := range s.clients {
-		go func(c *wsClient) {
-			c.send <- data
-		}(client)
+// statusMessage monta a mensagem de saúde do watcher
+func statusMessage(st watcher.Status) wsMessage {
+	return wsMessage{
+		Type:    "status",
+		Status:  string(st.Health),
+		Message: st.Message,
 	}
 }
+
+// broadcastStatus envia a saúde do watcher (ok, degraded, rescan) para todos os clientes
+func (s *Server) broadcastStatus(st watcher.Status) {
+	s.broadcast(statusMessage(st))
+}
@@ -0,0 +1,430 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/verseles/sidelook/internal/watcher"
+)
+
+func TestIndex_HostileFileNames(t *testing.T) {
+	names := []string{
+		`"quote".png`,
+		`</script><script>alert(1)</script>.png`, // Subdiretórios "<" e "script><script>alert(1)<"
+		`back\slash.png`,
+		`it's & <b>bold</b>.png`,
+		`100% #1?.png`,
+		`a+b=c;d.png`,
+		"line\u2028sep.png",
+	}
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	for i, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, image.NewGray(image.Rect(0, 0, 4, 4)))
+		f.Close()
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(path, mtime, mtime)
+	}
+	s := newTestServer(t, dir, watcher.Options{Recursive: true, SlideshowCount: len(names)}, Options{})
+
+	rec := get(s, "/", nil)
+	page := rec.Body.String()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / = %d", rec.Code)
+	}
+	if n := strings.Count(page, "</script>"); n != 1 {
+		t.Errorf("página tem %d </script>, want 1", n)
+	}
+	if strings.Contains(page, "<script>alert") || strings.Contains(page, "<b>bold") {
+		t.Error("nome de arquivo escapou para o HTML")
+	}
+
+	// Estado inicial: a imagem mais recente e o slideshow com todos os nomes
+	const marker = "const state = "
+	i := strings.Index(page, marker)
+	end := strings.Index(page[i:], ";\n")
+	if i < 0 || end < 0 {
+		t.Fatal("página sem o estado inicial")
+	}
+	var state struct {
+		InitialImage string            `json:"initialImage"`
+		Slideshow    []string          `json:"slideshow"`
+		Versions     map[string]string `json:"versions"`
+	}
+	if err := json.Unmarshal([]byte(page[i+len(marker):i+end]), &state); err != nil {
+		t.Fatalf("estado inicial inválido: %v", err)
+	}
+	if state.InitialImage != names[len(names)-1] || len(state.Slideshow) != len(names) {
+		t.Errorf("estado = %+v", state)
+	}
+
+	// Cada nome, escapado por segmento como faz a página, serve a imagem
+	for _, name := range state.Slideshow {
+		for _, prefix := range []string{"/image/", "/thumb/", "/api/v1/images/"} {
+			url := prefix + escapePath(name)
+			if rec := get(s, url, nil); rec.Code != http.StatusOK {
+				t.Errorf("GET %s = %d, want 200", url, rec.Code)
+			}
+		}
+		if state.Versions[name] == "" {
+			t.Errorf("sem versão para %q", name)
+		}
+	}
+
+	// URLs geradas pela API já vêm escapadas
+	var list apiImageList
+	getJSON(t, s, "/api/v1/images?limit=100", http.StatusOK, &list)
+	for _, img := range list.Images {
+		if rec := get(s, img.URL, nil); rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", img.URL, rec.Code)
+		}
+	}
+}
+
+func TestImageResize(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewGray(image.Rect(0, 0, 600, 400))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i)
+	}
+	f, _ := os.Create(filepath.Join(dir, "render.png"))
+	png.Encode(f, img)
+	f.Close()
+	original, _ := os.ReadFile(filepath.Join(dir, "render.png"))
+	s := newTestServer(t, dir, watcher.Options{}, Options{})
+
+	tests := []struct {
+		url         string
+		status      int
+		contentType string
+		width       int // 0 = o arquivo original
+	}{
+		{"/image/render.png?w=100&h=100", http.StatusOK, "image/png", 100},
+		{"/image/render.png?w=100&format=jpeg", http.StatusOK, "image/jpeg", 100},
+		{"/image/render.png?w=1920&h=1080", http.StatusOK, "image/png", 0},
+		{"/image/render.png?w=600&h=400&format=png", http.StatusOK, "image/png", 0},
+		{"/image/render.png?w=100&format=webp", http.StatusBadRequest, "", 0},
+		{"/image/render.png?format=gif", http.StatusBadRequest, "", 0},
+	}
+	for _, tt := range tests {
+		rec := get(s, tt.url, nil)
+		if rec.Code != tt.status {
+			t.Errorf("GET %s = %d, want %d", tt.url, rec.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("GET %s Content-Type = %q, want %q", tt.url, ct, tt.contentType)
+		}
+		if tt.width == 0 {
+			// Já cabe no tamanho pedido: o original, sem recodificar
+			if !bytes.Equal(rec.Body.Bytes(), original) {
+				t.Errorf("GET %s deveria servir o arquivo original", tt.url)
+			}
+			continue
+		}
+		cfg, _, err := image.DecodeConfig(rec.Body)
+		if err != nil || cfg.Width != tt.width {
+			t.Errorf("GET %s = %dx%d, %v, want largura %d", tt.url, cfg.Width, cfg.Height, err, tt.width)
+		}
+	}
+}
+
+func TestImage_TooLarge(t *testing.T) {
+	// PNG de 16 bits (convertido ao servir) que declara 40000x40000 pixels
+	var png16 bytes.Buffer
+	png16.WriteString("\x89PNG\r\n\x1a\n")
+	for _, c := range []struct {
+		typ  string
+		data []byte
+	}{
+		{"IHDR", []byte{0, 0, 0x9c, 0x40, 0, 0, 0x9c, 0x40, 16, 6, 0, 0, 0}},
+		{"IDAT", []byte{0x78, 0x9c, 0x03, 0x00, 0x00, 0x00, 0x00, 0x01}},
+		{"IEND", nil},
+	} {
+		binary.Write(&png16, binary.BigEndian, uint32(len(c.data)))
+		png16.WriteString(c.typ)
+		png16.Write(c.data)
+		binary.Write(&png16, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(c.typ), c.data...)))
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "huge.png"), png16.Bytes(), 0644)
+	s := newTestServer(t, dir, watcher.Options{}, Options{})
+
+	for _, url := range []string{"/image/huge.png", "/image/huge.png?w=100"} {
+		if rec := get(s, url, nil); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("GET %s = %d, want 422", url, rec.Code)
+		}
+	}
+	// O original continua disponível
+	if rec := get(s, "/image/huge.png?raw=1", nil); rec.Code != http.StatusOK {
+		t.Errorf("GET ?raw=1 = %d, want 200", rec.Code)
+	}
+}
+
+func TestResolveImage_Traversal(t *testing.T) {
+	// base/img é monitorado; img-private e outside ficam ao lado dele
+	base := t.TempDir()
+	root := filepath.Join(base, "img")
+	for _, p := range []string{"img/a.png", "img/sub/b.png", "img-private/x.png", "outside/secret.png"} {
+		path := filepath.Join(base, filepath.FromSlash(p))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("\x89PNG\r\n\x1a\nfake"), 0644)
+	}
+	for link, target := range map[string]string{
+		"img/inner.png":    "a.png",
+		"img/link-out.png": filepath.Join(base, "outside", "secret.png"),
+		"img/linkdir":      filepath.Join(base, "outside"),
+	} {
+		if err := os.Symlink(target, filepath.Join(base, filepath.FromSlash(link))); err != nil {
+			t.Skipf("links simbólicos indisponíveis: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		want   int // Sem --follow-symlinks
+		follow int // Com --follow-symlinks
+	}{
+		{"arquivo", "a.png", 200, 200},
+		{"subdiretório", "sub/b.png", 200, 200},
+		{"ponto-ponto que volta para dentro", "sub/../a.png", 200, 200},
+		{"link para dentro", "inner.png", 200, 200},
+		{"inexistente", "missing.png", 404, 404},
+		{"vazio", "", 404, 404},
+		{"prefixo irmão", "../img-private/x.png", 403, 403},
+		{"ponto-ponto", "../outside/secret.png", 403, 403},
+		{"ponto-ponto em subdiretório", "sub/../../img-private/x.png", 403, 403},
+		{"várias barras", "//../img-private/x.png", 403, 403},
+		{"caminho absoluto", "/" + filepath.ToSlash(filepath.Join(base, "outside", "secret.png")), 404, 404},
+		{"não é imagem", "../outside", 403, 403},
+		{"link para fora", "link-out.png", 403, 200},
+		{"subdiretório é link para fora", "linkdir/secret.png", 403, 200},
+	}
+	for _, follow := range []bool{false, true} {
+		s := newTestServer(t, root, watcher.Options{FollowSymlinks: follow}, Options{})
+
+		for _, tt := range tests {
+			want := tt.want
+			if follow {
+				want = tt.follow
+			}
+			// Direto no handler: o ServeMux limparia ".." com um redirect
+			req := httptest.NewRequest(http.MethodGet, "/image/", nil)
+			req.URL.Path = "/image/" + tt.path
+			rec := httptest.NewRecorder()
+			if _, _, ok := s.resolveImage(rec, req, "/image/"); ok != (want == 200) || (!ok && rec.Code != want) {
+				t.Errorf("follow=%v: %s (%q) = %d, ok=%v, want %d", follow, tt.name, tt.path, rec.Code, ok, want)
+			}
+		}
+	}
+
+	// Pelas rotas: o arquivo do diretório irmão não é servido nem com a barra codificada
+	s := newTestServer(t, root, watcher.Options{}, Options{})
+	for _, url := range []string{"/image/..%2fimg-private%2fx.png", "/thumb/..%2f..%2fimg-private/x.png", "/image/link-out.png?raw=1"} {
+		if rec := get(s, url, nil); rec.Code == http.StatusOK {
+			t.Errorf("GET %s = 200, want recusa", url)
+		}
+	}
+}
+
+func TestImage_Ignored(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, watcher.IgnoreFileName), []byte("*_mask.png\ndrafts/\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "drafts"), 0755)
+	for _, name := range []string{".cover.png", "render_mask.png", ".tmp-1.png", "render.png.part", "drafts/a.png"} {
+		os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte("\x89PNG\r\n\x1a\nfake"), 0644)
+	}
+	s := newTestServer(t, dir, watcher.Options{}, Options{})
+
+	// Ignorados pelo watcher também não são servidos pelo caminho direto
+	tests := []struct {
+		path string
+		want int
+	}{
+		{".cover.png", http.StatusOK},
+		{"render_mask.png", http.StatusForbidden},
+		{"drafts/a.png", http.StatusForbidden},
+		{".tmp-1.png", http.StatusForbidden},
+		{"render.png.part", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rec := get(s, "/image/"+tt.path, nil); rec.Code != tt.want {
+			t.Errorf("GET /image/%s = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusForbidden {
+			if rec := get(s, "/thumb/"+tt.path, nil); rec.Code != tt.want {
+				t.Errorf("GET /thumb/%s = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+		}
+	}
+}
+
+// dialWS conecta um cliente WebSocket ao servidor e espera o registro
+func dialWS(t *testing.T, s *Server) *websocket.Conn {
+	t.Helper()
+	ts := httptest.NewServer(s.handler)
+	t.Cleanup(ts.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.clientsMu.RLock()
+		n := len(s.clients)
+		s.clientsMu.RUnlock()
+		if n > 0 {
+			return conn
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cliente WebSocket não registrado")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readWS(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("lendo WebSocket: %v", err)
+	}
+	return msg
+}
+
+func TestWebSocket_NewImageAndSlideshowUpdate(t *testing.T) {
+	dir := apiFixture(t)
+	s := newTestServer(t, dir, watcher.Options{Recursive: true, SlideshowCount: 2}, Options{})
+	if err := s.watcher.Start(); err != nil {
+		t.Fatal(err)
+	}
+	conn := dialWS(t, s)
+
+	// Escrita fora do diretório e movida para dentro: um único evento
+	staging := filepath.Join(t.TempDir(), "new.png")
+	f, err := os.Create(staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewGray(image.Rect(0, 0, 8, 4)))
+	f.Close()
+	if err := os.Rename(staging, filepath.Join(dir, "new.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A nova imagem chega antes da lista que já a inclui
+	msg := readWS(t, conn)
+	if msg.Type != "new_image" || msg.Path != "new.png" || msg.Version == "" {
+		t.Fatalf("primeira mensagem = %+v, want new_image de new.png", msg)
+	}
+	if msg.Meta == nil || msg.Meta.Width != 8 || msg.Meta.Height != 4 {
+		t.Errorf("new_image meta = %+v", msg.Meta)
+	}
+
+	msg = readWS(t, conn)
+	if msg.Type != "slideshow_update" {
+		t.Fatalf("segunda mensagem = %+v, want slideshow_update", msg)
+	}
+	want := []string{"new.png", "notes dec.png"}
+	if !reflect.DeepEqual(msg.Images, want) {
+		t.Errorf("slideshow_update images = %v, want %v", msg.Images, want)
+	}
+	for _, p := range want {
+		if msg.Versions[p] == "" || msg.Metas[p] == nil {
+			t.Errorf("slideshow_update sem versão ou metadados de %q: %v %v", p, msg.Versions, msg.Metas)
+		}
+	}
+	if msg.Metas["notes dec.png"].Width != 20 {
+		t.Errorf("slideshow_update meta de notes dec.png = %+v", msg.Metas["notes dec.png"])
+	}
+}
+
+func TestBroadcast_Order(t *testing.T) {
+	s := newTestServer(t, t.TempDir(), watcher.Options{}, Options{})
+	conn := dialWS(t, s)
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		s.broadcast(wsMessage{Type: "status", Message: strconv.Itoa(i)})
+	}
+	for i := 0; i < n; i++ {
+		if msg := readWS(t, conn); msg.Message != strconv.Itoa(i) {
+			t.Fatalf("mensagem %d = %q, fora de ordem", i, msg.Message)
+		}
+	}
+}
+
+func TestBroadcast_SlowClient(t *testing.T) {
+	s := newTestServer(t, t.TempDir(), watcher.Options{}, Options{})
+
+	// Cliente cuja fila nunca é esvaziada (sem writePump)
+	conns := make(chan *websocket.Conn, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := s.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	defer ts.Close()
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	slow := &wsClient{conn: <-conns, send: make(chan []byte, 1)}
+	s.clientsMu.Lock()
+	s.clients[slow] = true
+	s.clientsMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.broadcast(wsMessage{Type: "status", Message: "1"})
+		s.broadcast(wsMessage{Type: "status", Message: "2"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("broadcast travou no cliente lento")
+	}
+
+	// A fila guarda a primeira mensagem; a segunda derruba a conexão
+	if got := string(<-slow.send); !strings.Contains(got, `"message":"1"`) {
+		t.Errorf("fila = %s", got)
+	}
+	peer.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := peer.ReadMessage(); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("cliente lento deveria ser desconectado, err = %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/verseles/sidelook/internal/watcher"
+)
+
+// newTestServer cria um servidor sobre dir, sem escutar em nenhuma porta, com
+// as miniaturas em um diretório temporário. O watcher é parado no fim do teste.
+func newTestServer(t *testing.T, dir string, wopts watcher.Options, opts Options) *Server {
+	t.Helper()
+	w, err := watcher.NewWithOptions(dir, wopts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Stop() })
+	if _, _, err := w.ScanExisting(); err != nil {
+		t.Fatal(err)
+	}
+	opts.ThumbDir = t.TempDir()
+	return NewWithOptions(w, opts)
+}
+
+// get faz um GET em url direto no handler do servidor, com os cabeçalhos header
+func get(s *Server, url string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
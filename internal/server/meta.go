@@ -0,0 +1,76 @@
+// internal/server/meta.go
+package server
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/verseles/sidelook/internal/imagetype"
+	"github.com/verseles/sidelook/internal/watcher"
+)
+
+// metadata são os metadados de uma imagem enviados ao navegador (mensagens
+// WebSocket e página inicial) e pela API. Dimensões, modelo de cor e quadros
+// vêm do cabeçalho do arquivo, lido uma vez e guardado no índice do watcher.
+type metadata struct {
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mtime"`
+	Format     string    `json:"format,omitempty"`
+	Width      int       `json:"width,omitempty"`
+	Height     int       `json:"height,omitempty"`
+	ColorModel string    `json:"color_model,omitempty"`
+	Depth      int       `json:"depth,omitempty"` // Bits por canal
+	Frames     int       `json:"frames,omitempty"`
+
+	// Orientation é a orientação EXIF; Width e Height já estão como a
+	// imagem é exibida
+	Orientation int `json:"orientation,omitempty"`
+
+	// Text são as chaves dos textos embutidos ("prompt", "workflow"...),
+	// servidos por /api/v1/images/{path}/text
+	Text []string `json:"text,omitempty"`
+}
+
+// metaOf monta os metadados de uma imagem já descrita pelo watcher
+func metaOf(img *watcher.ImageInfo) metadata {
+	meta := metadata{Size: img.Size, ModTime: img.ModTime}
+	if h := img.Header; h != nil {
+		meta.Format = string(h.Format)
+		meta.Width, meta.Height = h.DisplaySize()
+		meta.ColorModel = string(h.ColorModel)
+		meta.Depth = h.Depth
+		meta.Frames = h.Frames
+		meta.Orientation = h.Orientation
+		meta.Text = h.Text
+	}
+	// Conteúdo não reconhecido: o formato que a extensão indica
+	if meta.Format == "" {
+		meta.Format = string(imagetype.FromExtension(filepath.Ext(img.Path)))
+	}
+	return meta
+}
+
+// imageMeta retorna os metadados de uma imagem pelo caminho relativo (nil se
+// ela não for conhecida)
+func (s *Server) imageMeta(rel string) *metadata {
+	if rel == "" {
+		return nil
+	}
+	img, ok := s.watcher.Describe(rel)
+	if !ok {
+		return nil
+	}
+	meta := metaOf(img)
+	return &meta
+}
+
+// imageMetas retorna os metadados das imagens informadas
+func (s *Server) imageMetas(paths []string) map[string]*metadata {
+	metas := make(map[string]*metadata, len(paths))
+	for _, p := range paths {
+		if meta := s.imageMeta(p); meta != nil {
+			metas[p] = meta
+		}
+	}
+	return metas
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/verseles/sidelook/internal/watcher"
+)
+
+func TestImageMeta(t *testing.T) {
+	dir := t.TempDir()
+	f, _ := os.Create(filepath.Join(dir, "render.png"))
+	png.Encode(f, image.NewGray16(image.Rect(0, 0, 40, 30)))
+	f.Close()
+	os.WriteFile(filepath.Join(dir, "broken.jpg"), []byte("não é jpeg"), 0644)
+	// render.png é a imagem atual mesmo que as duas caiam no mesmo tick do mtime
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "broken.jpg"), past, past)
+	s := newTestServer(t, dir, watcher.Options{}, Options{})
+
+	meta := s.imageMeta("render.png")
+	if meta == nil {
+		t.Fatal("imageMeta() = nil")
+	}
+	info, _ := os.Stat(filepath.Join(dir, "render.png"))
+	want := metadata{Size: info.Size(), ModTime: info.ModTime(), Format: "png", Width: 40, Height: 30, ColorModel: "gray", Depth: 16, Frames: 1, Orientation: 1}
+	if !meta.ModTime.Equal(want.ModTime) {
+		t.Errorf("mtime = %v, want %v", meta.ModTime, want.ModTime)
+	}
+	meta.ModTime = want.ModTime
+	if !reflect.DeepEqual(*meta, want) {
+		t.Errorf("imageMeta() = %+v, want %+v", *meta, want)
+	}
+
+	// Conteúdo não reconhecido: formato pela extensão, sem dimensões
+	if meta := s.imageMeta("broken.jpg"); meta == nil || meta.Format != "jpeg" || meta.Width != 0 {
+		t.Errorf("imageMeta(broken.jpg) = %+v", meta)
+	}
+	if s.imageMeta("") != nil || s.imageMeta("missing.png") != nil {
+		t.Error("imageMeta() de imagem desconhecida deveria ser nil")
+	}
+
+	// Mensagem WebSocket leva os metadados de Path
+	data, _ := json.Marshal(wsMessage{Type: "new_image", Path: "render.png", Meta: s.imageMeta("render.png")})
+	var msg struct {
+		Meta map[string]any `json:"meta"`
+	}
+	json.Unmarshal(data, &msg)
+	for _, key := range []string{"size", "mtime", "format", "width", "height", "color_model", "depth", "frames", "orientation"} {
+		if _, ok := msg.Meta[key]; !ok {
+			t.Errorf("mensagem sem meta.%s: %s", key, data)
+		}
+	}
+
+	// Página inicial já traz as dimensões da imagem atual
+	body := get(s, "/", nil).Body.String()
+	if !strings.Contains(body, `"render.png":{"size":`) || !strings.Contains(body, `"width":40`) {
+		t.Error("página inicial sem os metadados da imagem atual")
+	}
+}
@@ -0,0 +1,48 @@
+// internal/server/security.go
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+)
+
+// Políticas de segurança de conteúdo (Content-Security-Policy)
+const (
+	// defaultPolicy vale para imagens, miniaturas e a API: nada é carregado
+	// nem executado, e o sandbox isola qualquer documento aberto direto
+	defaultPolicy = "default-src 'none'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'; sandbox"
+
+	// svgPolicy deixa um SVG aberto direto usar estilos e imagens embutidos,
+	// mas sem scripts e em uma origem isolada da página e do WebSocket
+	svgPolicy = "default-src 'none'; style-src 'unsafe-inline'; img-src data:; font-src data:; frame-ancestors 'none'; sandbox"
+)
+
+// pagePolicy é a política da página do visualizador: só o script e o estilo
+// com o nonce da resposta rodam, e as conexões ficam na mesma origem. A
+// página pode ser embutida (ex: painel lateral do editor).
+func pagePolicy(nonce string) string {
+	return "default-src 'none'; script-src 'nonce-" + nonce + "'; style-src 'nonce-" + nonce + "'; " +
+		"img-src 'self' data: blob:; connect-src 'self'; base-uri 'none'; form-action 'none'"
+}
+
+// newNonce gera um nonce aleatório para a Content-Security-Policy da página
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// securityHeaders adiciona os cabeçalhos de segurança a todas as respostas.
+// Handlers que servem HTML ou SVG trocam a política padrão pela sua.
+func securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Content-Security-Policy", defaultPolicy)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "no-referrer")
+		next.ServeHTTP(w, r)
+	})
+}
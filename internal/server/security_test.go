@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/verseles/sidelook/internal/watcher"
+)
+
+const evilSVG = `<svg xmlns="http://www.w3.org/2000/svg" onload="fetch('/api/v1/status')"><script>alert(document.domain)</script><rect width="4" height="4"/></svg>`
+
+func TestSecurityHeaders(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "render.png"), []byte("\x89PNG\r\n\x1a\nfake"), 0644)
+	s := newTestServer(t, dir, watcher.Options{Recursive: true}, Options{})
+
+	for _, url := range []string{"/", "/image/render.png", "/thumb/render.png", "/api/v1/status", "/image/missing.png", "/nada"} {
+		rec := get(s, url, nil)
+		h := rec.Header()
+		if got := h.Get("X-Content-Type-Options"); got != "nosniff" {
+			t.Errorf("GET %s X-Content-Type-Options = %q", url, got)
+		}
+		if got := h.Get("Referrer-Policy"); got != "no-referrer" {
+			t.Errorf("GET %s Referrer-Policy = %q", url, got)
+		}
+		if url != "/" && h.Get("Content-Security-Policy") != defaultPolicy {
+			t.Errorf("GET %s Content-Security-Policy = %q", url, h.Get("Content-Security-Policy"))
+		}
+	}
+
+	// A página só roda o script e o estilo com o nonce da resposta
+	nonceAttr := regexp.MustCompile(`<(script|style) nonce="([^"]+)">`)
+	var nonces []string
+	for i := 0; i < 2; i++ {
+		rec := get(s, "/", nil)
+		page := rec.Body.String()
+		csp := rec.Header().Get("Content-Security-Policy")
+
+		tags := nonceAttr.FindAllStringSubmatch(page, -1)
+		if len(tags) != 2 || tags[0][2] != tags[1][2] {
+			t.Fatalf("tags com nonce = %q", tags)
+		}
+		nonce := tags[0][2]
+		if csp != pagePolicy(nonce) || strings.Contains(csp, "unsafe-inline") {
+			t.Errorf("Content-Security-Policy = %q", csp)
+		}
+		if strings.Contains(page, "onclick=") || strings.Count(page, "<script") != 1 {
+			t.Error("página com script ou handler inline fora do nonce")
+		}
+		nonces = append(nonces, nonce)
+	}
+	if nonces[0] == nonces[1] {
+		t.Error("nonce deveria mudar a cada resposta")
+	}
+}
+
+func TestSVGServing(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "evil.svg"), []byte(evilSVG), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.svg"), []byte(`<svg><g></svg>`), 0644)
+
+	for _, sanitize := range []bool{false, true} {
+		s := newTestServer(t, dir, watcher.Options{}, Options{SanitizeSVG: sanitize})
+
+		for _, url := range []string{"/image/evil.svg", "/thumb/evil.svg", "/image/evil.svg?raw=1"} {
+			rec := get(s, url, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("sanitize=%v: GET %s = %d", sanitize, url, rec.Code)
+			}
+			// Mesmo sem limpar, o SVG aberto direto não roda script na origem do visualizador
+			if csp := rec.Header().Get("Content-Security-Policy"); csp != svgPolicy {
+				t.Errorf("sanitize=%v: GET %s Content-Security-Policy = %q", sanitize, url, csp)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "image/svg+xml" {
+				t.Errorf("sanitize=%v: GET %s Content-Type = %q", sanitize, url, ct)
+			}
+
+			body := rec.Body.String()
+			cleaned := sanitize && !strings.Contains(url, "raw=1")
+			if cleaned && (strings.Contains(body, "<script") || strings.Contains(body, "onload") || !strings.Contains(body, "<rect")) {
+				t.Errorf("GET %s não limpou o SVG:\n%s", url, body)
+			}
+			if !cleaned && body != evilSVG {
+				t.Errorf("sanitize=%v: GET %s deveria servir o original:\n%s", sanitize, url, body)
+			}
+		}
+
+		// SVG que não dá para limpar não é servido
+		want := http.StatusOK
+		if sanitize {
+			want = http.StatusForbidden
+		}
+		if rec := get(s, "/image/broken.svg", nil); rec.Code != want {
+			t.Errorf("sanitize=%v: GET /image/broken.svg = %d, want %d", sanitize, rec.Code, want)
+		}
+	}
+}
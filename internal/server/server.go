@@ -3,13 +3,17 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/verseles/sidelook/internal/thumb"
+	"github.com/verseles/sidelook/internal/transcode"
 	"github.com/verseles/sidelook/internal/watcher"
 )
 
@@ -18,9 +22,16 @@ type Server struct {
 	watcher           *watcher.ImageWatcher
 	server            *http.Server
 	mux               *http.ServeMux
+	handler           http.Handler // mux com os cabeçalhos de segurança
 	port              int
+	bind              string // Endereço de escuta
+	token             string // Token de acesso ("" = livre)
 	upgrader          websocket.Upgrader
 	slideshowInterval int // Intervalo em segundos entre imagens no slideshow
+	transcoder        *transcode.Transcoder
+	thumbs            *thumb.Cache
+	sanitizeSVG       bool
+	started           time.Time
 
 	clients   map[*wsClient]bool
 	clientsMu sync.RWMutex
@@ -32,21 +43,67 @@ type wsClient struct {
 	send chan []byte
 }
 
+// Options configura o servidor
+type Options struct {
+	// Port é a porta preferida (0 = 8080); se ocupada, as seguintes são tentadas
+	Port int
+
+	// Bind é o endereço de escuta ("" = 127.0.0.1; "0.0.0.0" ou "::" para a
+	// rede local). Fora do loopback, Start gera um token se Token for vazio.
+	Bind string
+
+	// Token, se informado, é exigido em todas as rotas (?token= ou cookie)
+	Token string
+
+	// SlideshowInterval é o intervalo em segundos entre imagens no slideshow
+	SlideshowInterval int
+
+	// ThumbDir é o diretório do cache de miniaturas (vazio = thumb.DefaultDir)
+	ThumbDir string
+
+	// ThumbCacheSize limita o cache de miniaturas em bytes (0 = thumb.DefaultMaxBytes)
+	ThumbCacheSize int64
+
+	// SanitizeSVG remove scripts e atributos de evento dos SVGs servidos
+	// (exceto com ?raw=1)
+	SanitizeSVG bool
+}
+
 // New cria um novo servidor
 func New(w *watcher.ImageWatcher, preferredPort int, slideshowInterval int) *Server {
+	return NewWithOptions(w, Options{Port: preferredPort, SlideshowInterval: slideshowInterval})
+}
+
+// NewWithOptions cria um novo servidor com as opções informadas
+func NewWithOptions(w *watcher.ImageWatcher, opts Options) *Server {
+	preferredPort, slideshowInterval := opts.Port, opts.SlideshowInterval
+
+	thumbDir := opts.ThumbDir
+	if thumbDir == "" {
+		thumbDir = thumb.DefaultDir()
+	}
+
 	s := &Server{
 		watcher:           w,
 		mux:               http.NewServeMux(),
 		clients:           make(map[*wsClient]bool),
 		slideshowInterval: slideshowInterval,
+		transcoder:        transcode.New(transcode.DefaultCacheSize),
+		thumbs:            thumb.New(thumbDir, opts.ThumbCacheSize),
+		sanitizeSVG:       opts.SanitizeSVG,
+		started:           time.Now(),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Aceitar qualquer origem (localhost)
-			},
 		},
 	}
+	s.upgrader.CheckOrigin = s.checkOrigin
+
+	s.bind = opts.Bind
+	if s.bind == "" {
+		s.bind = "127.0.0.1"
+	}
+	s.token = opts.Token
 
 	if preferredPort == 0 {
 		preferredPort = 8080
@@ -59,10 +116,21 @@ func New(w *watcher.ImageWatcher, preferredPort int, slideshowInterval int) *Ser
 	s.slideshowInterval = slideshowInterval
 
 	s.registerRoutes()
+	s.handler = securityHeaders(s.requireToken(s.mux))
 
 	// Configurar callbacks do watcher
 	w.OnNewImage = s.broadcastNewImage
 	w.OnImageDeleted = s.broadcastImageDeleted
+	w.OnRecentChanged = s.broadcastSlideshowUpdate
+
+	// Preservar o callback de saúde já configurado (ex: log no terminal)
+	onHealth := w.OnHealthChanged
+	w.OnHealthChanged = func(st watcher.Status) {
+		if onHealth != nil {
+			onHealth(st)
+		}
+		s.broadcastStatus(st)
+	}
 
 	return s
 }
@@ -71,17 +139,28 @@ func New(w *watcher.ImageWatcher, preferredPort int, slideshowInterval int) *Ser
 func (s *Server) Start() error {
 	const maxAttempts = 100
 
+	// Fora do loopback, qualquer um na rede alcança o servidor
+	if s.token == "" && !isLoopback(s.bind) {
+		token, err := newNonce()
+		if err != nil {
+			return fmt.Errorf("não foi possível gerar o token de acesso: %w", err)
+		}
+		s.token = token
+	}
+
+	var lastErr error
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		addr := fmt.Sprintf("127.0.0.1:%d", s.port)
+		addr := net.JoinHostPort(s.bind, strconv.Itoa(s.port))
 
 		listener, err := net.Listen("tcp", addr)
 		if err != nil {
+			lastErr = err
 			s.port++
 			continue
 		}
 
 		s.server = &http.Server{
-			Handler:      s.mux,
+			Handler:      s.handler,
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
 			IdleTimeout:  60 * time.Second,
@@ -91,8 +170,8 @@ func (s *Server) Start() error {
 		return nil
 	}
 
-	return fmt.Errorf("não foi possível iniciar servidor: portas %d-%d indisponíveis",
-		s.port-maxAttempts+1, s.port)
+	return fmt.Errorf("não foi possível iniciar servidor: portas %d-%d indisponíveis em %s: %w",
+		s.port-maxAttempts+1, s.port, s.bind, lastErr)
 }
 
 // Port retorna a porta em que o servidor está rodando
@@ -100,9 +179,19 @@ func (s *Server) Port() int {
 	return s.port
 }
 
-// URL retorna a URL completa do servidor
+// URL retorna a URL completa do servidor nesta máquina, com o token de
+// acesso quando há um
 func (s *Server) URL() string {
-	return fmt.Sprintf("http://localhost:%d", s.port)
+	host := "localhost"
+	if ip := net.ParseIP(s.bind); ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
+		host = ip.String() // Escutando só em um IP da rede
+	}
+	return serverURL(host, s.port, s.token)
+}
+
+// Token retorna o token de acesso ("" quando o acesso é livre)
+func (s *Server) Token() string {
+	return s.token
 }
 
 // Stop para o servidor graciosamente
@@ -140,6 +229,13 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	s.clients[client] = true
 	s.clientsMu.Unlock()
 
+	// Clientes que conectam durante um problema recebem a saúde atual
+	if st := s.watcher.Status(); st.Health != watcher.HealthOK {
+		if data, err := json.Marshal(statusMessage(st)); err == nil {
+			client.send <- data
+		}
+	}
+
 	go s.writePump(client)
 	go s.readPump(client)
 }
@@ -0,0 +1,259 @@
+// Package svgsafe remove de arquivos SVG o que pode executar código quando
+// o arquivo é aberto direto no navegador: elementos script, atributos de
+// evento (onload, onclick...), links javascript:, conteúdo de outros
+// namespaces (HTML embutido) e animações que trocam links ou eventos.
+package svgsafe
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"maps"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MaxSize é o maior SVG aceito (16MB)
+const MaxSize = 16 << 20
+
+const svgNS = "http://www.w3.org/2000/svg"
+
+var (
+	// ErrTooLarge indica um arquivo maior que MaxSize
+	ErrTooLarge = errors.New("svgsafe: arquivo grande demais")
+
+	// ErrInvalid indica um arquivo que não é um SVG bem formado
+	ErrInvalid = errors.New("svgsafe: SVG inválido")
+)
+
+// blockedElements são removidos com todo o conteúdo. handler e listener são
+// os scripts do SVG Tiny (XML Events).
+var blockedElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"handler":       true,
+	"listener":      true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+}
+
+// animationElements alteram atributos de outros elementos em tempo de
+// execução (ex: <set attributeName="href" to="javascript:...">)
+var animationElements = map[string]bool{
+	"animate":          true,
+	"animatecolor":     true,
+	"animatemotion":    true,
+	"animatetransform": true,
+	"set":              true,
+}
+
+// entityDecl reconhece entidades internas do DOCTYPE, comuns em SVGs do
+// Illustrator (<!ENTITY ns_svg "http://www.w3.org/2000/svg">)
+var entityDecl = regexp.MustCompile(`<!ENTITY\s+([A-Za-z_][\w.:-]*)\s+(?:"([^"]*)"|'([^']*)')\s*>`)
+
+// Sanitize lê um SVG e retorna uma cópia sem scripts. Comentários, instruções
+// de processamento (incluindo folhas de estilo XSLT) e o DOCTYPE são
+// descartados; as entidades declaradas nele são expandidas.
+func Sanitize(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Entity = map[string]string{}
+
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+
+	var open []xml.Name               // Elementos abertos, para conferir os fechamentos
+	scopes := []map[string]string{{}} // Prefixo -> namespace em cada nível
+	skip := 0                         // Profundidade dentro de um elemento removido
+	root := false
+
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalid
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(open) == 0 {
+				if root || t.Name.Local != "svg" {
+					return nil, ErrInvalid
+				}
+				root = true
+			}
+			scope := bind(scopes[len(scopes)-1], t.Attr)
+			scopes = append(scopes, scope)
+			open = append(open, t.Name)
+			if skip > 0 || !allowed(t, scope) {
+				skip++
+				continue
+			}
+			writeStart(&out, t)
+
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, ErrInvalid
+			}
+			open = open[:len(open)-1]
+			scopes = scopes[:len(scopes)-1]
+			if skip > 0 {
+				skip--
+				continue
+			}
+			out.WriteString("</")
+			writeName(&out, t.Name)
+			out.WriteByte('>')
+
+		case xml.CharData:
+			if skip == 0 && len(open) > 0 {
+				xml.EscapeText(&out, t)
+			}
+
+		case xml.Directive:
+			for _, m := range entityDecl.FindAllSubmatch(t, -1) {
+				dec.Entity[string(m[1])] = string(m[2]) + string(m[3])
+			}
+		}
+	}
+
+	if !root || len(open) > 0 {
+		return nil, ErrInvalid
+	}
+	return out.Bytes(), nil
+}
+
+// bind retorna o escopo de namespaces com as declarações xmlns de attrs
+func bind(parent map[string]string, attrs []xml.Attr) map[string]string {
+	scope := parent
+	copied := false
+	for _, a := range attrs {
+		var prefix string
+		switch {
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+		case a.Name.Space == "xmlns":
+			prefix = a.Name.Local
+		default:
+			continue
+		}
+		if !copied {
+			scope, copied = maps.Clone(parent), true
+		}
+		scope[prefix] = a.Value
+	}
+	return scope
+}
+
+// allowed indica se o elemento pode ser mantido: só SVG (ou sem namespace,
+// que o navegador nem trata como SVG), fora da lista de bloqueados e sem
+// animar links ou eventos
+func allowed(el xml.StartElement, scope map[string]string) bool {
+	ns := scope[el.Name.Space]
+	if ns != svgNS && (ns != "" || el.Name.Space != "") {
+		return false
+	}
+
+	name := strings.ToLower(el.Name.Local)
+	if blockedElements[name] {
+		return false
+	}
+	if animationElements[name] {
+		for _, a := range el.Attr {
+			if !strings.EqualFold(a.Name.Local, "attributeName") {
+				continue
+			}
+			target := strings.ToLower(strings.TrimSpace(a.Value))
+			if i := strings.IndexByte(target, ':'); i >= 0 {
+				target = target[i+1:] // xlink:href
+			}
+			if target == "href" || strings.HasPrefix(target, "on") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// safeAttr indica se o atributo pode ser mantido: atributos de evento são
+// removidos e links só podem apontar para http(s), fragmentos, caminhos
+// relativos ou imagens raster em data:
+func safeAttr(a xml.Attr) bool {
+	name := strings.ToLower(a.Name.Local)
+	if strings.HasPrefix(name, "on") {
+		return false
+	}
+	switch name {
+	case "href", "src", "action", "formaction":
+		return safeURL(a.Value)
+	}
+	return true
+}
+
+// safeImages são os tipos aceitos em URLs data:
+var safeImages = []string{"data:image/png", "data:image/jpeg", "data:image/gif", "data:image/webp"}
+
+func safeURL(value string) bool {
+	// Navegadores ignoram espaços e caracteres de controle no esquema
+	// ("java\tscript:")
+	var b strings.Builder
+	for _, r := range value {
+		if r > ' ' {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	u := b.String()
+
+	i := strings.IndexAny(u, ":/?#")
+	if i < 0 || u[i] != ':' {
+		return true // Relativo ou fragmento
+	}
+	switch u[:i] {
+	case "http", "https":
+		return true
+	case "data":
+		for _, prefix := range safeImages {
+			if strings.HasPrefix(u, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeStart(out *bytes.Buffer, el xml.StartElement) {
+	out.WriteByte('<')
+	writeName(out, el.Name)
+	for _, a := range el.Attr {
+		if !safeAttr(a) {
+			continue
+		}
+		out.WriteByte(' ')
+		writeName(out, a.Name)
+		out.WriteString(`="`)
+		xml.EscapeText(out, []byte(a.Value))
+		out.WriteByte('"')
+	}
+	out.WriteByte('>')
+}
+
+// writeName escreve o nome com o prefixo original (RawToken não resolve
+// namespaces)
+func writeName(out *bytes.Buffer, name xml.Name) {
+	if name.Space != "" {
+		out.WriteString(name.Space)
+		out.WriteByte(':')
+	}
+	out.WriteString(name.Local)
+}
@@ -0,0 +1,114 @@
+package svgsafe
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitize_RemovesScripts(t *testing.T) {
+	tests := []struct {
+		name string
+		svg  string
+	}{
+		{"script", `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script><rect/></svg>`},
+		{"script com CDATA", `<svg xmlns="http://www.w3.org/2000/svg"><script><![CDATA[alert(1)]]></script><rect/></svg>`},
+		{"script com prefixo", `<s:svg xmlns:s="http://www.w3.org/2000/svg"><s:script>alert(1)</s:script><s:rect/></s:svg>`},
+		{"atributo de evento", `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><rect/></svg>`},
+		{"evento em maiúsculas", `<svg xmlns="http://www.w3.org/2000/svg"><rect ONCLICK="alert(1)"/></svg>`},
+		{"link javascript", `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><a xlink:href="javascript:alert(1)"><rect/></a></svg>`},
+		{"link com entidade e tab", `<svg xmlns="http://www.w3.org/2000/svg"><a href=" java&#9;script&#58;alert(1)"><rect/></a></svg>`},
+		{"HTML embutido", `<svg xmlns="http://www.w3.org/2000/svg"><foreignObject><body xmlns="http://www.w3.org/1999/xhtml"><img src="x" onerror="alert(1)"/></body></foreignObject><rect/></svg>`},
+		{"script XHTML", `<svg xmlns="http://www.w3.org/2000/svg" xmlns:h="http://www.w3.org/1999/xhtml"><h:script>alert(1)</h:script><rect/></svg>`},
+		{"iframe XHTML sem prefixo", `<svg xmlns="http://www.w3.org/2000/svg"><iframe xmlns="http://www.w3.org/1999/xhtml" src="javascript:alert(1)"/><rect/></svg>`},
+		{"set em href", `<svg xmlns="http://www.w3.org/2000/svg"><a><set attributeName="href" to="javascript:alert(1)"/><rect/></a></svg>`},
+		{"animate em xlink:href", `<svg xmlns="http://www.w3.org/2000/svg"><a><animate attributeName="xlink:href" values="javascript:alert(1)"/><rect/></a></svg>`},
+		{"handler SVG Tiny", `<svg xmlns="http://www.w3.org/2000/svg"><handler type="application/ecmascript">alert(1)</handler><rect/></svg>`},
+		{"use com SVG em data", `<svg xmlns="http://www.w3.org/2000/svg"><use href="data:image/svg+xml;base64,PHN2Zz48c2NyaXB0PmFsZXJ0KDEpPC9zY3JpcHQ+PC9zdmc+#x"/><rect/></svg>`},
+		{"XSLT", `<?xml-stylesheet type="text/xsl" href="data:text/xml,alert(1)"?><svg xmlns="http://www.w3.org/2000/svg"><rect/></svg>`},
+		{"entidade com script", `<!DOCTYPE svg [<!ENTITY x "&lt;script&gt;alert(1)&lt;/script&gt;">]><svg xmlns="http://www.w3.org/2000/svg"><text>&x;</text><rect/></svg>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Sanitize(strings.NewReader(tt.svg))
+			if err != nil {
+				t.Fatalf("Sanitize() error = %v", err)
+			}
+			got := strings.ToLower(string(out))
+			for _, bad := range []string{"<script", "script>", "onload", "onclick", "onerror", "javascript", "<iframe", "foreignobject", "data:image/svg", "xml-stylesheet", "<handler"} {
+				if strings.Contains(got, bad) {
+					t.Errorf("Sanitize() manteve %q:\n%s", bad, out)
+				}
+			}
+			// O resto do desenho é mantido e o resultado continua bem formado
+			if !strings.Contains(got, "rect") {
+				t.Errorf("Sanitize() removeu o desenho:\n%s", out)
+			}
+			if err := xml.Unmarshal(out, new(struct{})); err != nil {
+				t.Errorf("resultado não é XML válido: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func TestSanitize_KeepsDrawing(t *testing.T) {
+	svg := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- Gerado pelo Illustrator -->
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd" [
+	<!ENTITY ns_svg "http://www.w3.org/2000/svg">
+]>
+<svg xmlns="&ns_svg;" xmlns:xlink="http://www.w3.org/1999/xlink" width="10" height="10">
+<style>.a { fill: url(#g); }</style>
+<defs><linearGradient id="g"><stop offset="0" stop-color="#f00"/></linearGradient></defs>
+<a href="https://example.com/?a=1&amp;b=2"><rect class="a" opacity="0.5" width="10" height="10"/></a>
+<use xlink:href="#g"/>
+<image href="data:image/png;base64,iVBORw0KGgo="/>
+<animate attributeName="opacity" from="0" to="1" dur="1s"/>
+<text>R&amp;D &lt;1&gt;</text>
+</svg>`
+	out, err := Sanitize(strings.NewReader(svg))
+	if err != nil {
+		t.Fatalf("Sanitize() error = %v", err)
+	}
+	got := string(out)
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="10" height="10">`,
+		`<style>.a { fill: url(#g); }</style>`,
+		`<stop offset="0" stop-color="#f00"></stop>`,
+		`<a href="https://example.com/?a=1&amp;b=2">`,
+		`<rect class="a" opacity="0.5" width="10" height="10"></rect>`,
+		`<use xlink:href="#g"></use>`,
+		`<image href="data:image/png;base64,iVBORw0KGgo="></image>`,
+		`<animate attributeName="opacity" from="0" to="1" dur="1s"></animate>`,
+		`<text>R&amp;D &lt;1&gt;</text>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Sanitize() sem %s:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Illustrator") || strings.Contains(got, "DOCTYPE") {
+		t.Errorf("comentário e DOCTYPE deveriam ser removidos:\n%s", got)
+	}
+}
+
+func TestSanitize_Invalid(t *testing.T) {
+	for _, svg := range []string{
+		"",
+		"não é xml",
+		`<html><script>alert(1)</script></html>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"><g></svg>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"><g></a></svg>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"/><svg/>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"><text>&desconhecida;</text></svg>`,
+	} {
+		if _, err := Sanitize(strings.NewReader(svg)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Sanitize(%q) error = %v, want ErrInvalid", svg, err)
+		}
+	}
+
+	big := `<svg xmlns="http://www.w3.org/2000/svg"><!--` + strings.Repeat("a", MaxSize) + `--></svg>`
+	if _, err := Sanitize(strings.NewReader(big)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Sanitize(grande) error = %v, want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,289 @@
+// Package textmeta lê textos embutidos em imagens: chunks tEXt, zTXt e iTXt
+// do PNG, comentários do JPEG e campos de texto do EXIF (JPEG e WebP). É onde
+// ferramentas de IA generativa (ComfyUI, Stable Diffusion) guardam prompt,
+// seed, sampler e o workflow usados para gerar a imagem.
+package textmeta
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/verseles/sidelook/internal/exif"
+	"github.com/verseles/sidelook/internal/imagetype"
+)
+
+// Limites de segurança contra arquivos malformados e bombas de compressão
+const (
+	maxEntryText = 8 << 20  // Texto de uma entrada, já descomprimido
+	maxTotalText = 32 << 20 // Soma dos textos de uma imagem
+	maxKeyword   = 79       // Limite de palavra-chave da especificação PNG
+	maxChunks    = 1 << 16
+)
+
+// errInvalid indica um contêiner malformado
+var errInvalid = errors.New("textmeta: arquivo inválido")
+
+// xmpKeyword é a palavra-chave do iTXt com o pacote XMP, lido pelo pacote exif
+const xmpKeyword = "XML:com.adobe.xmp"
+
+// Entry é um texto embutido na imagem. Key é a palavra-chave do chunk PNG
+// ("parameters", "prompt", "workflow"...), "comment" para comentários JPEG
+// ou o nome do campo EXIF de onde o texto veio.
+type Entry struct {
+	Key  string `json:"key"`
+	Text string `json:"text"`
+}
+
+// ReadFile lê os textos embutidos na imagem em path
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Decode(f, info.Size())
+}
+
+// Decode lê os textos embutidos em r, na ordem em que aparecem no arquivo.
+// Formatos sem textos (ou arquivos sem eles) retornam uma lista vazia.
+func Decode(r io.ReaderAt, size int64) ([]Entry, error) {
+	return decode(r, size, false)
+}
+
+// Keys retorna só as chaves dos textos embutidos em r, sem ler nem
+// descomprimir os textos dos chunks PNG
+func Keys(r io.ReaderAt, size int64) ([]string, error) {
+	entries, err := decode(r, size, true)
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	return keys, err
+}
+
+func decode(r io.ReaderAt, size int64, keysOnly bool) ([]Entry, error) {
+	head := make([]byte, imagetype.HeaderSize)
+	n, err := r.ReadAt(head, 0)
+	if n == 0 && err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch imagetype.Detect(head[:n]) {
+	case imagetype.PNG:
+		return decodePNG(r, size, keysOnly)
+	case imagetype.JPEG:
+		entries, err := decodeJPEG(r, size)
+		return append(entries, exifEntries(r, size)...), err
+	case imagetype.WebP:
+		return exifEntries(r, size), nil
+	}
+	return []Entry{}, nil
+}
+
+// decodePNG percorre todos os chunks: os textos podem vir antes ou depois
+// dos dados da imagem
+func decodePNG(r io.ReaderAt, size int64, keysOnly bool) ([]Entry, error) {
+	entries := []Entry{}
+	total := 0
+	offset := int64(8)
+	header := make([]byte, 8)
+
+	for i := 0; i < maxChunks && offset+8 <= size; i++ {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return entries, errInvalid
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		typ := string(header[4:8])
+		if typ == "IEND" {
+			break
+		}
+
+		if typ == "tEXt" || typ == "zTXt" || typ == "iTXt" {
+			// Só o suficiente para a palavra-chave quando o texto não interessa
+			n := length
+			if keysOnly {
+				n = min(length, maxKeyword+1)
+			}
+			if n > maxEntryText {
+				offset += 12 + length
+				continue
+			}
+			data := make([]byte, n)
+			if _, err := r.ReadAt(data, offset+8); err != nil {
+				return entries, errInvalid
+			}
+
+			e, ok := parseTextChunk(typ, data, keysOnly)
+			if ok && total+len(e.Text) <= maxTotalText {
+				total += len(e.Text)
+				entries = append(entries, e)
+			}
+		}
+		offset += 12 + length
+	}
+	return entries, nil
+}
+
+// parseTextChunk interpreta um chunk tEXt, zTXt ou iTXt
+func parseTextChunk(typ string, data []byte, keysOnly bool) (Entry, bool) {
+	sep := bytes.IndexByte(data, 0)
+	if sep < 1 || sep > maxKeyword {
+		return Entry{}, false
+	}
+	key := latin1(data[:sep])
+	if key == xmpKeyword {
+		return Entry{}, false
+	}
+	if keysOnly {
+		return Entry{Key: key}, true
+	}
+	rest := data[sep+1:]
+
+	switch typ {
+	case "tEXt":
+		return Entry{Key: key, Text: latin1(rest)}, true
+
+	case "zTXt":
+		// Método de compressão (0 = zlib) seguido dos dados
+		if len(rest) < 1 || rest[0] != 0 {
+			return Entry{}, false
+		}
+		text, ok := inflate(rest[1:])
+		if !ok {
+			return Entry{}, false
+		}
+		return Entry{Key: key, Text: latin1(text)}, true
+
+	case "iTXt":
+		// Flag e método de compressão, idioma e palavra-chave traduzida
+		if len(rest) < 2 {
+			return Entry{}, false
+		}
+		compressed, method := rest[0] == 1, rest[1]
+		rest = rest[2:]
+		for i := 0; i < 2; i++ {
+			end := bytes.IndexByte(rest, 0)
+			if end < 0 {
+				return Entry{}, false
+			}
+			rest = rest[end+1:]
+		}
+		text := rest
+		if compressed {
+			var ok bool
+			if text, ok = inflate(rest); !ok || method != 0 {
+				return Entry{}, false
+			}
+		}
+		if !utf8.Valid(text) {
+			return Entry{}, false
+		}
+		return Entry{Key: key, Text: string(text)}, true
+	}
+	return Entry{}, false
+}
+
+// inflate descomprime dados zlib, recusando textos maiores que maxEntryText
+func inflate(data []byte) ([]byte, bool) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	defer zr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(zr, maxEntryText+1))
+	if err != nil || len(out) > maxEntryText {
+		return nil, false
+	}
+	return out, true
+}
+
+// latin1 converte texto ISO-8859-1 (tEXt e zTXt) em UTF-8
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// decodeJPEG lê os segmentos de comentário (COM) antes dos dados da imagem
+func decodeJPEG(r io.ReaderAt, size int64) ([]Entry, error) {
+	entries := []Entry{}
+	offset := int64(2)
+	marker := make([]byte, 4)
+
+	for offset+4 <= size {
+		if _, err := r.ReadAt(marker, offset); err != nil || marker[0] != 0xFF {
+			break
+		}
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			break
+		}
+		length := int64(binary.BigEndian.Uint16(marker[2:4]))
+		if length < 2 {
+			return entries, errInvalid
+		}
+
+		if marker[1] == 0xFE && length > 2 {
+			data := make([]byte, length-2)
+			if _, err := r.ReadAt(data, offset+4); err != nil {
+				return entries, errInvalid
+			}
+			text := strings.TrimRight(string(data), "\x00")
+			if !utf8.ValidString(text) {
+				text = latin1([]byte(text))
+			}
+			if strings.TrimSpace(text) != "" {
+				entries = append(entries, Entry{Key: "comment", Text: text})
+			}
+		}
+		offset += 2 + length
+	}
+	return entries, nil
+}
+
+// prefixedText reconhece "chave:texto" nos campos EXIF em que o ComfyUI
+// grava prompt e workflow (ex: Make = "workflow:{...}")
+var prefixedText = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_ ]{0,39}):\s*([\[{][\s\S]*)$`)
+
+// exifFields são os campos EXIF de texto livre usados por ferramentas de IA:
+// UserComment (Stable Diffusion) e ImageDescription, Make e Model (ComfyUI)
+var exifFields = []string{"UserComment", "ImageDescription", "Make", "Model"}
+
+// exifEntries extrai os textos de geração guardados em campos EXIF. Make e
+// Model só entram quando trazem "chave:JSON", para não listar a câmera.
+func exifEntries(r io.ReaderAt, size int64) []Entry {
+	entries := []Entry{}
+	e, err := exif.Decode(r, size)
+	if err != nil {
+		return entries
+	}
+	fields := e.Fields()
+	for _, name := range exifFields {
+		text := fields[name]
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if m := prefixedText.FindStringSubmatch(text); m != nil {
+			entries = append(entries, Entry{Key: m[1], Text: m[2]})
+			continue
+		}
+		if name == "UserComment" || name == "ImageDescription" {
+			entries = append(entries, Entry{Key: name, Text: text})
+		}
+	}
+	return entries
+}
@@ -0,0 +1,222 @@
+package textmeta
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pngWithChunks insere chunks antes do IEND de um PNG 1x1
+func pngWithChunks(chunks ...[]byte) []byte {
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := img.Bytes()
+	iend := len(data) - 12
+
+	out := append([]byte(nil), data[:iend]...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return append(out, data[iend:]...)
+}
+
+func chunk(typ string, data []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	b = append(b, typ...)
+	b = append(b, data...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+}
+
+func deflate(s string) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return buf.Bytes()
+}
+
+// jpegWith monta um JPEG 8x8 com os segmentos informados depois do SOI
+func jpegWith(segments ...[]byte) []byte {
+	var img bytes.Buffer
+	jpeg.Encode(&img, image.NewGray(image.Rect(0, 0, 8, 8)), nil)
+	out := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, img.Bytes()[2:]...)
+}
+
+func segment(marker byte, payload []byte) []byte {
+	b := []byte{0xFF, marker}
+	b = binary.BigEndian.AppendUint16(b, uint16(len(payload)+2))
+	return append(b, payload...)
+}
+
+// exifASCII monta um bloco TIFF com tags ASCII no IFD0 e UserComment no IFD Exif
+func exifASCII(tags map[uint16]string, userComment string) []byte {
+	le := binary.LittleEndian
+	ids := []uint16{0x010E, 0x010F, 0x0110}
+
+	var entries []uint16
+	for _, id := range ids {
+		if _, ok := tags[id]; ok {
+			entries = append(entries, id)
+		}
+	}
+	count := len(entries) + 1 // + ponteiro para o IFD Exif
+	exifIFD := 8 + 2 + 12*count + 4
+	dataOffset := exifIFD + 2 + 12 + 4
+
+	var data []byte
+	b := le.AppendUint32([]byte("II*\x00"), 8)
+	b = le.AppendUint16(b, uint16(count))
+	for _, id := range entries {
+		text := tags[id] + "\x00"
+		b = le.AppendUint16(b, id)
+		b = le.AppendUint16(b, 2)
+		b = le.AppendUint32(b, uint32(len(text)))
+		b = le.AppendUint32(b, uint32(dataOffset+len(data)))
+		data = append(data, text...)
+	}
+	b = le.AppendUint16(b, 0x8769)
+	b = le.AppendUint16(b, 4)
+	b = le.AppendUint32(b, 1)
+	b = le.AppendUint32(b, uint32(exifIFD))
+	b = le.AppendUint32(b, 0)
+
+	comment := "ASCII\x00\x00\x00" + userComment
+	b = le.AppendUint16(b, 1)
+	b = le.AppendUint16(b, 0x9286)
+	b = le.AppendUint16(b, 7)
+	b = le.AppendUint32(b, uint32(len(comment)))
+	b = le.AppendUint32(b, uint32(dataOffset+len(data)))
+	b = le.AppendUint32(b, 0)
+	data = append(data, comment...)
+	return append(b, data...)
+}
+
+func TestDecode_PNG(t *testing.T) {
+	workflow := `{"3": {"class_type": "KSampler", "inputs": {"seed": 42}}}`
+	data := pngWithChunks(
+		chunk("tEXt", []byte("parameters\x00a cat in space\nSteps: 20, Sampler: Euler a, Seed: 42")),
+		chunk("zTXt", append([]byte("workflow\x00\x00"), deflate(workflow)...)),
+		chunk("iTXt", []byte("Title\x00\x00\x00pt\x00Título\x00Gato no espaço")),
+		chunk("iTXt", append([]byte("prompt\x00\x01\x00\x00\x00"), deflate(`{"prompt": "café"}`)...)),
+		chunk("tEXt", []byte("Author\x00Jos\xe9")), // Latin-1
+		chunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<x:xmpmeta/>")),
+		chunk("tEXt", []byte("\x00sem chave")),
+	)
+
+	entries, err := Decode(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []Entry{
+		{"parameters", "a cat in space\nSteps: 20, Sampler: Euler a, Seed: 42"},
+		{"workflow", workflow},
+		{"Title", "Gato no espaço"},
+		{"prompt", `{"prompt": "café"}`},
+		{"Author", "José"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Decode() = %q, want %q", entries, want)
+	}
+
+	keys, err := Keys(bytes.NewReader(data), int64(len(data)))
+	if err != nil || !reflect.DeepEqual(keys, []string{"parameters", "workflow", "Title", "prompt", "Author"}) {
+		t.Errorf("Keys() = %v, %v", keys, err)
+	}
+}
+
+func TestDecode_PNGLimits(t *testing.T) {
+	// Bomba de compressão: o texto descomprimido passa do limite e é ignorado
+	bomb := deflate(strings.Repeat("a", maxEntryText+1))
+	data := pngWithChunks(
+		chunk("zTXt", append([]byte("bomb\x00\x00"), bomb...)),
+		chunk("zTXt", []byte("broken\x00\x00not zlib")),
+		chunk("tEXt", []byte("ok\x00fim")),
+	)
+	entries, err := Decode(bytes.NewReader(data), int64(len(data)))
+	if err != nil || !reflect.DeepEqual(entries, []Entry{{"ok", "fim"}}) {
+		t.Errorf("Decode() = %q, %v, want só a entrada válida", entries, err)
+	}
+
+	// Sem textos: lista vazia, não nil (vira [] no JSON)
+	plain := pngWithChunks()
+	if entries, err := Decode(bytes.NewReader(plain), int64(len(plain))); err != nil || entries == nil || len(entries) != 0 {
+		t.Errorf("Decode(sem textos) = %#v, %v", entries, err)
+	}
+}
+
+func TestDecode_JPEG(t *testing.T) {
+	params := "a lighthouse at dusk\nNegative prompt: blurry\nSteps: 30, Seed: 7"
+	data := jpegWith(
+		segment(0xE1, append([]byte("Exif\x00\x00"), exifASCII(map[uint16]string{0x010F: "Canon", 0x0110: "prompt:{\"1\": {}}"}, params)...)),
+		segment(0xFE, []byte("feito no sidelook")),
+	)
+
+	entries, err := Decode(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []Entry{
+		{"comment", "feito no sidelook"},
+		{"UserComment", params},
+		{"prompt", `{"1": {}}`},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Decode() = %q, want %q", entries, want)
+	}
+}
+
+func TestDecode_WebP(t *testing.T) {
+	// ComfyUI grava o workflow em Make e o prompt em Model
+	tiff := exifASCII(map[uint16]string{0x010F: `workflow:{"nodes": []}`, 0x0110: `prompt:{"3": {}}`}, "")
+	body := append([]byte("WEBPVP8 "), binary.LittleEndian.AppendUint32(nil, 10)...)
+	body = append(body, make([]byte, 10)...)
+	exifChunk := append([]byte("EXIF"), binary.LittleEndian.AppendUint32(nil, uint32(len(tiff)))...)
+	body = append(body, exifChunk...)
+	body = append(body, tiff...)
+	if len(tiff)%2 == 1 {
+		body = append(body, 0)
+	}
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	entries, err := Decode(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []Entry{{"workflow", `{"nodes": []}`}, {"prompt", `{"3": {}}`}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Decode() = %q, want %q", entries, want)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ComfyUI_00001_.png")
+	os.WriteFile(path, pngWithChunks(chunk("tEXt", []byte("prompt\x00{}"))), 0644)
+
+	entries, err := ReadFile(path)
+	if err != nil || !reflect.DeepEqual(entries, []Entry{{"prompt", "{}"}}) {
+		t.Errorf("ReadFile() = %q, %v", entries, err)
+	}
+
+	gif := filepath.Join(t.TempDir(), "anim.gif")
+	os.WriteFile(gif, []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), 0644)
+	if entries, err := ReadFile(gif); err != nil || len(entries) != 0 {
+		t.Errorf("ReadFile(gif) = %q, %v, want vazio", entries, err)
+	}
+	if _, err := ReadFile(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Error("ReadFile(inexistente) deveria falhar")
+	}
+}
@@ -0,0 +1,391 @@
+// Package thumb gera miniaturas sob demanda e as guarda em um cache em disco
+// endereçado pelo conteúdo das imagens
+package thumb
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Registrar decodificadores
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/verseles/sidelook/internal/transcode"
+)
+
+const (
+	// DefaultSize é o lado maior padrão das miniaturas, em pixels
+	DefaultSize = 256
+
+	// MinSize e MaxSize limitam os tamanhos aceitos
+	MinSize = 16
+	MaxSize = 1024
+
+	// DefaultMaxBytes é o limite padrão do cache em disco (256MB)
+	DefaultMaxBytes = 256 << 20
+
+	// defaultMaxHashes limita quantos hashes de conteúdo ficam em memória; os
+	// arquivos pedidos há mais tempo são esquecidos e lidos de novo se voltarem
+	defaultMaxHashes = 10000
+
+	// formatVersion entra no nome dos arquivos e muda quando as miniaturas
+	// passam a ser geradas de outro jeito (2: orientação EXIF aplicada)
+	formatVersion = 2
+)
+
+// ErrUnsupported indica que não há decodificador para o formato da imagem
+// (ex: WebP, SVG, AVIF); nesse caso o original deve ser servido
+var ErrUnsupported = errors.New("formato sem suporte a miniaturas")
+
+// Thumbnail é uma miniatura pronta para ser servida
+type Thumbnail struct {
+	Data        []byte
+	ContentType string
+}
+
+// Cache gera miniaturas com um número limitado de workers e as guarda em
+// disco. Os arquivos são nomeados pelo hash do conteúdo da imagem, então
+// cópias e renomeações reaproveitam a mesma miniatura e uma imagem
+// modificada (mtime ou tamanho diferente) gera outra. Os arquivos menos
+// usados são removidos quando o cache passa do limite.
+type Cache struct {
+	dir      string
+	maxBytes int64
+	workers  chan struct{}
+
+	mu        sync.Mutex
+	hashes    map[string]*list.Element // Hash do conteúdo da versão atual de cada arquivo
+	hashOrder *list.List               // Mais recente na frente
+	maxHashes int
+	inflight  map[callKey]*call
+	size      int64 // Bytes em disco (-1 = ainda não medido)
+
+	pruneMu   sync.Mutex
+	generated atomic.Int64 // Miniaturas geradas (para testes)
+}
+
+type fileHash struct {
+	path    string
+	modTime time.Time
+	size    int64
+	sum     string
+}
+
+// callKey identifica uma miniatura antes de o conteúdo ser lido
+type callKey struct {
+	path    string
+	modTime int64
+	size    int64
+	thumb   int
+}
+
+// call é uma geração em andamento, compartilhada por pedidos simultâneos
+type call struct {
+	done  chan struct{}
+	thumb *Thumbnail
+	err   error
+}
+
+// DefaultDir retorna o diretório padrão do cache ($XDG_CACHE_HOME/sidelook/thumbs
+// no Linux, com os equivalentes do sistema no macOS e Windows)
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "sidelook", "thumbs")
+}
+
+// New cria um cache em dir limitado a maxBytes. O diretório é criado na
+// primeira gravação; se não puder ser gravado, as miniaturas continuam
+// sendo geradas, apenas sem cache.
+func New(dir string, maxBytes int64) *Cache {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Cache{
+		dir:       dir,
+		maxBytes:  maxBytes,
+		workers:   make(chan struct{}, min(runtime.NumCPU(), 4)),
+		hashes:    make(map[string]*list.Element),
+		hashOrder: list.New(),
+		maxHashes: defaultMaxHashes,
+		inflight:  make(map[callKey]*call),
+		size:      -1,
+	}
+}
+
+// Get retorna a miniatura de path com o lado maior limitado a size pixels
+func (c *Cache) Get(path string, info os.FileInfo, size int) (*Thumbnail, error) {
+	size = min(max(size, MinSize), MaxSize)
+
+	// Com o hash já conhecido, a miniatura em disco sai sem esperar um worker
+	if sum, ok := c.knownHash(path, info); ok {
+		if t := c.load(c.base(sum, size)); t != nil {
+			return t, nil
+		}
+	}
+
+	// Pedidos simultâneos da mesma miniatura esperam uma única geração
+	key := callKey{path: path, modTime: info.ModTime().UnixNano(), size: info.Size(), thumb: size}
+	c.mu.Lock()
+	if cl, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-cl.done
+		return cl.thumb, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.inflight[key] = cl
+	c.mu.Unlock()
+
+	// O hash lê o arquivo inteiro, então também fica limitado pelos workers
+	c.workers <- struct{}{}
+	sum, err := c.contentHash(path, info)
+	if err == nil {
+		cl.thumb, cl.err = c.generate(path, c.base(sum, size), size)
+	} else {
+		cl.err = err
+	}
+	<-c.workers
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(cl.done)
+
+	return cl.thumb, cl.err
+}
+
+// base é o caminho no cache, sem extensão, da miniatura de um conteúdo
+func (c *Cache) base(sum string, size int) string {
+	return filepath.Join(c.dir, sum[:2], fmt.Sprintf("%s-%d-v%d", sum, size, formatVersion))
+}
+
+// knownHash retorna o hash já calculado para a versão atual de path
+func (c *Cache) knownHash(path string, info os.FileInfo) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.hashes[path]
+	if !ok {
+		return "", false
+	}
+	h := el.Value.(*fileHash)
+	if !h.modTime.Equal(info.ModTime()) || h.size != info.Size() {
+		return "", false
+	}
+	c.hashOrder.MoveToFront(el)
+	return h.sum, true
+}
+
+// contentHash retorna o SHA-256 do conteúdo de path, recalculando apenas
+// quando mtime ou tamanho mudam
+func (c *Cache) contentHash(path string, info os.FileInfo) (string, error) {
+	if sum, ok := c.knownHash(path, info); ok {
+		return sum, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.hashes[path]; ok {
+		c.hashOrder.Remove(el)
+	}
+	c.hashes[path] = c.hashOrder.PushFront(&fileHash{path: path, modTime: info.ModTime(), size: info.Size(), sum: sum})
+	for c.hashOrder.Len() > c.maxHashes {
+		old := c.hashOrder.Remove(c.hashOrder.Back()).(*fileHash)
+		delete(c.hashes, old.path)
+	}
+	return sum, nil
+}
+
+// load lê uma miniatura já gerada, marcando-a como usada recentemente
+func (c *Cache) load(base string) *Thumbnail {
+	for _, ext := range []string{".jpg", ".png"} {
+		data, err := os.ReadFile(base + ext)
+		if err != nil {
+			continue
+		}
+		now := time.Now()
+		os.Chtimes(base+ext, now, now)
+		return &Thumbnail{Data: data, ContentType: contentType(ext)}
+	}
+	return nil
+}
+
+// generate decodifica a imagem, reduz e grava a miniatura no cache
+func (c *Cache) generate(path, base string, size int) (*Thumbnail, error) {
+	// Outro pedido pode ter gerado enquanto esperávamos um worker
+	if t := c.load(base); t != nil {
+		return t, nil
+	}
+
+	img, err := decode(path)
+	if err != nil {
+		return nil, err
+	}
+	c.generated.Add(1)
+
+	// Miniaturas não levam o EXIF: a orientação é aplicada aqui
+	o := transcode.Orientation(path)
+	b := img.Bounds()
+	dispW, dispH := b.Dx(), b.Dy()
+	if transcode.Transposed(o) {
+		dispW, dispH = dispH, dispW
+	}
+	w, h := transcode.FitSize(dispW, dispH, size, size)
+	small := transcode.ResizeOriented(transcode.To8Bit(img), image.Rect(0, 0, dispW, dispH), w, h, o)
+
+	var buf bytes.Buffer
+	ext := ".jpg"
+	if small.Opaque() {
+		err = jpeg.Encode(&buf, small, &jpeg.Options{Quality: transcode.JPEGQuality})
+	} else {
+		ext = ".png"
+		err = png.Encode(&buf, small)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(base+ext, buf.Bytes())
+	return &Thumbnail{Data: buf.Bytes(), ContentType: contentType(ext)}, nil
+}
+
+// decode decodifica path recusando formatos sem decodificador e imagens
+// grandes demais para a memória
+func decode(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if errors.Is(err, image.ErrFormat) {
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := transcode.CheckSize(cfg); err != nil {
+		return nil, err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// store grava a miniatura atomicamente e poda o cache se passar do limite.
+// Falhas de gravação são ignoradas: a miniatura só não fica em cache.
+func (c *Cache) store(name string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	c.mu.Lock()
+	if c.size >= 0 {
+		c.size += int64(len(data))
+	}
+	over := c.size < 0 || c.size > c.maxBytes
+	c.mu.Unlock()
+
+	if over {
+		c.prune()
+	}
+}
+
+// prune mede o cache e remove as miniaturas usadas há mais tempo até ficar
+// abaixo de 90% do limite, para não podar a cada nova miniatura
+func (c *Cache) prune() {
+	c.pruneMu.Lock()
+	defer c.pruneMu.Unlock()
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []entry
+	var total int64
+	filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, entry{path, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+
+	if total > c.maxBytes {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].modTime.Before(entries[j].modTime)
+		})
+		target := c.maxBytes / 10 * 9
+		for _, e := range entries {
+			if total <= target {
+				break
+			}
+			if os.Remove(e.path) == nil {
+				total -= e.size
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.size = total
+	c.mu.Unlock()
+}
+
+func contentType(ext string) string {
+	if ext == ".png" {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,358 @@
+package thumb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeImage(t *testing.T, path string, img image.Image) os.FileInfo {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func solid(w, h int, c color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// cacheFiles lista as miniaturas gravadas em disco
+func cacheFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+func TestGet_JPEG(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "render.png")
+	info := writeImage(t, src, solid(1000, 500, color.NRGBA{200, 100, 50, 255}))
+
+	c := New(t.TempDir(), 0)
+	th, err := c.Get(src, info, 256)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if th.ContentType != "image/jpeg" {
+		t.Errorf("ContentType = %q, want image/jpeg (imagem opaca)", th.ContentType)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(th.Data))
+	if err != nil {
+		t.Fatalf("miniatura não é um JPEG válido: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 256 || b.Dy() != 128 {
+		t.Errorf("dimensões = %dx%d, want 256x128", b.Dx(), b.Dy())
+	}
+	r, g, b, _ := img.At(10, 10).RGBA()
+	if r>>8 < 190 || g>>8 < 90 || g>>8 > 110 || b>>8 > 60 {
+		t.Errorf("cor = %d,%d,%d, want ~200,100,50", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestGet_Orientation(t *testing.T) {
+	// Gravada deitada (300x100, terço direito vermelho) com orientação 8
+	// (girar 90° no sentido anti-horário): em pé, com o vermelho em cima
+	src := image.NewNRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.NRGBA{255, 255, 255, 255}
+			if x >= 200 {
+				c = color.NRGBA{255, 0, 0, 255}
+			}
+			src.SetNRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Segmento APP1 com um IFD0 contendo só a tag Orientation
+	le := binary.LittleEndian
+	tiff := le.AppendUint32([]byte("II*\x00"), 8)
+	tiff = le.AppendUint16(tiff, 1)
+	tiff = append(tiff, 0x12, 0x01, 3, 0, 1, 0, 0, 0, 8, 0, 0, 0)
+	tiff = le.AppendUint32(tiff, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	data := append([]byte{0xFF, 0xD8, 0xFF, 0xE1}, byte((len(payload)+2)>>8), byte(len(payload)+2))
+	data = append(append(data, payload...), buf.Bytes()[2:]...)
+
+	path := filepath.Join(t.TempDir(), "phone.jpg")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(path)
+
+	th, err := New(t.TempDir(), 0).Get(path, info, 60)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(th.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 60 {
+		t.Fatalf("dimensões = %dx%d, want 20x60", b.Dx(), b.Dy())
+	}
+	if _, g, _, _ := img.At(10, 5).RGBA(); g>>8 > 60 {
+		t.Errorf("topo = %v, want vermelho", img.At(10, 5))
+	}
+	if _, g, _, _ := img.At(10, 50).RGBA(); g>>8 < 200 {
+		t.Errorf("base = %v, want branco", img.At(10, 50))
+	}
+}
+
+func TestGet_TransparentPNG(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "sprite.png")
+	info := writeImage(t, src, solid(64, 64, color.NRGBA{255, 0, 0, 128}))
+
+	th, err := New(t.TempDir(), 0).Get(src, info, 32)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if th.ContentType != "image/png" {
+		t.Fatalf("ContentType = %q, want image/png (transparência)", th.ContentType)
+	}
+	img, err := png.Decode(bytes.NewReader(th.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA)
+	if got.A < 126 || got.A > 130 || got.R < 250 {
+		t.Errorf("pixel = %v, want ~{255 0 0 128}", got)
+	}
+}
+
+func TestGet_SmallImageNotEnlarged(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "icon.png")
+	info := writeImage(t, src, solid(40, 20, color.White))
+
+	th, err := New(t.TempDir(), 0).Get(src, info, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(th.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 40 || cfg.Height != 20 {
+		t.Errorf("dimensões = %dx%d, want 40x20", cfg.Width, cfg.Height)
+	}
+}
+
+func TestGet_CacheAndInvalidation(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+	src := filepath.Join(dir, "render.png")
+	info := writeImage(t, src, solid(300, 300, color.White))
+
+	c := New(cacheDir, 0)
+	if _, err := c.Get(src, info, 128); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(src, info, 128); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.generated.Load(); n != 1 {
+		t.Errorf("gerou %d miniaturas, want 1 (segunda vem do cache)", n)
+	}
+
+	// Um novo Cache no mesmo diretório reaproveita o disco
+	c2 := New(cacheDir, 0)
+	if _, err := c2.Get(src, info, 128); err != nil {
+		t.Fatal(err)
+	}
+	if n := c2.generated.Load(); n != 0 {
+		t.Errorf("gerou %d miniaturas após reiniciar, want 0", n)
+	}
+
+	// Cópia com o mesmo conteúdo usa a mesma miniatura
+	copyPath := filepath.Join(dir, "copy.png")
+	data, _ := os.ReadFile(src)
+	os.WriteFile(copyPath, data, 0644)
+	copyInfo, _ := os.Stat(copyPath)
+	if _, err := c.Get(copyPath, copyInfo, 128); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.generated.Load(); n != 1 {
+		t.Errorf("gerou %d miniaturas para cópia idêntica, want 1", n)
+	}
+
+	// Imagem modificada gera nova miniatura
+	info = writeImage(t, src, solid(300, 300, color.Black))
+	later := info.ModTime().Add(time.Second)
+	os.Chtimes(src, later, later)
+	info, _ = os.Stat(src)
+
+	th, err := c.Get(src, info, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := c.generated.Load(); n != 2 {
+		t.Errorf("gerou %d miniaturas após modificação, want 2", n)
+	}
+	img, _ := jpeg.Decode(bytes.NewReader(th.Data))
+	if r, _, _, _ := img.At(5, 5).RGBA(); r>>8 > 10 {
+		t.Errorf("miniatura após modificação ainda é clara (r=%d)", r>>8)
+	}
+}
+
+func TestGet_Concurrent(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "render.png")
+	info := writeImage(t, src, solid(800, 800, color.White))
+
+	c := New(t.TempDir(), 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(src, info, 256); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := c.generated.Load(); n != 1 {
+		t.Errorf("gerou %d miniaturas para pedidos simultâneos, want 1", n)
+	}
+}
+
+func TestGet_HashInWorkerPool(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "render.png")
+	info := writeImage(t, src, solid(64, 64, color.White))
+
+	// Com todos os workers ocupados, nem o hash do arquivo é calculado
+	c := New(t.TempDir(), 0)
+	for i := 0; i < cap(c.workers); i++ {
+		c.workers <- struct{}{}
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Get(src, info, 64)
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := c.knownHash(src, info); ok {
+		t.Error("hash calculado fora do pool de workers")
+	}
+
+	for i := 0; i < cap(c.workers); i++ {
+		<-c.workers
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get() não terminou após liberar os workers")
+	}
+	if _, ok := c.knownHash(src, info); !ok {
+		t.Error("hash não ficou em memória")
+	}
+}
+
+func TestGet_HashesBounded(t *testing.T) {
+	dir := t.TempDir()
+	c := New(t.TempDir(), 0)
+	c.maxHashes = 2
+
+	var paths []string
+	var infos []os.FileInfo
+	for i, name := range []string{"a.png", "b.png", "c.png"} {
+		path := filepath.Join(dir, name)
+		infos = append(infos, writeImage(t, path, solid(16+i, 16, color.White)))
+		paths = append(paths, path)
+	}
+
+	// a é pedido de novo depois de b, então b é o esquecido
+	for _, i := range []int{0, 1, 0, 2} {
+		if _, err := c.Get(paths[i], infos[i], 16); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(c.hashes) != 2 || c.hashOrder.Len() != 2 {
+		t.Fatalf("hashes em memória = %d, want 2", len(c.hashes))
+	}
+	for i, want := range []bool{true, false, true} {
+		if _, ok := c.knownHash(paths[i], infos[i]); ok != want {
+			t.Errorf("hash de %s em memória = %v, want %v", filepath.Base(paths[i]), ok, want)
+		}
+	}
+}
+
+func TestGet_Unsupported(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "photo.webp")
+	os.WriteFile(src, []byte("RIFF\x24\x00\x00\x00WEBPVP8 \x00\x00\x00\x00"), 0644)
+	info, _ := os.Stat(src)
+
+	_, err := New(t.TempDir(), 0).Get(src, info, 256)
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Get(webp) error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	// Cada miniatura PNG de ruído tem alguns KB; o limite comporta poucas
+	c := New(cacheDir, 8<<10)
+	for i := 0; i < 10; i++ {
+		img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+		for j := range img.Pix {
+			img.Pix[j] = byte(j*31 + i*97 + j*j)
+		}
+		src := filepath.Join(dir, "noise"+string(rune('a'+i))+".png")
+		info := writeImage(t, src, img)
+		if _, err := c.Get(src, info, 64); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var total int64
+	for _, f := range cacheFiles(t, cacheDir) {
+		info, _ := os.Stat(f)
+		total += info.Size()
+	}
+	if total > 8<<10 {
+		t.Errorf("cache ocupa %d bytes, want <= %d", total, 8<<10)
+	}
+	if len(cacheFiles(t, cacheDir)) == 0 {
+		t.Error("poda removeu todas as miniaturas")
+	}
+}
@@ -0,0 +1,85 @@
+package tiff
+
+import "errors"
+
+// Códigos especiais do LZW do TIFF
+const (
+	lzwClear   = 256
+	lzwEOI     = 257
+	lzwFirst   = 258
+	lzwMaxCode = 4096
+)
+
+var errLZW = errors.New("tiff: dados LZW inválidos")
+
+// decodeLZW descomprime dados LZW no dialeto do TIFF: bits MSB primeiro e
+// troca antecipada do tamanho do código (um código antes do LZW do GIF, por
+// isso compress/lzw não serve). Dados truncados retornam o que foi decodificado;
+// a saída para em size bytes.
+func decodeLZW(src []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, min(size, maxPrealloc))
+
+	var (
+		table [lzwMaxCode][]byte
+		next  = lzwFirst
+		width = uint(9)
+		prev  []byte
+
+		bits  uint32
+		nbits uint
+		pos   int
+	)
+	for i := 0; i < 256; i++ {
+		table[i] = []byte{byte(i)}
+	}
+
+	for {
+		// Ler o próximo código
+		for nbits < width {
+			if pos >= len(src) {
+				return out, nil
+			}
+			bits = bits<<8 | uint32(src[pos])
+			pos++
+			nbits += 8
+		}
+		code := int(bits>>(nbits-width)) & (1<<width - 1)
+		nbits -= width
+
+		switch {
+		case code == lzwEOI:
+			return out, nil
+
+		case code == lzwClear:
+			next = lzwFirst
+			width = 9
+			prev = nil
+			continue
+		}
+
+		var entry []byte
+		switch {
+		case code < next && table[code] != nil:
+			entry = table[code]
+		case code == next && prev != nil:
+			entry = append(prev[:len(prev):len(prev)], prev[0])
+		default:
+			return out, errLZW
+		}
+		if len(entry) > size-len(out) {
+			return append(out, entry[:size-len(out)]...), nil
+		}
+		out = append(out, entry...)
+
+		if prev != nil && next < lzwMaxCode {
+			table[next] = append(prev[:len(prev):len(prev)], entry[0])
+			next++
+		}
+		prev = entry
+
+		// Troca antecipada: o TIFF aumenta o código ao chegar em 2^n - 1
+		if next >= 1<<width-1 && width < 12 {
+			width++
+		}
+	}
+}
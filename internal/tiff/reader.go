@@ -0,0 +1,584 @@
+// Package tiff decodifica imagens TIFF baseline: tons de cinza, RGB e paleta
+// de 1 a 16 bits por amostra, em faixas (strips) ou blocos (tiles), sem
+// compressão ou com LZW, Deflate e PackBits. Apenas a primeira página é lida.
+//
+// Importar o pacote registra o formato em image.Decode.
+package tiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Tags usadas pelo decodificador
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPlanarConfig    = 284
+	tagPredictor       = 317
+	tagColorMap        = 320
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagExtraSamples    = 338
+	tagSampleFormat    = 339
+)
+
+// Valores de Compression
+const (
+	compressionNone     = 1
+	compressionLZW      = 5
+	compressionDeflate  = 8
+	compressionPackBits = 32773
+	compressionDeflate2 = 32946
+)
+
+// Valores de PhotometricInterpretation
+const (
+	photoWhiteIsZero = 0
+	photoBlackIsZero = 1
+	photoRGB         = 2
+	photoPalette     = 3
+)
+
+// Limites de segurança contra arquivos malformados
+const (
+	maxEntries = 4096
+	maxPixels  = 1 << 26 // Vale para a imagem e para cada faixa ou bloco
+	maxSamples = 16      // Amostras por pixel
+	maxBlocks  = 1 << 20 // Faixas ou blocos por imagem
+
+	// maxPrealloc limita a memória reservada antes de descomprimir um bloco;
+	// além disso, o buffer cresce conforme os dados são de fato escritos
+	maxPrealloc = 1 << 20
+)
+
+// FormatError indica um arquivo TIFF inválido
+type FormatError string
+
+func (e FormatError) Error() string { return "tiff: arquivo inválido: " + string(e) }
+
+// UnsupportedError indica um recurso TIFF válido mas não suportado
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string { return "tiff: não suportado: " + string(e) }
+
+func init() {
+	image.RegisterFormat("tiff", "II*\x00", Decode, DecodeConfig)
+	image.RegisterFormat("tiff", "MM\x00*", Decode, DecodeConfig)
+}
+
+// decoder guarda o arquivo e os campos do primeiro IFD
+type decoder struct {
+	data  []byte
+	order binary.ByteOrder
+	tags  map[uint16][]uint32
+
+	width, height int
+	bps           int // Bits por amostra (igual para todas as amostras)
+	spp           int // Amostras por pixel
+	photometric   int
+	alpha         int // 0 = sem alfa, 1 = associado (pré-multiplicado), 2 = não associado
+	palette       color.Palette
+}
+
+// DecodeConfig retorna as dimensões e o modelo de cor sem decodificar os pixels
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: d.colorModel(), Width: d.width, Height: d.height}, nil
+}
+
+// Decode decodifica a primeira página do TIFF
+func Decode(r io.Reader) (image.Image, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.decode()
+}
+
+func newDecoder(r io.Reader) (*decoder, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8 {
+		return nil, FormatError("cabeçalho curto")
+	}
+
+	d := &decoder{data: data, tags: make(map[uint16][]uint32)}
+	switch string(data[0:4]) {
+	case "II*\x00":
+		d.order = binary.LittleEndian
+	case "MM\x00*":
+		d.order = binary.BigEndian
+	case "II+\x00", "MM\x00+":
+		return nil, UnsupportedError("BigTIFF")
+	default:
+		return nil, FormatError("assinatura")
+	}
+
+	if err := d.readIFD(int(d.order.Uint32(data[4:8]))); err != nil {
+		return nil, err
+	}
+	if err := d.parseFields(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// typeSizes são os tamanhos em bytes dos tipos de dados TIFF numéricos
+var typeSizes = map[uint16]int{1: 1, 3: 2, 4: 4, 6: 1, 8: 2, 9: 4, 16: 8}
+
+// readIFD lê as entradas numéricas do IFD em offset
+func (d *decoder) readIFD(offset int) error {
+	if offset < 8 || offset+2 > len(d.data) {
+		return FormatError("offset do IFD")
+	}
+	n := int(d.order.Uint16(d.data[offset:]))
+	if n > maxEntries || offset+2+n*12 > len(d.data) {
+		return FormatError("número de entradas do IFD")
+	}
+
+	for i := 0; i < n; i++ {
+		entry := d.data[offset+2+i*12:]
+		id := d.order.Uint16(entry[0:2])
+		typ := d.order.Uint16(entry[2:4])
+		count := int(d.order.Uint32(entry[4:8]))
+
+		size, ok := typeSizes[typ]
+		if !ok {
+			continue // Textos, racionais etc. não são usados
+		}
+		if count <= 0 || count > len(d.data)/size {
+			return FormatError(fmt.Sprintf("tamanho da tag %d", id))
+		}
+
+		raw := entry[8:12]
+		if total := size * count; total > 4 {
+			at := int(d.order.Uint32(raw))
+			if at < 0 || at+total > len(d.data) {
+				return FormatError(fmt.Sprintf("offset da tag %d", id))
+			}
+			raw = d.data[at : at+total]
+		}
+
+		values := make([]uint32, count)
+		for j := range values {
+			switch size {
+			case 1:
+				values[j] = uint32(raw[j])
+			case 2:
+				values[j] = uint32(d.order.Uint16(raw[j*2:]))
+			case 4:
+				values[j] = d.order.Uint32(raw[j*4:])
+			case 8:
+				values[j] = uint32(d.order.Uint64(raw[j*8:]))
+			}
+		}
+		d.tags[id] = values
+	}
+	return nil
+}
+
+// first retorna o primeiro valor da tag, ou def se ausente
+func (d *decoder) first(id uint16, def int) int {
+	if v := d.tags[id]; len(v) > 0 {
+		return int(v[0])
+	}
+	return def
+}
+
+// parseFields valida os campos e escolhe o modelo de cor
+func (d *decoder) parseFields() error {
+	d.width = d.first(tagImageWidth, 0)
+	d.height = d.first(tagImageLength, 0)
+	if d.width <= 0 || d.height <= 0 {
+		return FormatError("dimensões")
+	}
+	if int64(d.width)*int64(d.height) > maxPixels {
+		return UnsupportedError("imagem grande demais")
+	}
+
+	d.spp = d.first(tagSamplesPerPixel, 1)
+	if d.spp < 1 || d.spp > maxSamples {
+		return UnsupportedError(fmt.Sprintf("%d amostras por pixel", d.spp))
+	}
+	d.bps = d.first(tagBitsPerSample, 1)
+	for _, b := range d.tags[tagBitsPerSample] {
+		if int(b) != d.bps {
+			return UnsupportedError("amostras com tamanhos diferentes")
+		}
+	}
+	switch d.bps {
+	case 1, 2, 4, 8, 16:
+	default:
+		return UnsupportedError(fmt.Sprintf("%d bits por amostra", d.bps))
+	}
+	if d.first(tagSampleFormat, 1) != 1 {
+		return UnsupportedError("amostras com sinal ou ponto flutuante")
+	}
+
+	d.photometric = d.first(tagPhotometric, photoBlackIsZero)
+	colorSamples := 1
+	switch d.photometric {
+	case photoWhiteIsZero, photoBlackIsZero:
+	case photoRGB:
+		colorSamples = 3
+	case photoPalette:
+		if err := d.parsePalette(); err != nil {
+			return err
+		}
+	default:
+		return UnsupportedError(fmt.Sprintf("interpretação fotométrica %d", d.photometric))
+	}
+	if d.spp < colorSamples {
+		return FormatError("amostras por pixel")
+	}
+	if d.spp > colorSamples && d.photometric != photoPalette {
+		// A primeira amostra extra é alfa se ExtraSamples disser que sim
+		if extra := d.tags[tagExtraSamples]; len(extra) > 0 && (extra[0] == 1 || extra[0] == 2) {
+			d.alpha = int(extra[0])
+		}
+	}
+	if (d.bps < 8 && d.photometric == photoRGB) || (d.bps < 8 && d.spp > 1) {
+		return UnsupportedError("menos de 8 bits por amostra em cores ou com alfa")
+	}
+
+	switch d.first(tagCompression, compressionNone) {
+	case compressionNone, compressionLZW, compressionDeflate, compressionDeflate2, compressionPackBits:
+	default:
+		return UnsupportedError(fmt.Sprintf("compressão %d", d.first(tagCompression, 0)))
+	}
+	switch d.first(tagPredictor, 1) {
+	case 1, 2:
+	default:
+		return UnsupportedError("preditor de ponto flutuante")
+	}
+	return nil
+}
+
+// parsePalette converte o ColorMap (3 tabelas de 16 bits) em uma paleta
+func (d *decoder) parsePalette() error {
+	if d.bps > 8 {
+		return UnsupportedError("paleta com mais de 8 bits")
+	}
+	cmap := d.tags[tagColorMap]
+	n := 1 << d.bps
+	if len(cmap) != 3*n {
+		return FormatError("tamanho do ColorMap")
+	}
+	d.palette = make(color.Palette, n)
+	for i := range d.palette {
+		d.palette[i] = color.RGBA{
+			R: uint8(cmap[i] >> 8),
+			G: uint8(cmap[n+i] >> 8),
+			B: uint8(cmap[2*n+i] >> 8),
+			A: 0xFF,
+		}
+	}
+	return nil
+}
+
+// colorModel é o modelo de cor da imagem retornada por decode
+func (d *decoder) colorModel() color.Model {
+	switch {
+	case d.photometric == photoPalette:
+		return d.palette
+	case d.spp == 1 && d.bps == 16:
+		return color.Gray16Model
+	case d.spp == 1:
+		return color.GrayModel
+	case d.bps == 16 && d.alpha == 1:
+		return color.RGBA64Model
+	case d.bps == 16 && d.alpha == 0 && d.photometric == photoRGB:
+		return color.RGBA64Model
+	case d.bps == 16:
+		return color.NRGBA64Model
+	case d.alpha == 1, d.alpha == 0 && d.photometric == photoRGB:
+		return color.RGBAModel
+	}
+	return color.NRGBAModel
+}
+
+// block descreve uma faixa ou bloco: posição na imagem e dados comprimidos
+type block struct {
+	x, y, w, h int
+	plane      int
+	data       []byte
+}
+
+// blocks lista as faixas ou blocos da imagem
+func (d *decoder) blocks() ([]block, error) {
+	tileW, tileH := d.width, d.first(tagRowsPerStrip, d.height)
+	offsets, counts := d.tags[tagStripOffsets], d.tags[tagStripByteCounts]
+	if _, tiled := d.tags[tagTileWidth]; tiled {
+		tileW, tileH = d.first(tagTileWidth, 0), d.first(tagTileLength, 0)
+		offsets, counts = d.tags[tagTileOffsets], d.tags[tagTileByteCounts]
+	}
+	if tileW <= 0 || tileH <= 0 || int64(tileW)*int64(min(tileH, d.height)) > maxPixels {
+		return nil, FormatError("tamanho das faixas")
+	}
+	// Linhas além da imagem seriam descartadas: nem são descomprimidas
+	if tileH > d.height {
+		tileH = d.height
+	}
+
+	across := (d.width + tileW - 1) / tileW
+	down := (d.height + tileH - 1) / tileH
+	planes := 1
+	if d.first(tagPlanarConfig, 1) == 2 {
+		planes = d.spp
+	}
+	total := across * down * planes
+	if total > maxBlocks {
+		return nil, UnsupportedError("faixas ou blocos demais")
+	}
+	if len(offsets) < total || len(counts) < total {
+		return nil, FormatError("número de faixas")
+	}
+
+	blocks := make([]block, total)
+	for i := range blocks {
+		start, size := int(offsets[i]), int(counts[i])
+		if start < 0 || size < 0 || start+size > len(d.data) || start+size < start {
+			return nil, FormatError("offset de faixa")
+		}
+		n := i % (across * down)
+		blocks[i] = block{
+			x:     (n % across) * tileW,
+			y:     (n / across) * tileH,
+			w:     tileW,
+			h:     tileH,
+			plane: i / (across * down),
+			data:  d.data[start : start+size],
+		}
+	}
+	return blocks, nil
+}
+
+// decompress descomprime os dados de um bloco
+func (d *decoder) decompress(data []byte, size int) ([]byte, error) {
+	switch d.first(tagCompression, compressionNone) {
+	case compressionLZW:
+		return decodeLZW(data, size)
+	case compressionDeflate, compressionDeflate2:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		out, err := io.ReadAll(io.LimitReader(zr, int64(size)))
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+		return out, nil
+	case compressionPackBits:
+		return decodePackBits(data, size), nil
+	}
+	return data, nil
+}
+
+// decodePackBits descomprime o RLE PackBits (Macintosh)
+func decodePackBits(src []byte, size int) []byte {
+	out := make([]byte, 0, min(size, maxPrealloc))
+	for i := 0; i < len(src) && len(out) < size; {
+		n := int(int8(src[i]))
+		i++
+		switch {
+		case n >= 0:
+			end := min(i+n+1, len(src))
+			out = append(out, src[i:end]...)
+			i = end
+		case n != -128 && i < len(src):
+			for j := 0; j < 1-n && len(out) < size; j++ {
+				out = append(out, src[i])
+			}
+			i++
+		}
+	}
+	return out
+}
+
+// decode lê os pixels de todas as faixas ou blocos
+func (d *decoder) decode() (image.Image, error) {
+	blocks, err := d.blocks()
+	if err != nil {
+		return nil, err
+	}
+
+	dst, set := d.newImage()
+
+	samples := d.spp // Amostras por pixel em cada plano
+	if d.first(tagPlanarConfig, 1) == 2 {
+		samples = 1
+	}
+	predictor := d.first(tagPredictor, 1) == 2
+
+	for _, b := range blocks {
+		rowBytes := (b.w*samples*d.bps + 7) / 8
+		buf, err := d.decompress(b.data, rowBytes*b.h)
+		if err != nil {
+			return nil, err
+		}
+
+		for row := 0; row < b.h && (row+1)*rowBytes <= len(buf); row++ {
+			y := b.y + row
+			if y >= d.height {
+				break
+			}
+			line := buf[row*rowBytes : (row+1)*rowBytes]
+			if predictor {
+				d.undoPredictor(line, samples)
+			}
+
+			for col := 0; col < b.w; col++ {
+				x := b.x + col
+				if x >= d.width {
+					break
+				}
+				for s := 0; s < samples; s++ {
+					set(x, y, b.plane+s, d.sample(line, col*samples+s))
+				}
+			}
+		}
+	}
+	return dst, nil
+}
+
+// sample lê a i-ésima amostra de uma linha
+func (d *decoder) sample(line []byte, i int) uint32 {
+	switch d.bps {
+	case 8:
+		return uint32(line[i])
+	case 16:
+		return uint32(d.order.Uint16(line[i*2:]))
+	}
+	bit := i * d.bps
+	shift := 8 - d.bps - bit%8
+	return uint32(line[bit/8]>>shift) & (1<<d.bps - 1)
+}
+
+// undoPredictor desfaz a diferenciação horizontal (Predictor = 2)
+func (d *decoder) undoPredictor(line []byte, samples int) {
+	switch d.bps {
+	case 8:
+		for i := samples; i < len(line); i++ {
+			line[i] += line[i-samples]
+		}
+	case 16:
+		for i := samples * 2; i+1 < len(line); i += 2 {
+			v := d.order.Uint16(line[i:]) + d.order.Uint16(line[i-samples*2:])
+			d.order.PutUint16(line[i:], v)
+		}
+	}
+}
+
+// newImage cria a imagem de destino e a função que grava a amostra s do pixel (x, y)
+func (d *decoder) newImage() (image.Image, func(x, y, s int, v uint32)) {
+	rect := image.Rect(0, 0, d.width, d.height)
+	maxVal := uint32(1)<<d.bps - 1
+	invert := d.photometric == photoWhiteIsZero
+
+	switch m := d.colorModel(); {
+	case d.photometric == photoPalette:
+		img := image.NewPaletted(rect, d.palette)
+		return img, func(x, y, s int, v uint32) {
+			img.Pix[y*img.Stride+x] = uint8(v)
+		}
+
+	case m == color.GrayModel:
+		img := image.NewGray(rect)
+		return img, func(x, y, s int, v uint32) {
+			if invert {
+				v = maxVal - v
+			}
+			img.Pix[y*img.Stride+x] = uint8(v * 0xFF / maxVal)
+		}
+
+	case m == color.Gray16Model:
+		img := image.NewGray16(rect)
+		return img, func(x, y, s int, v uint32) {
+			if invert {
+				v = maxVal - v
+			}
+			i := y*img.Stride + x*2
+			img.Pix[i], img.Pix[i+1] = uint8(v>>8), uint8(v)
+		}
+	}
+
+	// Cinza com alfa ou RGB(A), 8 ou 16 bits: amostras 0..2 são cor e 3 é alfa
+	// (com cinza, a amostra 0 vale para R, G e B e a 1 é alfa)
+	gray := d.photometric != photoRGB
+	alphaSample := 3
+	if gray {
+		alphaSample = 1
+	}
+	var img image.Image
+	var pix []byte
+	var stride, bytesPer int
+	switch d.colorModel() {
+	case color.RGBA64Model:
+		i := image.NewRGBA64(rect)
+		img, pix, stride, bytesPer = i, i.Pix, i.Stride, 2
+	case color.NRGBA64Model:
+		i := image.NewNRGBA64(rect)
+		img, pix, stride, bytesPer = i, i.Pix, i.Stride, 2
+	case color.RGBAModel:
+		i := image.NewRGBA(rect)
+		img, pix, stride, bytesPer = i, i.Pix, i.Stride, 1
+	default:
+		i := image.NewNRGBA(rect)
+		img, pix, stride, bytesPer = i, i.Pix, i.Stride, 1
+	}
+
+	// Sem alfa, a imagem é opaca
+	if d.alpha == 0 {
+		for i := 3 * bytesPer; i < len(pix); i += 4 * bytesPer {
+			for b := 0; b < bytesPer; b++ {
+				pix[i+b] = 0xFF
+			}
+		}
+	}
+
+	put := func(i int, v uint32) {
+		if bytesPer == 2 {
+			pix[i], pix[i+1] = uint8(v>>8), uint8(v)
+		} else {
+			pix[i] = uint8(v)
+		}
+	}
+	return img, func(x, y, s int, v uint32) {
+		base := y*stride + x*4*bytesPer
+		switch {
+		case s == alphaSample && d.alpha != 0:
+			put(base+3*bytesPer, v)
+		case gray && s == 0:
+			if invert {
+				v = maxVal - v
+			}
+			put(base, v)
+			put(base+bytesPer, v)
+			put(base+2*bytesPer, v)
+		case !gray && s < 3:
+			put(base+s*bytesPer, v)
+		}
+	}
+}
@@ -0,0 +1,535 @@
+package tiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"sort"
+	"testing"
+)
+
+// field é uma tag a gravar no IFD de teste (SHORT ou LONG)
+type field struct {
+	typ    uint16
+	values []uint32
+}
+
+func short(v ...uint32) field { return field{3, v} }
+func long(v ...uint32) field  { return field{4, v} }
+
+// buildTIFF monta um TIFF com os blocos (faixas ou tiles) e as tags
+// informadas. Os offsets e tamanhos dos blocos são preenchidos nas tags
+// offsetTag e countTag.
+func buildTIFF(order binary.ByteOrder, fields map[uint16]field, blocks [][]byte, offsetTag, countTag uint16) []byte {
+	app := order.(binary.AppendByteOrder)
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II*\x00")
+	} else {
+		buf.WriteString("MM\x00*")
+	}
+	buf.Write(make([]byte, 4)) // Offset do IFD, preenchido abaixo
+
+	var offsets, counts []uint32
+	for _, b := range blocks {
+		offsets = append(offsets, uint32(buf.Len()))
+		counts = append(counts, uint32(len(b)))
+		buf.Write(b)
+	}
+	fields[offsetTag] = long(offsets...)
+	fields[countTag] = long(counts...)
+
+	if buf.Len()%2 == 1 {
+		buf.WriteByte(0)
+	}
+	ifd := buf.Len()
+
+	ids := make([]int, 0, len(fields))
+	for id := range fields {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	// Valores que não cabem na entrada vão depois do IFD
+	extra := ifd + 2 + len(ids)*12 + 4
+	var entries, overflow bytes.Buffer
+	for _, id := range ids {
+		f := fields[uint16(id)]
+		size := 2
+		if f.typ == 4 {
+			size = 4
+		}
+		var raw []byte
+		for _, v := range f.values {
+			if size == 2 {
+				raw = app.AppendUint16(raw, uint16(v))
+			} else {
+				raw = app.AppendUint32(raw, v)
+			}
+		}
+
+		entry := app.AppendUint16(nil, uint16(id))
+		entry = app.AppendUint16(entry, f.typ)
+		entry = app.AppendUint32(entry, uint32(len(f.values)))
+		if len(raw) <= 4 {
+			entry = append(entry, append(raw, make([]byte, 4-len(raw))...)...)
+		} else {
+			entry = app.AppendUint32(entry, uint32(extra+overflow.Len()))
+			overflow.Write(raw)
+		}
+		entries.Write(entry)
+	}
+
+	buf.Write(app.AppendUint16(nil, uint16(len(ids))))
+	buf.Write(entries.Bytes())
+	buf.Write(make([]byte, 4)) // Próximo IFD
+	buf.Write(overflow.Bytes())
+
+	data := buf.Bytes()
+	order.PutUint32(data[4:8], uint32(ifd))
+	return data
+}
+
+// encodeLZW gera LZW do TIFF emitindo apenas códigos literais, acompanhando o
+// crescimento da tabela do decodificador (incluindo a troca antecipada)
+func encodeLZW(src []byte) []byte {
+	var out []byte
+	var bits uint64
+	var nbits uint
+	width := uint(9)
+	next := lzwFirst
+
+	emit := func(code int) {
+		bits = bits<<width | uint64(code)
+		nbits += width
+		for nbits >= 8 {
+			out = append(out, byte(bits>>(nbits-8)))
+			nbits -= 8
+		}
+	}
+
+	emit(lzwClear)
+	first := true
+	for _, b := range src {
+		emit(int(b))
+		if !first {
+			next++
+		}
+		first = false
+		if next >= 1<<width-1 && width < 12 {
+			width++
+		}
+		if next >= lzwMaxCode-2 {
+			emit(lzwClear)
+			next, width, first = lzwFirst, 9, true
+		}
+	}
+	emit(lzwEOI)
+	if nbits > 0 {
+		out = append(out, byte(bits<<(8-nbits)))
+	}
+	return out
+}
+
+func deflate(src []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(src)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeLZW(t *testing.T) {
+	src := make([]byte, 20000)
+	for i := range src {
+		src[i] = byte(i * 7 % 251)
+	}
+	got, err := decodeLZW(encodeLZW(src), len(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, src) {
+		t.Fatalf("decodeLZW() diverge da entrada (%d de %d bytes)", len(got), len(src))
+	}
+
+	// Sequência com repetição usa o caso código == próximo (KwKwK)
+	// 0x41 = 'A': códigos A, 258 ("AA") geram "AAA"
+	var out []byte
+	var bits uint32
+	var nbits uint
+	for _, c := range []uint32{lzwClear, 'A', 258, lzwEOI} {
+		bits = bits<<9 | c
+		nbits += 9
+		for nbits >= 8 {
+			out = append(out, byte(bits>>(nbits-8)))
+			nbits -= 8
+		}
+	}
+	out = append(out, byte(bits<<(8-nbits)))
+	if got, _ := decodeLZW(out, 3); string(got) != "AAA" {
+		t.Errorf("decodeLZW(KwKwK) = %q, want AAA", got)
+	}
+
+	// A saída para no tamanho esperado do bloco
+	if got, _ := decodeLZW(out, 2); string(got) != "AA" {
+		t.Errorf("decodeLZW(limite 2) = %q, want AA", got)
+	}
+	if got, _ := decodeLZW(encodeLZW(src), 100); !bytes.Equal(got, src[:100]) {
+		t.Errorf("decodeLZW(limite 100) = %d bytes, want 100", len(got))
+	}
+}
+
+func TestDecodePackBits(t *testing.T) {
+	// Exemplo da especificação TIFF 6.0
+	src := []byte{0xFE, 0xAA, 0x02, 0x80, 0x00, 0x2A, 0xFD, 0xAA, 0x03, 0x80, 0x00, 0x2A, 0x22, 0xF7, 0xAA}
+	want := []byte{0xAA, 0xAA, 0xAA, 0x80, 0x00, 0x2A, 0xAA, 0xAA, 0xAA, 0xAA, 0x80, 0x00, 0x2A, 0x22,
+		0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA}
+	if got := decodePackBits(src, len(want)); !bytes.Equal(got, want) {
+		t.Errorf("decodePackBits() = %x, want %x", got, want)
+	}
+}
+
+func TestDecode_RGB8Strips(t *testing.T) {
+	// 2x2 RGB, uma linha por faixa, big-endian
+	rows := [][]byte{
+		{255, 0, 0, 0, 255, 0},
+		{0, 0, 255, 10, 20, 30},
+	}
+	data := buildTIFF(binary.BigEndian, map[uint16]field{
+		tagImageWidth:      long(2),
+		tagImageLength:     long(2),
+		tagBitsPerSample:   short(8, 8, 8),
+		tagPhotometric:     short(photoRGB),
+		tagSamplesPerPixel: short(3),
+		tagRowsPerStrip:    long(1),
+	}, rows, tagStripOffsets, tagStripByteCounts)
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "tiff" {
+		t.Errorf("formato = %q, want tiff", format)
+	}
+	want := map[image.Point]color.RGBA{
+		{0, 0}: {255, 0, 0, 255},
+		{1, 0}: {0, 255, 0, 255},
+		{0, 1}: {0, 0, 255, 255},
+		{1, 1}: {10, 20, 30, 255},
+	}
+	for p, c := range want {
+		if got := color.RGBAModel.Convert(img.At(p.X, p.Y)); got != c {
+			t.Errorf("pixel %v = %v, want %v", p, got, c)
+		}
+	}
+}
+
+func TestDecode_Gray16LZWPredictor(t *testing.T) {
+	const w, h = 40, 30
+	raw := make([]byte, 0, w*h*2)
+	want := make([]uint16, 0, w*h)
+	for y := 0; y < h; y++ {
+		prev := uint16(0)
+		for x := 0; x < w; x++ {
+			v := uint16(x*1000 + y*37)
+			want = append(want, v)
+			raw = binary.LittleEndian.AppendUint16(raw, v-prev)
+			prev = v
+		}
+	}
+
+	data := buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:    long(w),
+		tagImageLength:   long(h),
+		tagBitsPerSample: short(16),
+		tagCompression:   short(compressionLZW),
+		tagPhotometric:   short(photoBlackIsZero),
+		tagPredictor:     short(2),
+	}, [][]byte{encodeLZW(raw)}, tagStripOffsets, tagStripByteCounts)
+
+	img, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray, ok := img.(*image.Gray16)
+	if !ok {
+		t.Fatalf("Decode() = %T, want *image.Gray16", img)
+	}
+	for i, v := range want {
+		if got := gray.Gray16At(i%w, i/w).Y; got != v {
+			t.Fatalf("pixel %d = %d, want %d", i, got, v)
+		}
+	}
+
+	cfg, err := DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width != w || cfg.Height != h || cfg.ColorModel != color.Gray16Model {
+		t.Errorf("DecodeConfig() = %+v, %v", cfg, err)
+	}
+}
+
+func TestDecode_PalettePackBits(t *testing.T) {
+	// 4x1, 4 bits por pixel: índices 0, 1, 2, 3
+	cmap := make([]uint32, 3*16)
+	cmap[1] = 0xFFFF                   // R do índice 1
+	cmap[16+2] = 0xFFFF                // G do índice 2
+	cmap[32+3] = 0xFFFF                // B do índice 3
+	packed := []byte{0x01, 0x01, 0x23} // Literal de 2 bytes
+
+	data := buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:    long(4),
+		tagImageLength:   long(1),
+		tagBitsPerSample: short(4),
+		tagCompression:   short(compressionPackBits),
+		tagPhotometric:   short(photoPalette),
+		tagColorMap:      short(cmap...),
+	}, [][]byte{packed}, tagStripOffsets, tagStripByteCounts)
+
+	img, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []color.RGBA{{0, 0, 0, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	for x, c := range want {
+		if got := color.RGBAModel.Convert(img.At(x, 0)); got != c {
+			t.Errorf("pixel %d = %v, want %v", x, got, c)
+		}
+	}
+}
+
+func TestDecode_TiledDeflateAlpha(t *testing.T) {
+	// 20x20 RGBA não associado em tiles de 16x16 (bordas parciais)
+	const w, h, tile = 20, 20, 16
+	var tiles [][]byte
+	for ty := 0; ty < h; ty += tile {
+		for tx := 0; tx < w; tx += tile {
+			raw := make([]byte, tile*tile*4)
+			for y := 0; y < tile; y++ {
+				for x := 0; x < tile; x++ {
+					i := (y*tile + x) * 4
+					raw[i], raw[i+1], raw[i+2], raw[i+3] = byte(tx+x), byte(ty+y), 7, 128
+				}
+			}
+			tiles = append(tiles, deflate(raw))
+		}
+	}
+
+	data := buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:      long(w),
+		tagImageLength:     long(h),
+		tagBitsPerSample:   short(8, 8, 8, 8),
+		tagCompression:     short(compressionDeflate),
+		tagPhotometric:     short(photoRGB),
+		tagSamplesPerPixel: short(4),
+		tagExtraSamples:    short(2),
+		tagTileWidth:       long(tile),
+		tagTileLength:      long(tile),
+	}, tiles, tagTileOffsets, tagTileByteCounts)
+
+	img, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		t.Fatalf("Decode() = %T, want *image.NRGBA", img)
+	}
+	for _, p := range []image.Point{{0, 0}, {15, 15}, {16, 3}, {19, 19}} {
+		want := color.NRGBA{uint8(p.X), uint8(p.Y), 7, 128}
+		if got := nrgba.NRGBAAt(p.X, p.Y); got != want {
+			t.Errorf("pixel %v = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestDecode_PlanarWhiteIsZero(t *testing.T) {
+	// RGB planar 2x1
+	planes := [][]byte{{10, 20}, {30, 40}, {50, 60}}
+	data := buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:      long(2),
+		tagImageLength:     long(1),
+		tagBitsPerSample:   short(8, 8, 8),
+		tagPhotometric:     short(photoRGB),
+		tagSamplesPerPixel: short(3),
+		tagPlanarConfig:    short(2),
+	}, planes, tagStripOffsets, tagStripByteCounts)
+
+	img, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := color.RGBAModel.Convert(img.At(1, 0)), (color.RGBA{20, 40, 60, 255}); got != want {
+		t.Errorf("pixel planar = %v, want %v", got, want)
+	}
+
+	// 1 bit, 0 = branco: 0b01000000 → branco, preto, branco...
+	data = buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:    long(3),
+		tagImageLength:   long(1),
+		tagBitsPerSample: short(1),
+		tagPhotometric:   short(photoWhiteIsZero),
+	}, [][]byte{{0x40}}, tagStripOffsets, tagStripByteCounts)
+
+	img, err = Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x, want := range []uint8{255, 0, 255} {
+		if got := img.(*image.Gray).GrayAt(x, 0).Y; got != want {
+			t.Errorf("pixel 1 bit %d = %d, want %d", x, got, want)
+		}
+	}
+}
+
+func TestDecode_Malformed(t *testing.T) {
+	valid := buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:      long(8),
+		tagImageLength:     long(8),
+		tagBitsPerSample:   short(8, 8, 8),
+		tagCompression:     short(compressionLZW),
+		tagPhotometric:     short(photoRGB),
+		tagSamplesPerPixel: short(3),
+	}, [][]byte{encodeLZW(make([]byte, 8*8*3))}, tagStripOffsets, tagStripByteCounts)
+
+	if _, err := Decode(bytes.NewReader(valid)); err != nil {
+		t.Fatalf("Decode(válido) error = %v", err)
+	}
+
+	// Nenhuma versão truncada ou corrompida pode causar panic
+	for n := 0; n < len(valid); n++ {
+		Decode(bytes.NewReader(valid[:n]))
+
+		corrupt := bytes.Clone(valid)
+		corrupt[n] ^= 0xFF
+		Decode(bytes.NewReader(corrupt))
+	}
+
+	unsupported := buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:    long(1),
+		tagImageLength:   long(1),
+		tagBitsPerSample: short(8),
+		tagCompression:   short(7), // JPEG
+	}, [][]byte{{0}}, tagStripOffsets, tagStripByteCounts)
+	if _, err := Decode(bytes.NewReader(unsupported)); err == nil {
+		t.Error("Decode() com compressão JPEG deveria falhar")
+	} else if _, ok := err.(UnsupportedError); !ok {
+		t.Errorf("Decode() error = %T, want UnsupportedError", err)
+	}
+}
+
+func TestDecode_MalformedHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[uint16]field
+		tiled  bool
+	}{
+		// Bloco de 2^31 pixels de largura em uma imagem 1x1: antes reservava 16GB
+		{"bloco largo demais", map[uint16]field{
+			tagImageWidth:      long(1),
+			tagImageLength:     long(1),
+			tagBitsPerSample:   short(16, 16, 16, 16),
+			tagCompression:     short(compressionLZW),
+			tagPhotometric:     short(photoRGB),
+			tagSamplesPerPixel: short(4),
+			tagExtraSamples:    short(2),
+			tagTileWidth:       long(0x7FFFFFFF),
+			tagTileLength:      long(1),
+		}, true},
+		{"bloco com pixels demais", map[uint16]field{
+			tagImageWidth:    long(16),
+			tagImageLength:   long(1 << 14),
+			tagBitsPerSample: short(8),
+			tagCompression:   short(compressionPackBits),
+			tagTileWidth:     long(1 << 14),
+			tagTileLength:    long(1 << 14),
+		}, true},
+		{"faixa larga demais", map[uint16]field{
+			tagImageWidth:    long(1 << 27),
+			tagImageLength:   long(1),
+			tagBitsPerSample: short(8),
+		}, false},
+		{"amostras demais", map[uint16]field{
+			tagImageWidth:      long(1),
+			tagImageLength:     long(1),
+			tagBitsPerSample:   short(8),
+			tagSamplesPerPixel: long(1 << 30),
+		}, false},
+		{"blocos demais", map[uint16]field{
+			tagImageWidth:    long(2048),
+			tagImageLength:   long(1024),
+			tagBitsPerSample: short(8),
+			tagTileWidth:     long(1),
+			tagTileLength:    long(1),
+		}, true},
+		{"bloco vazio", map[uint16]field{
+			tagImageWidth:    long(1),
+			tagImageLength:   long(1),
+			tagBitsPerSample: short(8),
+			tagTileWidth:     long(0),
+			tagTileLength:    long(1),
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offsetTag, countTag := uint16(tagStripOffsets), uint16(tagStripByteCounts)
+			if tt.tiled {
+				offsetTag, countTag = tagTileOffsets, tagTileByteCounts
+			}
+			data := buildTIFF(binary.LittleEndian, tt.fields, [][]byte{{0x80, 0x00}}, offsetTag, countTag)
+			if _, err := Decode(bytes.NewReader(data)); err == nil {
+				t.Error("Decode() deveria falhar")
+			}
+		})
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add(buildTIFF(binary.BigEndian, map[uint16]field{
+		tagImageWidth:      long(2),
+		tagImageLength:     long(2),
+		tagBitsPerSample:   short(8, 8, 8),
+		tagPhotometric:     short(photoRGB),
+		tagSamplesPerPixel: short(3),
+		tagRowsPerStrip:    long(1),
+	}, [][]byte{{255, 0, 0, 0, 255, 0}, {0, 0, 255, 10, 20, 30}}, tagStripOffsets, tagStripByteCounts))
+	f.Add(buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:    long(8),
+		tagImageLength:   long(8),
+		tagBitsPerSample: short(16),
+		tagCompression:   short(compressionLZW),
+		tagPredictor:     short(2),
+		tagTileWidth:     long(16),
+		tagTileLength:    long(16),
+	}, [][]byte{encodeLZW(make([]byte, 16*16*2))}, tagTileOffsets, tagTileByteCounts))
+	f.Add(buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:      long(4),
+		tagImageLength:     long(4),
+		tagBitsPerSample:   short(8, 8, 8, 8),
+		tagCompression:     short(compressionDeflate),
+		tagPhotometric:     short(photoRGB),
+		tagSamplesPerPixel: short(4),
+		tagExtraSamples:    short(1),
+		tagPlanarConfig:    short(2),
+	}, [][]byte{deflate(make([]byte, 16)), deflate(make([]byte, 16)), deflate(make([]byte, 16)), deflate(make([]byte, 16))}, tagStripOffsets, tagStripByteCounts))
+	f.Add(buildTIFF(binary.LittleEndian, map[uint16]field{
+		tagImageWidth:    long(3),
+		tagImageLength:   long(1),
+		tagBitsPerSample: short(1),
+		tagCompression:   short(compressionPackBits),
+		tagPhotometric:   short(photoWhiteIsZero),
+	}, [][]byte{{0x00, 0xA0}}, tagStripOffsets, tagStripByteCounts))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cfg, err := DecodeConfig(bytes.NewReader(data))
+		img, derr := Decode(bytes.NewReader(data))
+		if err != nil || derr != nil {
+			return
+		}
+		// A imagem decodificada tem o tamanho anunciado pelo cabeçalho
+		if b := img.Bounds(); b.Dx() != cfg.Width || b.Dy() != cfg.Height {
+			t.Errorf("Decode() = %v, DecodeConfig() = %dx%d", b, cfg.Width, cfg.Height)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package transcode
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Key identifica uma versão de um arquivo: mudanças de mtime ou tamanho
+// geram uma nova chave, invalidando o resultado anterior
+type Key struct {
+	Path    string
+	ModTime time.Time
+	Size    int64
+
+	// Variant distingue resultados diferentes do mesmo arquivo (ex: tamanhos)
+	Variant string
+}
+
+// Cache é um cache LRU limitado pelo total de bytes armazenados
+type Cache struct {
+	max int64
+
+	mu    sync.Mutex
+	size  int64
+	order *list.List // Mais recente na frente
+	items map[Key]*list.Element
+}
+
+type cacheEntry struct {
+	key    Key
+	result *Result
+}
+
+// NewCache cria um cache que guarda até max bytes
+func NewCache(max int64) *Cache {
+	return &Cache{
+		max:   max,
+		order: list.New(),
+		items: make(map[Key]*list.Element),
+	}
+}
+
+// Get retorna o resultado em cache para key
+func (c *Cache) Get(key Key) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).result, true
+}
+
+// Put armazena result, descartando os menos usados se o limite for
+// ultrapassado. Resultados maiores que o limite não são armazenados.
+func (c *Cache) Put(key Key, result *Result) {
+	n := int64(len(result.Data))
+	if n > c.max {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Versões antigas do mesmo arquivo nunca mais serão pedidas
+	for k, el := range c.items {
+		if k.Path == key.Path && k.Variant == key.Variant && k != key {
+			c.removeElement(el)
+		}
+	}
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, result: result})
+	c.size += n
+
+	for c.size > c.max {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Len retorna quantos resultados estão em cache
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *Cache) removeElement(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.order.Remove(el)
+	delete(c.items, entry.key)
+	c.size -= int64(len(entry.result.Data))
+}
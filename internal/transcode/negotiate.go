@@ -0,0 +1,84 @@
+package transcode
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/verseles/sidelook/internal/imagetype"
+)
+
+// ParseFormat interpreta o parâmetro format de uma requisição. Só os formatos
+// que podem ser codificados são aceitos (não há codificador WebP).
+func ParseFormat(value string) (imagetype.Format, bool) {
+	switch strings.ToLower(value) {
+	case "":
+		return "", true
+	case "png":
+		return imagetype.PNG, true
+	case "jpeg", "jpg":
+		return imagetype.JPEG, true
+	}
+	return "", false
+}
+
+// Negotiate escolhe o formato de saída entre os que podem ser codificados
+// (PNG e JPEG). Um formato pedido explicitamente tem prioridade; senão,
+// vale o cabeçalho Accept. Retorna "" (formato da origem) quando o cliente
+// aceita ambos ou nenhum deles.
+func Negotiate(requested imagetype.Format, accept string) imagetype.Format {
+	if requested == imagetype.PNG || requested == imagetype.JPEG {
+		return requested
+	}
+	if accept == "" {
+		return ""
+	}
+
+	png := acceptQuality(accept, "image/png") > 0
+	jpeg := acceptQuality(accept, "image/jpeg") > 0
+	switch {
+	case png && !jpeg:
+		return imagetype.PNG
+	case jpeg && !png:
+		return imagetype.JPEG
+	}
+	return ""
+}
+
+// acceptQuality retorna o fator q que o cabeçalho Accept atribui a mime,
+// usando a faixa mais específica que casa (image/png > image/* > */*)
+func acceptQuality(accept, mime string) float64 {
+	major, _, _ := strings.Cut(mime, "/")
+
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+
+		var spec int
+		switch mediaRange {
+		case mime:
+			spec = 2
+		case major + "/*":
+			spec = 1
+		case "*/*":
+			spec = 0
+		default:
+			continue
+		}
+		if spec < specificity {
+			continue
+		}
+
+		value := 1.0
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					value = f
+				}
+			}
+		}
+		q, specificity = value, spec
+	}
+	return q
+}
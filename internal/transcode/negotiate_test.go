@@ -0,0 +1,52 @@
+package transcode
+
+import (
+	"testing"
+
+	"github.com/verseles/sidelook/internal/imagetype"
+)
+
+func TestNegotiate(t *testing.T) {
+	browser := "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8"
+
+	tests := []struct {
+		name      string
+		requested imagetype.Format
+		accept    string
+		want      imagetype.Format
+	}{
+		{"sem preferências", "", "", ""},
+		{"navegador", "", browser, ""},
+		{"png explícito", imagetype.PNG, browser, imagetype.PNG},
+		{"jpeg explícito", imagetype.JPEG, "image/png", imagetype.JPEG},
+		{"apenas png", "", "image/png", imagetype.PNG},
+		{"apenas jpeg", "", "image/webp,image/jpeg", imagetype.JPEG},
+		{"png recusado", "", "image/png;q=0,image/*", imagetype.JPEG},
+		{"jpeg recusado", "", "image/*;q=0.9, image/jpeg;q=0", imagetype.PNG},
+		{"nenhum aceito", "", "text/html", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.requested, tt.accept); got != tt.want {
+				t.Errorf("Negotiate(%q, %q) = %q, want %q", tt.requested, tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for value, want := range map[string]imagetype.Format{
+		"": "", "png": imagetype.PNG, "JPG": imagetype.JPEG, "jpeg": imagetype.JPEG,
+	} {
+		if got, ok := ParseFormat(value); !ok || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q", value, got, ok, want)
+		}
+	}
+	// Sem codificador, WebP é recusado em vez de virar outro formato
+	for _, value := range []string{"gif", "webp"} {
+		if _, ok := ParseFormat(value); ok {
+			t.Errorf("ParseFormat(%s) deveria falhar", value)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package transcode
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/verseles/sidelook/internal/exif"
+)
+
+// Orientation retorna a orientação EXIF do arquivo em path (1 quando não há
+// EXIF ou a tag está ausente)
+func Orientation(path string) int {
+	e, err := exif.ReadFile(path)
+	if err != nil {
+		return 1
+	}
+	return e.Orientation()
+}
+
+// Transposed indica se a orientação troca largura e altura (rotações de 90°
+// e 270°, com ou sem espelhamento)
+func Transposed(o int) bool {
+	return o >= 5 && o <= 8
+}
+
+// Orient aplica a orientação EXIF o a img, devolvendo a imagem como deve ser
+// exibida. Imagens RGBA, NRGBA e Gray mantêm o tipo; as demais viram NRGBA.
+func Orient(img image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return img
+	}
+
+	switch src := img.(type) {
+	case *image.RGBA:
+		dst := image.NewRGBA(orientedBounds(src.Rect, o))
+		orientPix(dst.Pix, dst.Stride, src.Pix, src.Stride, src.Rect.Dx(), src.Rect.Dy(), 4, o)
+		return dst
+	case *image.NRGBA:
+		dst := image.NewNRGBA(orientedBounds(src.Rect, o))
+		orientPix(dst.Pix, dst.Stride, src.Pix, src.Stride, src.Rect.Dx(), src.Rect.Dy(), 4, o)
+		return dst
+	case *image.Gray:
+		dst := image.NewGray(orientedBounds(src.Rect, o))
+		orientPix(dst.Pix, dst.Stride, src.Pix, src.Stride, src.Rect.Dx(), src.Rect.Dy(), 1, o)
+		return dst
+	}
+
+	b := img.Bounds()
+	tmp := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(tmp, tmp.Rect, img, b.Min, draw.Src)
+	return Orient(tmp, o)
+}
+
+// orientedBounds retorna o retângulo de destino, com origem em (0,0)
+func orientedBounds(r image.Rectangle, o int) image.Rectangle {
+	if Transposed(o) {
+		return image.Rect(0, 0, r.Dy(), r.Dx())
+	}
+	return image.Rect(0, 0, r.Dx(), r.Dy())
+}
+
+// orientPix copia os pixels de src (w×h, bpp bytes por pixel) para a posição
+// em que aparecem na orientação o
+func orientPix(dst []byte, dstStride int, src []byte, srcStride, w, h, bpp, o int) {
+	for y := 0; y < h; y++ {
+		row := src[y*srcStride:]
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2: // Espelhada na horizontal
+				dx, dy = w-1-x, y
+			case 3: // Girada 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // Espelhada na vertical
+				dx, dy = x, h-1-y
+			case 5: // Transposta
+				dx, dy = y, x
+			case 6: // Girada 90° no sentido horário
+				dx, dy = h-1-y, x
+			case 7: // Transversa
+				dx, dy = h-1-y, w-1-x
+			case 8: // Girada 90° no sentido anti-horário
+				dx, dy = y, w-1-x
+			}
+			copy(dst[dy*dstStride+dx*bpp:dy*dstStride+dx*bpp+bpp], row[x*bpp:x*bpp+bpp])
+		}
+	}
+}
+
+// storedRect converte um retângulo na imagem exibida (já orientada) para o
+// retângulo correspondente nos pixels gravados, de tamanho w×h
+func storedRect(r image.Rectangle, w, h, o int) image.Rectangle {
+	switch o {
+	case 2:
+		return image.Rect(w-r.Max.X, r.Min.Y, w-r.Min.X, r.Max.Y)
+	case 3:
+		return image.Rect(w-r.Max.X, h-r.Max.Y, w-r.Min.X, h-r.Min.Y)
+	case 4:
+		return image.Rect(r.Min.X, h-r.Max.Y, r.Max.X, h-r.Min.Y)
+	case 5:
+		return image.Rect(r.Min.Y, r.Min.X, r.Max.Y, r.Max.X)
+	case 6:
+		return image.Rect(r.Min.Y, h-r.Max.X, r.Max.Y, h-r.Min.X)
+	case 7:
+		return image.Rect(w-r.Max.Y, h-r.Max.X, w-r.Min.Y, h-r.Min.X)
+	case 8:
+		return image.Rect(w-r.Max.Y, r.Min.X, w-r.Min.Y, r.Max.X)
+	}
+	return r
+}
+
+// ResizeOriented reduz para w×h a região sr da imagem como é exibida na
+// orientação o. img tem os pixels como foram gravados no arquivo; só a região
+// correspondente é lida, e a orientação é aplicada no resultado reduzido.
+func ResizeOriented(img image.Image, sr image.Rectangle, w, h, o int) *image.RGBA {
+	b := img.Bounds()
+	stored := storedRect(sr, b.Dx(), b.Dy(), o).Add(b.Min)
+	if Transposed(o) {
+		w, h = h, w
+	}
+	return Orient(Resize(img, stored, w, h), o).(*image.RGBA)
+}
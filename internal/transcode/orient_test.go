@@ -0,0 +1,170 @@
+package transcode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/verseles/sidelook/internal/imagetype"
+)
+
+// withOrientation insere em um JPEG um segmento APP1 com a tag Orientation
+func withOrientation(data []byte, o int) []byte {
+	le := binary.LittleEndian
+	tiff := []byte("II*\x00")
+	tiff = le.AppendUint32(tiff, 8)
+	tiff = le.AppendUint16(tiff, 1)
+	tiff = le.AppendUint16(tiff, 0x0112)
+	tiff = le.AppendUint16(tiff, 3)
+	tiff = le.AppendUint32(tiff, 1)
+	tiff = le.AppendUint16(tiff, uint16(o))
+	tiff = le.AppendUint16(tiff, 0)
+	tiff = le.AppendUint32(tiff, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(payload)+2))
+	app1 = append(app1, payload...)
+
+	out := append([]byte(nil), data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func TestOrient(t *testing.T) {
+	// Pixels gravados:
+	//   0 1 2
+	//   3 4 5
+	src := image.NewGray(image.Rect(0, 0, 3, 2))
+	copy(src.Pix, []byte{0, 1, 2, 3, 4, 5})
+
+	tests := []struct {
+		o    int
+		w, h int
+		want []byte
+	}{
+		{1, 3, 2, []byte{0, 1, 2, 3, 4, 5}},
+		{2, 3, 2, []byte{2, 1, 0, 5, 4, 3}},
+		{3, 3, 2, []byte{5, 4, 3, 2, 1, 0}},
+		{4, 3, 2, []byte{3, 4, 5, 0, 1, 2}},
+		{5, 2, 3, []byte{0, 3, 1, 4, 2, 5}},
+		{6, 2, 3, []byte{3, 0, 4, 1, 5, 2}},
+		{7, 2, 3, []byte{5, 2, 4, 1, 3, 0}},
+		{8, 2, 3, []byte{2, 5, 1, 4, 0, 3}},
+	}
+	for _, tt := range tests {
+		got, ok := Orient(src, tt.o).(*image.Gray)
+		if !ok {
+			t.Fatalf("Orient(%d) mudou o tipo da imagem", tt.o)
+		}
+		if b := got.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("Orient(%d) = %v, want %dx%d", tt.o, b, tt.w, tt.h)
+		}
+		if !bytes.Equal(got.Pix, tt.want) {
+			t.Errorf("Orient(%d) = %v, want %v", tt.o, got.Pix, tt.want)
+		}
+
+		// Outros modelos de cor passam pelo mesmo mapeamento
+		rgba := Orient(Resize(src, src.Rect, 3, 2), tt.o).(*image.RGBA)
+		for i, v := range tt.want {
+			if rgba.Pix[i*4] != v {
+				t.Errorf("Orient(%d) RGBA pixel %d = %d, want %d", tt.o, i, rgba.Pix[i*4], v)
+				break
+			}
+		}
+	}
+}
+
+func TestStoredRect(t *testing.T) {
+	// Recortar a imagem exibida deve dar o mesmo que orientar o recorte
+	// correspondente dos pixels gravados
+	src := image.NewGray(image.Rect(0, 0, 5, 3))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+	for o := 1; o <= 8; o++ {
+		display := Orient(src, o).(*image.Gray)
+		r := image.Rect(1, 1, display.Rect.Dx(), display.Rect.Dy()-1)
+
+		want := image.NewGray(image.Rect(0, 0, r.Dx(), r.Dy()))
+		for y := 0; y < r.Dy(); y++ {
+			for x := 0; x < r.Dx(); x++ {
+				want.SetGray(x, y, display.GrayAt(r.Min.X+x, r.Min.Y+y))
+			}
+		}
+
+		stored := storedRect(r, 5, 3, o)
+		sub := image.NewGray(image.Rect(0, 0, stored.Dx(), stored.Dy()))
+		for y := 0; y < stored.Dy(); y++ {
+			for x := 0; x < stored.Dx(); x++ {
+				sub.SetGray(x, y, src.GrayAt(stored.Min.X+x, stored.Min.Y+y))
+			}
+		}
+		if got := Orient(sub, o).(*image.Gray); !reflect.DeepEqual(got.Pix, want.Pix) {
+			t.Errorf("orientação %d: recorte %v -> %v = %v, want %v", o, r, stored, got.Pix, want.Pix)
+		}
+	}
+}
+
+func TestTranscoder_ResizeOriented(t *testing.T) {
+	dir := t.TempDir()
+
+	// Foto de celular gravada deitada (400x200, metade esquerda vermelha)
+	// com orientação 6: exibida em pé, 200x400, com o vermelho em cima
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			c := color.RGBA{0, 0, 255, 255}
+			if x < 200 {
+				c = color.RGBA{255, 0, 0, 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "phone.jpg")
+	info := writeFile(t, path, withOrientation(buf.Bytes(), 6))
+
+	if o := Orientation(path); o != 6 {
+		t.Fatalf("Orientation() = %d, want 6", o)
+	}
+
+	res, err := New(0).Resize(path, info, Spec{Width: 100, Height: 100, Format: imagetype.PNG})
+	if err != nil {
+		t.Fatalf("Resize() error = %v", err)
+	}
+	img := decodeResult(t, res)
+	if b := img.Bounds(); b.Dx() != 50 || b.Dy() != 100 {
+		t.Fatalf("dimensões = %v, want 50x100", b)
+	}
+	if r, _, b, _ := img.At(25, 10).RGBA(); r>>8 < 200 || b>>8 > 50 {
+		t.Errorf("topo = %v, want vermelho", img.At(25, 10))
+	}
+	if r, _, b, _ := img.At(25, 90).RGBA(); r>>8 > 50 || b>>8 < 200 {
+		t.Errorf("base = %v, want azul", img.At(25, 90))
+	}
+
+	// Cover recorta o centro da imagem exibida
+	res, err = New(0).Resize(path, info, Spec{Width: 50, Height: 50, Cover: true, Format: imagetype.PNG})
+	if err != nil {
+		t.Fatalf("Resize(cover) error = %v", err)
+	}
+	img = decodeResult(t, res)
+	if b := img.Bounds(); b.Dx() != 50 || b.Dy() != 50 {
+		t.Errorf("dimensões = %v, want 50x50", b)
+	}
+
+	// No tamanho original o arquivo é servido como está (o navegador aplica
+	// a orientação do EXIF)
+	if res, err := New(0).Resize(path, info, Spec{Width: 200, Height: 400}); res != nil || err != nil {
+		t.Errorf("Resize(tamanho exibido) = %v, %v, want nil, nil", res, err)
+	}
+}
@@ -0,0 +1,242 @@
+package transcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/verseles/sidelook/internal/imagetype"
+)
+
+const (
+	// MaxDimension é o maior Width/Height aceito em Spec
+	MaxDimension = 16384
+
+	// MaxPixels é o maior número de pixels decodificado (64 MP, fotos de
+	// câmeras de 50 MP passam), para não tentar imagens que não caberiam na
+	// memória (ver CheckSize). Com as decodificações limitadas a 4 por vez,
+	// o pior caso fica em torno de 2GB (RGBA de 16 bits).
+	MaxPixels = 1 << 26
+
+	// JPEGQuality é a qualidade das versões e miniaturas geradas em JPEG
+	JPEGQuality = 85
+)
+
+// ErrTooLarge indica uma imagem com mais de MaxPixels pixels
+var ErrTooLarge = errors.New("imagem grande demais para decodificar")
+
+// CheckSize recusa imagens, pelo cabeçalho já lido, que passam de MaxPixels
+func CheckSize(cfg image.Config) error {
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	return nil
+}
+
+// Spec descreve uma versão redimensionada de uma imagem
+type Spec struct {
+	// Width e Height limitam o tamanho em pixels (0 = sem limite)
+	Width, Height int
+
+	// Cover preenche exatamente Width×Height recortando o excesso no centro.
+	// Sem Cover (contain), a imagem inteira cabe em Width×Height.
+	Cover bool
+
+	// Format é o formato de saída, PNG ou JPEG ("" = o formato da origem;
+	// origens que não são JPEG viram PNG, sem perdas)
+	Format imagetype.Format
+}
+
+func (s Spec) variant() string {
+	return fmt.Sprintf("%dx%d cover=%t %s", s.Width, s.Height, s.Cover, s.Format)
+}
+
+// layout calcula a região de origem e o tamanho de saída para uma imagem
+// w×h. Imagens nunca são ampliadas.
+func (s Spec) layout(w, h int) (crop image.Rectangle, outW, outH int) {
+	if !s.Cover || s.Width <= 0 || s.Height <= 0 {
+		maxW, maxH := w, h
+		if s.Width > 0 {
+			maxW = s.Width
+		}
+		if s.Height > 0 {
+			maxH = s.Height
+		}
+		outW, outH = FitSize(w, h, maxW, maxH)
+		return image.Rect(0, 0, w, h), outW, outH
+	}
+
+	// Maior região central com a proporção pedida
+	cw, ch := w, h
+	if w*s.Height > h*s.Width {
+		cw = max(1, h*s.Width/s.Height)
+	} else {
+		ch = max(1, w*s.Height/s.Width)
+	}
+	x, y := (w-cw)/2, (h-ch)/2
+	crop = image.Rect(x, y, x+cw, y+ch)
+
+	if cw <= s.Width {
+		return crop, cw, ch
+	}
+	return crop, s.Width, s.Height
+}
+
+// Resize retorna a imagem em path redimensionada conforme spec. Retorna nil
+// sem erro quando não há o que fazer: a imagem já tem o tamanho e o formato
+// pedidos, não há decodificador para o formato (WebP, SVG...) ou é um GIF,
+// servido original para não perder a animação.
+func (t *Transcoder) Resize(path string, info os.FileInfo, spec Spec) (*Result, error) {
+	key := Key{Path: path, ModTime: info.ModTime(), Size: info.Size(), Variant: spec.variant()}
+	return t.do(key, func() (*Result, error) {
+		return t.resize(path, spec)
+	})
+}
+
+func (t *Transcoder) resize(path string, spec Spec) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if errors.Is(err, image.ErrFormat) || format == "gif" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decodificar %s: %w", path, err)
+	}
+	if err := CheckSize(cfg); err != nil {
+		return nil, err
+	}
+
+	// Tamanhos e recortes valem para a imagem como é exibida. O original,
+	// quando servido, leva o EXIF e o navegador aplica a orientação.
+	o := Orientation(path)
+	dispW, dispH := cfg.Width, cfg.Height
+	if Transposed(o) {
+		dispW, dispH = dispH, dispW
+	}
+	crop, w, h := spec.layout(dispW, dispH)
+	if w == dispW && h == dispH && (spec.Format == "" || string(spec.Format) == format) {
+		return nil, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	defer t.acquire()()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decodificar %s: %w", path, err)
+	}
+	img = To8Bit(img)
+
+	out := ResizeOriented(img, crop, w, h, o)
+	outFormat := spec.Format
+	if outFormat == "" {
+		outFormat = imagetype.PNG
+		if format == "jpeg" {
+			outFormat = imagetype.JPEG
+		}
+	}
+	res, err := encode(out, outFormat)
+	if err != nil {
+		return nil, fmt.Errorf("codificar %s: %w", path, err)
+	}
+	return res, nil
+}
+
+// encode codifica img em JPEG ou PNG
+func encode(img *image.RGBA, format imagetype.Format) (*Result, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == imagetype.JPEG {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEGQuality})
+	} else {
+		format = imagetype.PNG
+		enc := png.Encoder{CompressionLevel: png.BestSpeed}
+		err = enc.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Data: buf.Bytes(), ContentType: format.MIME()}, nil
+}
+
+// FitSize retorna as dimensões de uma imagem w×h reduzida para caber em
+// maxW×maxH mantendo a proporção. Imagens menores não são ampliadas.
+func FitSize(w, h, maxW, maxH int) (int, int) {
+	if w <= maxW && h <= maxH {
+		return w, h
+	}
+	// Comparar proporções sem divisão: w/h > maxW/maxH
+	if w*maxH > h*maxW {
+		return maxW, max(1, h*maxW/w)
+	}
+	return max(1, w*maxH/h), maxH
+}
+
+// Resize reduz a região sr de src para w×h fazendo a média dos pixels de
+// origem de cada pixel de destino (filtro de caixa). A origem é lida linha a
+// linha, então a memória usada não depende do tamanho de src. Ampliações não
+// são suportadas: w e h são limitados ao tamanho de sr.
+func Resize(src image.Image, sr image.Rectangle, w, h int) *image.RGBA {
+	sw, sh := sr.Dx(), sr.Dy()
+	w, h = min(max(w, 1), sw), min(max(h, 1), sh)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if sw <= 0 || sh <= 0 {
+		return dst
+	}
+
+	// Coluna de destino de cada coluna de origem
+	cols := make([]int, sw)
+	colCount := make([]uint64, w)
+	for x := range cols {
+		cols[x] = x * w / sw
+		colCount[cols[x]]++
+	}
+
+	row := image.NewRGBA(image.Rect(0, 0, sw, 1))
+	acc := make([]uint64, w*4)
+	flush := func(dy int, rows uint64) {
+		out := dst.Pix[dy*dst.Stride:]
+		for dx := 0; dx < w; dx++ {
+			n := colCount[dx] * rows
+			for c := 0; c < 4; c++ {
+				out[dx*4+c] = uint8((acc[dx*4+c] + n/2) / n)
+				acc[dx*4+c] = 0
+			}
+		}
+	}
+
+	cur, rows := 0, uint64(0)
+	for y := 0; y < sh; y++ {
+		if dy := y * h / sh; dy != cur {
+			flush(cur, rows)
+			cur, rows = dy, 0
+		}
+		// draw converte qualquer modelo de cor para RGBA pré-multiplicado,
+		// com caminhos rápidos para YCbCr (JPEG), Gray, NRGBA...
+		draw.Draw(row, row.Rect, src, image.Pt(sr.Min.X, sr.Min.Y+y), draw.Src)
+		for x, dx := range cols {
+			p := row.Pix[x*4 : x*4+4]
+			a := acc[dx*4 : dx*4+4]
+			a[0] += uint64(p[0])
+			a[1] += uint64(p[1])
+			a[2] += uint64(p[2])
+			a[3] += uint64(p[3])
+		}
+		rows++
+	}
+	flush(cur, rows)
+
+	return dst
+}
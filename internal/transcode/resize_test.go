@@ -0,0 +1,237 @@
+package transcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/verseles/sidelook/internal/imagetype"
+)
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		w, h, maxW, maxH int
+		wantW, wantH     int
+	}{
+		{1000, 500, 256, 256, 256, 128},
+		{500, 1000, 256, 256, 128, 256},
+		{100, 50, 256, 256, 100, 50},
+		{4000, 3000, 800, 600, 800, 600},
+		{10000, 1, 100, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		w, h := FitSize(tt.w, tt.h, tt.maxW, tt.maxH)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("FitSize(%d, %d, %d, %d) = %dx%d, want %dx%d",
+				tt.w, tt.h, tt.maxW, tt.maxH, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestResize_Average(t *testing.T) {
+	// Listras verticais preto/branco viram cinza médio
+	src := image.NewGray(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if x%2 == 0 {
+				src.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+
+	dst := Resize(src, src.Bounds(), 2, 2)
+	if dst.Bounds().Dx() != 2 || dst.Bounds().Dy() != 2 {
+		t.Fatalf("dimensões = %v, want 2x2", dst.Bounds())
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			if got := dst.RGBAAt(x, y); got.R != 128 || got.A != 255 {
+				t.Errorf("pixel (%d,%d) = %v, want cinza 128 opaco", x, y, got)
+			}
+		}
+	}
+}
+
+func TestResize_SubRect(t *testing.T) {
+	// Metade esquerda vermelha, direita azul; recortar só a direita
+	src := image.NewRGBA(image.Rect(10, 10, 30, 20))
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 30; x++ {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= 20 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	dst := Resize(src, image.Rect(20, 10, 30, 20), 5, 5)
+	if got := dst.RGBAAt(0, 0); got.B != 255 || got.R != 0 {
+		t.Errorf("pixel = %v, want azul", got)
+	}
+}
+
+func TestResize_NoUpscale(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	if b := Resize(src, src.Bounds(), 100, 50).Bounds(); b.Dx() != 10 || b.Dy() != 10 {
+		t.Errorf("dimensões = %v, want 10x10", b)
+	}
+}
+
+func TestSpecLayout(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       Spec
+		w, h       int
+		crop       image.Rectangle
+		outW, outH int
+	}{
+		{"contain", Spec{Width: 800, Height: 800}, 4000, 2000, image.Rect(0, 0, 4000, 2000), 800, 400},
+		{"só largura", Spec{Width: 1000}, 4000, 3000, image.Rect(0, 0, 4000, 3000), 1000, 750},
+		{"só altura", Spec{Height: 300}, 4000, 3000, image.Rect(0, 0, 4000, 3000), 400, 300},
+		{"contain menor", Spec{Width: 800, Height: 800}, 100, 50, image.Rect(0, 0, 100, 50), 100, 50},
+		{"cover larga", Spec{Width: 100, Height: 100, Cover: true}, 400, 200, image.Rect(100, 0, 300, 200), 100, 100},
+		{"cover alta", Spec{Width: 200, Height: 100, Cover: true}, 400, 400, image.Rect(0, 100, 400, 300), 200, 100},
+		{"cover menor", Spec{Width: 1000, Height: 1000, Cover: true}, 400, 200, image.Rect(100, 0, 300, 200), 200, 200},
+		{"cover sem altura", Spec{Width: 100, Cover: true}, 400, 200, image.Rect(0, 0, 400, 200), 100, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crop, w, h := tt.spec.layout(tt.w, tt.h)
+			if crop != tt.crop || w != tt.outW || h != tt.outH {
+				t.Errorf("layout() = %v %dx%d, want %v %dx%d", crop, w, h, tt.crop, tt.outW, tt.outH)
+			}
+		})
+	}
+}
+
+func TestTranscoder_Resize(t *testing.T) {
+	dir := t.TempDir()
+	tr := New(0)
+
+	photo := filepath.Join(dir, "render.png")
+	info := writeFile(t, photo, encodePNG(t, image.NewRGBA(image.Rect(0, 0, 800, 400))))
+
+	// PNG totalmente transparente continua PNG
+	res, err := tr.Resize(photo, info, Spec{Width: 200, Height: 200})
+	if err != nil {
+		t.Fatalf("Resize() error = %v", err)
+	}
+	img := decodeResult(t, res)
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Errorf("dimensões = %v, want 200x100", b)
+	}
+
+	// Sem formato pedido, imagens opacas mantêm o formato da origem: PNG
+	// continua PNG (sem perdas) e JPEG continua JPEG
+	opaque := image.NewGray(image.Rect(0, 0, 800, 400))
+	for i := range opaque.Pix {
+		opaque.Pix[i] = byte(i)
+	}
+	opaquePNG := filepath.Join(dir, "opaque.png")
+	opaqueInfo := writeFile(t, opaquePNG, encodePNG(t, opaque))
+	if res, err := tr.Resize(opaquePNG, opaqueInfo, Spec{Width: 200}); err != nil || res == nil || res.ContentType != "image/png" {
+		t.Errorf("Resize(PNG opaco) = %v, %v, want PNG", res, err)
+	}
+	var jpg bytes.Buffer
+	jpeg.Encode(&jpg, opaque, nil)
+	opaqueJPEG := filepath.Join(dir, "opaque.jpg")
+	opaqueInfo = writeFile(t, opaqueJPEG, jpg.Bytes())
+	if res, err := tr.Resize(opaqueJPEG, opaqueInfo, Spec{Width: 200}); err != nil || res == nil || res.ContentType != "image/jpeg" {
+		t.Errorf("Resize(JPEG) = %v, %v, want JPEG", res, err)
+	}
+
+	// Formato explícito
+	res, err = tr.Resize(photo, info, Spec{Width: 100, Height: 100, Cover: true, Format: imagetype.JPEG})
+	if err != nil {
+		t.Fatalf("Resize(jpeg) error = %v", err)
+	}
+	if res.ContentType != "image/jpeg" {
+		t.Errorf("ContentType = %q, want image/jpeg", res.ContentType)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(res.Data))
+	if err != nil || cfg.Width != 100 || cfg.Height != 100 {
+		t.Errorf("JPEG = %dx%d, %v, want 100x100", cfg.Width, cfg.Height, err)
+	}
+
+	// Repetição vem do cache
+	again, _ := tr.Resize(photo, info, Spec{Width: 100, Height: 100, Cover: true, Format: imagetype.JPEG})
+	if again != res {
+		t.Error("segundo Resize() deveria vir do cache")
+	}
+
+	// Já cabe no tamanho pedido: nada a fazer
+	if res, err := tr.Resize(photo, info, Spec{Width: 4000, Height: 4000}); res != nil || err != nil {
+		t.Errorf("Resize(maior que a imagem) = %v, %v, want nil, nil", res, err)
+	}
+
+	// Mesmo tamanho, outro formato: converte
+	res, err = tr.Resize(photo, info, Spec{Width: 4000, Format: imagetype.JPEG})
+	if err != nil || res == nil || res.ContentType != "image/jpeg" {
+		t.Errorf("Resize(só formato) = %v, %v, want JPEG", res, err)
+	}
+
+	// GIF e formatos sem decodificador são servidos originais
+	gifPath := filepath.Join(dir, "anim.gif")
+	var buf bytes.Buffer
+	gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 500, 500), color.Palette{color.Black}), nil)
+	info = writeFile(t, gifPath, buf.Bytes())
+	if res, err := tr.Resize(gifPath, info, Spec{Width: 100}); res != nil || err != nil {
+		t.Errorf("Resize(gif) = %v, %v, want nil, nil", res, err)
+	}
+	webp := filepath.Join(dir, "photo.webp")
+	info = writeFile(t, webp, []byte("RIFF\x24\x00\x00\x00WEBPVP8 \x00\x00"))
+	if res, err := tr.Resize(webp, info, Spec{Width: 100}); res != nil || err != nil {
+		t.Errorf("Resize(webp) = %v, %v, want nil, nil", res, err)
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	if err := CheckSize(image.Config{Width: 8192, Height: 8192}); err != nil {
+		t.Errorf("CheckSize(8192x8192) = %v, want nil", err)
+	}
+	if err := CheckSize(image.Config{Width: 16384, Height: 16384}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("CheckSize(16384x16384) = %v, want ErrTooLarge", err)
+	}
+	if err := CheckSize(image.Config{Width: 1 << 16, Height: 1 << 16}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("CheckSize(65536x65536) = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestTranscoder_DecodeLimit(t *testing.T) {
+	tr := New(0)
+	path := filepath.Join(t.TempDir(), "render.png")
+	info := writeFile(t, path, encodePNG(t, image.NewRGBA(image.Rect(0, 0, 400, 400))))
+
+	// Com todas as vagas ocupadas, a decodificação espera
+	for i := 0; i < cap(tr.decodes); i++ {
+		tr.decodes <- struct{}{}
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := tr.Resize(path, info, Spec{Width: 100})
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("Resize() deveria esperar uma vaga de decodificação")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-tr.decodes
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Resize() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Resize() não terminou depois de liberada uma vaga")
+	}
+}
@@ -0,0 +1,225 @@
+// Package transcode converte imagens que os navegadores não exibem (TIFF,
+// PNG de 16 bits) em PNG de 8 bits e gera versões redimensionadas,
+// guardando os resultados em cache
+package transcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/verseles/sidelook/internal/imagetype"
+	"github.com/verseles/sidelook/internal/svgsafe"
+	_ "github.com/verseles/sidelook/internal/tiff" // Registrar decodificador TIFF
+)
+
+// DefaultCacheSize é o limite padrão do cache de conversões (64MB)
+const DefaultCacheSize = 64 << 20
+
+// Result é uma imagem convertida pronta para ser servida
+type Result struct {
+	Data        []byte
+	ContentType string
+}
+
+// Transcoder converte imagens sob demanda. Seguro para uso concorrente.
+type Transcoder struct {
+	cache *Cache
+
+	// decodes limita as decodificações simultâneas, como os workers das
+	// miniaturas: cada uma pode ocupar até MaxPixels pixels na memória
+	decodes chan struct{}
+
+	mu       sync.Mutex
+	inflight map[Key]*call
+}
+
+// call é uma conversão em andamento, compartilhada por pedidos simultâneos
+// (ex: vários clientes no mesmo slideshow)
+type call struct {
+	done chan struct{}
+	res  *Result
+	err  error
+}
+
+// New cria um Transcoder com cache de até cacheSize bytes
+func New(cacheSize int64) *Transcoder {
+	if cacheSize <= 0 {
+		cacheSize = DefaultCacheSize
+	}
+	return &Transcoder{
+		cache:    NewCache(cacheSize),
+		decodes:  make(chan struct{}, min(runtime.NumCPU(), 4)),
+		inflight: make(map[Key]*call),
+	}
+}
+
+// do retorna o resultado em cache para key ou o produz com fn, uma única
+// vez mesmo com pedidos simultâneos. Resultados nil não são guardados.
+func (t *Transcoder) do(key Key, fn func() (*Result, error)) (*Result, error) {
+	if res, ok := t.cache.Get(key); ok {
+		return res, nil
+	}
+
+	t.mu.Lock()
+	if c, ok := t.inflight[key]; ok {
+		t.mu.Unlock()
+		<-c.done
+		return c.res, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	t.inflight[key] = c
+	t.mu.Unlock()
+
+	c.res, c.err = fn()
+	if c.err == nil && c.res != nil {
+		t.cache.Put(key, c.res)
+	}
+
+	t.mu.Lock()
+	delete(t.inflight, key)
+	t.mu.Unlock()
+	close(c.done)
+
+	return c.res, c.err
+}
+
+// NeedsTranscode indica se o início do arquivo descreve uma imagem que os
+// navegadores não exibem: qualquer TIFF, ou PNG com 16 bits por canal (que
+// alguns navegadores reduzem mal ou recusam)
+func NeedsTranscode(head []byte) bool {
+	switch imagetype.Detect(head) {
+	case imagetype.TIFF:
+		return true
+	case imagetype.PNG:
+		// Assinatura (8) + tamanho (4) + "IHDR" (4) + largura (4) + altura (4)
+		return len(head) > 24 && string(head[12:16]) == "IHDR" && head[24] == 16
+	}
+	return false
+}
+
+// Convert retorna a versão para navegador do arquivo em path. Retorna nil
+// sem erro quando o arquivo pode ser servido como está.
+func (t *Transcoder) Convert(path string, info os.FileInfo) (*Result, error) {
+	key := Key{Path: path, ModTime: info.ModTime(), Size: info.Size()}
+	return t.do(key, func() (*Result, error) {
+		return t.convert(path)
+	})
+}
+
+// Sanitize retorna o SVG em path sem scripts nem atributos de evento (ver
+// svgsafe), guardado no cache como as conversões
+func (t *Transcoder) Sanitize(path string, info os.FileInfo) (*Result, error) {
+	key := Key{Path: path, ModTime: info.ModTime(), Size: info.Size(), Variant: "svgsafe"}
+	return t.do(key, func() (*Result, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		data, err := svgsafe.Sanitize(f)
+		if err != nil {
+			return nil, fmt.Errorf("limpar %s: %w", path, err)
+		}
+		return &Result{Data: data, ContentType: "image/svg+xml"}, nil
+	})
+}
+
+// acquire ocupa uma vaga de decodificação; a função retornada a libera
+func (t *Transcoder) acquire() func() {
+	t.decodes <- struct{}{}
+	return func() { <-t.decodes }
+}
+
+func (t *Transcoder) convert(path string) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, imagetype.HeaderSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	if !NeedsTranscode(head[:n]) {
+		return nil, nil
+	}
+
+	// O cabeçalho declara o tamanho: imagens grandes demais nem são decodificadas
+	cfg, _, err := image.DecodeConfig(io.MultiReader(bytes.NewReader(head[:n]), f))
+	if err != nil {
+		return nil, fmt.Errorf("decodificar %s: %w", path, err)
+	}
+	if err := CheckSize(cfg); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	defer t.acquire()()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decodificar %s: %w", path, err)
+	}
+
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	// O PNG gerado não leva o EXIF, então a orientação é aplicada aqui
+	if err := enc.Encode(&buf, Orient(To8Bit(img), Orientation(path))); err != nil {
+		return nil, fmt.Errorf("codificar %s: %w", path, err)
+	}
+
+	return &Result{Data: buf.Bytes(), ContentType: "image/png"}, nil
+}
+
+// To8Bit reduz imagens de 16 bits para 8 bits por canal. Tons de cinza são
+// esticados para a faixa usada de fato, já que imagens científicas costumam
+// ocupar só parte dos 16 bits e ficariam quase pretas.
+func To8Bit(img image.Image) image.Image {
+	switch src := img.(type) {
+	case *image.Gray16:
+		return stretchGray16(src)
+	case *image.RGBA64, *image.NRGBA64:
+		dst := image.NewNRGBA(img.Bounds())
+		draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+		return dst
+	}
+	return img
+}
+
+func stretchGray16(src *image.Gray16) *image.Gray {
+	b := src.Bounds()
+	lo, hi := uint16(0xFFFF), uint16(0)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := src.Gray16At(x, y).Y
+			lo = min(lo, v)
+			hi = max(hi, v)
+		}
+	}
+
+	dst := image.NewGray(b)
+	span := uint32(hi) - uint32(lo)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := src.Gray16At(x, y).Y
+			if span == 0 {
+				// Imagem uniforme: não há faixa para esticar
+				dst.Pix[dst.PixOffset(x, y)] = uint8(v >> 8)
+				continue
+			}
+			dst.Pix[dst.PixOffset(x, y)] = uint8(uint32(v-lo) * 255 / span)
+		}
+	}
+	return dst
+}
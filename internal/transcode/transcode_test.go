@@ -0,0 +1,288 @@
+package transcode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// grayTIFF monta um TIFF mínimo em tons de cinza de 8 bits, sem compressão
+func grayTIFF(w, h int, pix []byte) []byte {
+	le := binary.LittleEndian
+	buf := []byte("II*\x00")
+	buf = le.AppendUint32(buf, 8)
+
+	type entry struct{ tag, typ, value uint32 }
+	entries := []entry{
+		{256, 4, uint32(w)},
+		{257, 4, uint32(h)},
+		{258, 3, 8},
+		{259, 3, 1},
+		{262, 3, 1},
+		{273, 4, uint32(8 + 2 + 9*12 + 4)},
+		{277, 3, 1},
+		{278, 4, uint32(h)},
+		{279, 4, uint32(len(pix))},
+	}
+	buf = le.AppendUint16(buf, uint16(len(entries)))
+	for _, e := range entries {
+		buf = le.AppendUint16(buf, uint16(e.tag))
+		buf = le.AppendUint16(buf, uint16(e.typ))
+		buf = le.AppendUint32(buf, 1)
+		if e.typ == 3 {
+			buf = le.AppendUint16(buf, uint16(e.value))
+			buf = le.AppendUint16(buf, 0)
+		} else {
+			buf = le.AppendUint32(buf, e.value)
+		}
+	}
+	buf = le.AppendUint32(buf, 0)
+	return append(buf, pix...)
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func writeFile(t *testing.T, path string, data []byte) os.FileInfo {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func decodeResult(t *testing.T, res *Result) image.Image {
+	t.Helper()
+	if res == nil {
+		t.Fatal("Convert() = nil, want conversão")
+	}
+	if res.ContentType != "image/png" {
+		t.Errorf("ContentType = %q, want image/png", res.ContentType)
+	}
+	img, err := png.Decode(bytes.NewReader(res.Data))
+	if err != nil {
+		t.Fatalf("resultado não é um PNG válido: %v", err)
+	}
+	return img
+}
+
+func TestConvert_TIFF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.tiff")
+	info := writeFile(t, path, grayTIFF(2, 2, []byte{0, 64, 128, 255}))
+
+	tr := New(0)
+	res, err := tr.Convert(path, info)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	img := decodeResult(t, res)
+	if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 2 {
+		t.Errorf("dimensões = %v, want 2x2", img.Bounds())
+	}
+	if got := color.GrayModel.Convert(img.At(1, 0)).(color.Gray).Y; got != 64 {
+		t.Errorf("pixel (1,0) = %d, want 64", got)
+	}
+}
+
+func TestConvert_PNG16(t *testing.T) {
+	src := image.NewGray16(image.Rect(0, 0, 3, 1))
+	src.SetGray16(0, 0, color.Gray16{Y: 1000})
+	src.SetGray16(1, 0, color.Gray16{Y: 2000})
+	src.SetGray16(2, 0, color.Gray16{Y: 3000})
+
+	path := filepath.Join(t.TempDir(), "sensor.png")
+	info := writeFile(t, path, encodePNG(t, src))
+
+	res, err := New(0).Convert(path, info)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	img := decodeResult(t, res)
+	gray, ok := img.(*image.Gray)
+	if !ok {
+		t.Fatalf("resultado = %T, want *image.Gray", img)
+	}
+	// Faixa 1000..3000 esticada para 0..255
+	if want := []byte{0, 127, 255}; !bytes.Equal(gray.Pix, want) {
+		t.Errorf("pixels = %v, want %v", gray.Pix, want)
+	}
+}
+
+func TestConvert_PNG16RGBA(t *testing.T) {
+	src := image.NewNRGBA64(image.Rect(0, 0, 1, 1))
+	src.SetNRGBA64(0, 0, color.NRGBA64{R: 0xFFFF, G: 0x8000, B: 0, A: 0xFFFF})
+
+	path := filepath.Join(t.TempDir(), "render.png")
+	info := writeFile(t, path, encodePNG(t, src))
+
+	res, err := New(0).Convert(path, info)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	img := decodeResult(t, res)
+	switch img.(type) {
+	case *image.RGBA64, *image.NRGBA64:
+		t.Fatalf("resultado = %T, want 8 bits por canal", img)
+	}
+	if got := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); got.R != 255 || got.G != 128 || got.A != 255 {
+		t.Errorf("pixel = %v, want {255 128 0 255}", got)
+	}
+}
+
+func TestConvert_Passthrough(t *testing.T) {
+	dir := t.TempDir()
+	tr := New(0)
+
+	pngPath := filepath.Join(dir, "normal.png")
+	info := writeFile(t, pngPath, encodePNG(t, image.NewRGBA(image.Rect(0, 0, 2, 2))))
+	if res, err := tr.Convert(pngPath, info); res != nil || err != nil {
+		t.Errorf("Convert(PNG 8 bits) = %v, %v, want nil, nil", res, err)
+	}
+
+	jpgPath := filepath.Join(dir, "photo.jpg")
+	info = writeFile(t, jpgPath, []byte{0xFF, 0xD8, 0xFF, 0xDB, 0x00})
+	if res, err := tr.Convert(jpgPath, info); res != nil || err != nil {
+		t.Errorf("Convert(JPEG) = %v, %v, want nil, nil", res, err)
+	}
+}
+
+func TestConvert_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.tif")
+	info := writeFile(t, path, []byte("II*\x00\xFF\xFF\xFF\x00"))
+
+	if _, err := New(0).Convert(path, info); err == nil {
+		t.Error("Convert() de TIFF corrompido deveria falhar")
+	}
+}
+
+// hugePNG16 monta um PNG RGBA de 16 bits cujo IHDR declara w×h, com um IDAT
+// mínimo: poucos bytes no disco que pediriam gigabytes ao decodificar
+func hugePNG16(w, h uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	chunk := func(typ string, data []byte) {
+		binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		buf.WriteString(typ)
+		buf.Write(data)
+		binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+	}
+	ihdr := binary.BigEndian.AppendUint32(nil, w)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, h)
+	ihdr = append(ihdr, 16, 6, 0, 0, 0) // 16 bits, RGBA
+	chunk("IHDR", ihdr)
+	chunk("IDAT", []byte{0x78, 0x9c, 0x03, 0x00, 0x00, 0x00, 0x00, 0x01})
+	chunk("IEND", nil)
+	return buf.Bytes()
+}
+
+func TestConvert_TooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "huge.png")
+	info := writeFile(t, path, hugePNG16(40000, 40000))
+
+	if _, err := New(0).Convert(path, info); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Convert() error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	dir := t.TempDir()
+	tr := New(0)
+
+	path := filepath.Join(dir, "logo.svg")
+	info := writeFile(t, path, []byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><rect/></svg>`))
+	res, err := tr.Sanitize(path, info)
+	if err != nil {
+		t.Fatalf("Sanitize() error = %v", err)
+	}
+	if res.ContentType != "image/svg+xml" || bytes.Contains(res.Data, []byte("onload")) || !bytes.Contains(res.Data, []byte("<rect>")) {
+		t.Errorf("Sanitize() = %s %q", res.ContentType, res.Data)
+	}
+	if again, _ := tr.Sanitize(path, info); again != res {
+		t.Error("segunda limpeza deveria vir do cache")
+	}
+
+	broken := filepath.Join(dir, "broken.svg")
+	info = writeFile(t, broken, []byte(`<svg><g></svg>`))
+	if _, err := tr.Sanitize(broken, info); err == nil {
+		t.Error("Sanitize() de SVG malformado deveria falhar")
+	}
+}
+
+func TestConvert_CacheInvalidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.tif")
+	info := writeFile(t, path, grayTIFF(1, 1, []byte{10}))
+
+	tr := New(0)
+	first, err := tr.Convert(path, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := tr.Convert(path, info)
+	if again != first {
+		t.Error("segunda conversão deveria vir do cache")
+	}
+
+	// Novo conteúdo com outro mtime gera nova conversão
+	info = writeFile(t, path, grayTIFF(1, 1, []byte{200}))
+	later := info.ModTime().Add(time.Second)
+	os.Chtimes(path, later, later)
+	info, _ = os.Stat(path)
+
+	updated, err := tr.Convert(path, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated == first {
+		t.Fatal("conversão após modificação não deveria vir do cache")
+	}
+	img := decodeResult(t, updated)
+	if got := color.GrayModel.Convert(img.At(0, 0)).(color.Gray).Y; got != 200 {
+		t.Errorf("pixel = %d, want 200", got)
+	}
+	if tr.cache.Len() != 1 {
+		t.Errorf("cache tem %d entradas, want 1 (versão antiga descartada)", tr.cache.Len())
+	}
+}
+
+func TestCache_Eviction(t *testing.T) {
+	c := NewCache(10)
+	res := func(n int) *Result { return &Result{Data: make([]byte, n)} }
+
+	c.Put(Key{Path: "a"}, res(4))
+	c.Put(Key{Path: "b"}, res(4))
+	c.Get(Key{Path: "a"}) // "a" passa a ser o mais recente
+	c.Put(Key{Path: "c"}, res(4))
+
+	if _, ok := c.Get(Key{Path: "b"}); ok {
+		t.Error("b deveria ter sido descartado (menos usado)")
+	}
+	if _, ok := c.Get(Key{Path: "a"}); !ok {
+		t.Error("a deveria continuar em cache")
+	}
+
+	c.Put(Key{Path: "huge"}, res(11))
+	if _, ok := c.Get(Key{Path: "huge"}); ok {
+		t.Error("resultado maior que o limite não deveria ser armazenado")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
@@ -0,0 +1,43 @@
+package watcher
+
+import "github.com/fsnotify/fsnotify"
+
+// backend é a fonte de eventos do sistema de arquivos usada pelo ImageWatcher.
+// Todas as implementações produzem eventos fsnotify com a mesma semântica
+// (Create, Write, Remove, Rename), tratados por handleEvent.
+type backend interface {
+	// Add passa a monitorar o diretório (não recursivo)
+	Add(path string) error
+
+	// Remove deixa de monitorar o diretório
+	Remove(path string) error
+
+	// Events retorna o canal de eventos
+	Events() <-chan fsnotify.Event
+
+	// Errors retorna o canal de erros
+	Errors() <-chan error
+
+	// Close encerra o backend e fecha os canais
+	Close() error
+}
+
+// fsnotifyBackend usa notificações do kernel (inotify, kqueue, ReadDirectoryChangesW)
+type fsnotifyBackend struct {
+	w *fsnotify.Watcher
+}
+
+// newFsnotifyBackend cria um backend baseado em fsnotify
+func newFsnotifyBackend() (*fsnotifyBackend, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &fsnotifyBackend{w: w}, nil
+}
+
+func (b *fsnotifyBackend) Add(path string) error         { return b.w.Add(path) }
+func (b *fsnotifyBackend) Remove(path string) error      { return b.w.Remove(path) }
+func (b *fsnotifyBackend) Events() <-chan fsnotify.Event { return b.w.Events }
+func (b *fsnotifyBackend) Errors() <-chan error          { return b.w.Errors }
+func (b *fsnotifyBackend) Close() error                  { return b.w.Close() }
@@ -0,0 +1,15 @@
+package watcher
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// changeTime retorna o ctime (última mudança de status) do arquivo
+func changeTime(info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctim.Unix())
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package watcher
+
+import (
+	"os"
+	"time"
+)
+
+// changeTime usa o mtime em plataformas sem suporte específico
+func changeTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
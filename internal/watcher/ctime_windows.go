@@ -0,0 +1,16 @@
+package watcher
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// changeTime retorna a data de criação do arquivo, que no Windows reflete o
+// momento da cópia (o equivalente útil do ctime)
+func changeTime(info os.FileInfo) time.Time {
+	if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, data.CreationTime.Nanoseconds())
+	}
+	return info.ModTime()
+}
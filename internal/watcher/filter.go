@@ -0,0 +1,139 @@
+package watcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/verseles/sidelook/internal/imagetype"
+)
+
+// globSet é um conjunto de globs de linha de comando (--include/--exclude).
+// Padrões sem barra casam com o nome do arquivo em qualquer nível; padrões com
+// barra casam com o caminho relativo ao diretório monitorado. "**" é suportado.
+type globSet []*regexp.Regexp
+
+// compileGlobs compila os padrões informados
+func compileGlobs(patterns []string) (globSet, error) {
+	var set globSet
+	for _, pattern := range patterns {
+		p := strings.TrimPrefix(filepath.ToSlash(pattern), "/")
+		if p == "" {
+			continue
+		}
+
+		expr := "^"
+		if !strings.Contains(p, "/") {
+			expr += "(?:.*/)?"
+		}
+		expr += globToRegexp(p) + "$"
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("padrão inválido %q: %w", pattern, err)
+		}
+		set = append(set, re)
+	}
+	return set, nil
+}
+
+// Match indica se o caminho relativo casa com algum dos padrões
+func (g globSet) Match(rel string) bool {
+	for _, re := range g {
+		if re.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeExtension converte "avif", ".AVIF" ou "*.avif" em ".avif"
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	ext = strings.TrimPrefix(ext, "*")
+	if ext == "" {
+		return ""
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// buildExtensions combina SupportedExtensions com as extensões extras
+func buildExtensions(extra []string) map[string]bool {
+	exts := make(map[string]bool, len(SupportedExtensions)+len(extra))
+	for ext := range SupportedExtensions {
+		exts[ext] = true
+	}
+	for _, ext := range extra {
+		if ext = normalizeExtension(ext); ext != "" {
+			exts[ext] = true
+		}
+	}
+	return exts
+}
+
+// hasImageExtension verifica a extensão de path contra as extensões do watcher
+func (iw *ImageWatcher) hasImageExtension(path string) bool {
+	return iw.extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// passesFilters aplica --include e --exclude (exclude tem prioridade)
+func (iw *ImageWatcher) passesFilters(path string) bool {
+	if len(iw.include) == 0 && len(iw.exclude) == 0 {
+		return true
+	}
+
+	// Padrões são relativos ao diretório de origem de cada arquivo
+	_, rel := iw.relIn(path)
+	if iw.exclude.Match(rel) {
+		return false
+	}
+	return len(iw.include) == 0 || iw.include.Match(rel)
+}
+
+// hasImageContent verifica, com Sniff, se o conteúdo de path é uma imagem
+// reconhecida. Extensões extras de formatos que não podem ser detectados pelo
+// conteúdo (ex: --ext heic) continuam valendo pela extensão.
+func (iw *ImageWatcher) hasImageContent(path string) bool {
+	if !iw.sniff {
+		return true
+	}
+	if iw.sniffFormat(path) != "" {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	return iw.extensions[ext] && imagetype.FromExtension(ext) == ""
+}
+
+// sniffFormat identifica o formato de path pelos primeiros bytes
+func (iw *ImageWatcher) sniffFormat(path string) imagetype.Format {
+	format, err := imagetype.DetectFile(path)
+	if err != nil {
+		return ""
+	}
+	return format
+}
+
+// ContentType retorna o tipo MIME de path identificado pelo conteúdo. Sem
+// Sniff, ou se o formato não for reconhecido, retorna "" e o tipo deve ser
+// deduzido da extensão.
+func (iw *ImageWatcher) ContentType(path string) string {
+	if !iw.sniff {
+		return ""
+	}
+	return iw.sniffFormat(path).MIME()
+}
+
+// isComplete verifica se path foi escrito por completo, usando o formato
+// detectado pelo conteúdo quando Sniff está ativo
+func (iw *ImageWatcher) isComplete(path string) bool {
+	if iw.sniff {
+		if format := iw.sniffFormat(path); format != "" {
+			return isCompleteAs(path, format.Extension())
+		}
+	}
+	return isComplete(path)
+}
@@ -0,0 +1,157 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGlobSet(t *testing.T) {
+	set, err := compileGlobs([]string{"*_final.png", "renders/**/*.jpg", "shots/*.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		rel      string
+		expected bool
+	}{
+		{"a_final.png", true},
+		{"sub/dir/a_final.png", true},
+		{"a_draft.png", false},
+		{"renders/x.jpg", true},
+		{"renders/2026/run-1/x.jpg", true},
+		{"other/renders/x.jpg", false},
+		{"shots/a.png", true},
+		{"shots/sub/a.png", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			if got := set.Match(tt.rel); got != tt.expected {
+				t.Errorf("Match(%q) = %v, want %v", tt.rel, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestImageWatcher_Filters(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	names := []string{"a_final.png", "b_final.png", "b_final_mask.png", "c_draft.png", "d_final.avif"}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte{0x00}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	tests := []struct {
+		name      string
+		opts      Options
+		wantCount int
+		wantRel   string
+	}{
+		{"sem filtros", Options{}, 4, "c_draft.png"},
+		{"include", Options{Include: []string{"*_final*"}}, 3, "b_final_mask.png"},
+		{"include e exclude", Options{Include: []string{"*_final*"}, Exclude: []string{"*_mask.png"}}, 2, "b_final.png"},
+		{"extensão extra", Options{Include: []string{"*_final*"}, Exclude: []string{"*_mask.png"}, Extensions: []string{"AVIF"}}, 3, "d_final.avif"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewWithOptions(tmpDir, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Stop()
+
+			count, _, err := w.ScanExisting()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("ScanExisting() count = %d, want %d", count, tt.wantCount)
+			}
+			if got := w.CurrentImageRelative(); got != tt.wantRel {
+				t.Errorf("CurrentImageRelative() = %q, want %q", got, tt.wantRel)
+			}
+		})
+	}
+}
+
+func TestImageWatcher_Sniff(t *testing.T) {
+	tmpDir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xDB}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"render", png},             // sem extensão
+		{"photo.png", jpeg},         // extensão errada
+		{"notes.png", []byte("ok")}, // não é imagem
+		{"scan.heic", []byte("??")}, // extra não detectável
+		{"readme.txt", []byte("oi")},
+	}
+	for i, f := range files {
+		path := filepath.Join(tmpDir, f.name)
+		os.WriteFile(path, f.data, 0644)
+		mt := time.Now().Add(time.Duration(i-10) * time.Second)
+		os.Chtimes(path, mt, mt)
+	}
+
+	w, err := NewWithOptions(tmpDir, Options{Sniff: true, Extensions: []string{"heic"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	count, _, err := w.ScanExisting()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("ScanExisting() count = %d, want 3 (render, photo.png, scan.heic)", count)
+	}
+	if w.Accepts(filepath.Join(tmpDir, "notes.png")) {
+		t.Error("notes.png não deveria ser aceito com sniff")
+	}
+
+	if got := w.ContentType(filepath.Join(tmpDir, "photo.png")); got != "image/jpeg" {
+		t.Errorf("ContentType(photo.png) = %q, want image/jpeg", got)
+	}
+	if got := w.ContentType(filepath.Join(tmpDir, "render")); got != "image/png" {
+		t.Errorf("ContentType(render) = %q, want image/png", got)
+	}
+
+	// Deleções continuam detectadas mesmo sem conteúdo para inspecionar
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(tmpDir, "scan.heic"))
+	deadline := time.Now().Add(2 * time.Second)
+	for w.CurrentImageRelative() != "photo.png" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	os.Remove(filepath.Join(tmpDir, "photo.png"))
+	for w.CurrentImageRelative() != "render" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := w.CurrentImageRelative(); got != "render" {
+		t.Errorf("CurrentImageRelative() após deleções = %q, want render", got)
+	}
+
+	// Sem sniff o tipo vem da extensão
+	plain, _ := New(tmpDir)
+	defer plain.Stop()
+	if got := plain.ContentType(filepath.Join(tmpDir, "render")); got != "" {
+		t.Errorf("ContentType() sem sniff = %q, want vazio", got)
+	}
+}
@@ -0,0 +1,79 @@
+package watcher
+
+import (
+	"errors"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Health indica se o watcher está em sincronia com o disco
+type Health string
+
+const (
+	// HealthOK indica que os eventos estão chegando normalmente
+	HealthOK Health = "ok"
+
+	// HealthDegraded indica que o backend reportou erros e eventos podem ter
+	// sido perdidos. Volta a ok após a próxima reconciliação bem-sucedida.
+	HealthDegraded Health = "degraded"
+
+	// HealthRescan indica que uma reconciliação com o disco está em andamento
+	HealthRescan Health = "rescan"
+
+	// HealthWaiting indica que um diretório monitorado foi removido ou movido
+	// e o watcher aguarda ele voltar
+	HealthWaiting Health = "waiting"
+)
+
+// Status é a saúde do watcher com a descrição do último problema
+type Status struct {
+	Health  Health
+	Message string
+}
+
+// Status retorna a saúde atual do watcher
+func (iw *ImageWatcher) Status() Status {
+	iw.mu.RLock()
+	defer iw.mu.RUnlock()
+	return iw.status
+}
+
+// setStatus atualiza a saúde e chama OnHealthChanged se ela mudou
+func (iw *ImageWatcher) setStatus(st Status) {
+	iw.mu.Lock()
+	changed := iw.status != st
+	iw.status = st
+	iw.mu.Unlock()
+
+	if changed && iw.OnHealthChanged != nil {
+		iw.OnHealthChanged(st)
+	}
+}
+
+// idleStatus é a saúde sem problemas pendentes: ok, ou aguardando se alguma
+// raiz ainda não voltou
+func (iw *ImageWatcher) idleStatus() Status {
+	iw.mu.RLock()
+	defer iw.mu.RUnlock()
+
+	for _, src := range iw.sources {
+		if src.lost {
+			return Status{Health: HealthWaiting, Message: "diretório removido ou movido: " + src.dir()}
+		}
+	}
+	return Status{Health: HealthOK}
+}
+
+// handleError trata um erro do backend. Estouro da fila de eventos dispara
+// uma reconciliação completa; outros erros deixam o watcher degradado.
+func (iw *ImageWatcher) handleError(err error) {
+	if iw.OnError != nil {
+		iw.OnError(err)
+	}
+
+	if errors.Is(err, fsnotify.ErrEventOverflow) {
+		iw.rescan("fila de eventos do sistema estourou")
+		return
+	}
+	iw.setStatus(Status{Health: HealthDegraded, Message: err.Error()})
+}
@@ -0,0 +1,246 @@
+package watcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fakeBackend é um backend controlado pelo teste, que nunca gera eventos sozinho
+type fakeBackend struct {
+	events chan fsnotify.Event
+	errors chan error
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		events: make(chan fsnotify.Event),
+		errors: make(chan error),
+	}
+}
+
+func (b *fakeBackend) Add(string) error              { return nil }
+func (b *fakeBackend) Remove(string) error           { return nil }
+func (b *fakeBackend) Events() <-chan fsnotify.Event { return b.events }
+func (b *fakeBackend) Errors() <-chan error          { return b.errors }
+func (b *fakeBackend) Close() error                  { return nil }
+
+// healthRecorder registra as mudanças de saúde e os callbacks de imagem
+type healthRecorder struct {
+	mu       sync.Mutex
+	statuses []Health
+	errs     []error
+	images   []string
+	deleted  []string
+	done     chan struct{}
+}
+
+func watchHealth(w *ImageWatcher) *healthRecorder {
+	r := &healthRecorder{done: make(chan struct{}, 10)}
+	w.OnHealthChanged = func(st Status) {
+		r.mu.Lock()
+		r.statuses = append(r.statuses, st.Health)
+		r.mu.Unlock()
+		if st.Health != HealthRescan {
+			r.done <- struct{}{}
+		}
+	}
+	w.OnError = func(err error) {
+		r.mu.Lock()
+		r.errs = append(r.errs, err)
+		r.mu.Unlock()
+	}
+	w.OnNewImage = func(path string) {
+		r.mu.Lock()
+		r.images = append(r.images, path)
+		r.mu.Unlock()
+	}
+	w.OnImageDeleted = func(path string) {
+		r.mu.Lock()
+		r.deleted = append(r.deleted, path)
+		r.mu.Unlock()
+	}
+	return r
+}
+
+func (r *healthRecorder) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-r.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout aguardando mudança de saúde")
+	}
+}
+
+func TestImageWatcher_OverflowRescan(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+	write := func(name string, age time.Duration) {
+		path := filepath.Join(tmpDir, name)
+		os.WriteFile(path, []byte("x"), 0644)
+		os.Chtimes(path, now.Add(-age), now.Add(-age))
+	}
+	write("a.png", 3*time.Minute)
+	write("b.png", 2*time.Minute)
+
+	w, err := NewWithSlideshowCount(tmpDir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := newFakeBackend()
+	w.backend = fake
+	defer w.Stop()
+
+	if _, _, err := w.ScanExisting(); err != nil {
+		t.Fatal(err)
+	}
+	rec := watchHealth(w)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mudanças sem eventos: b some, c e d aparecem
+	os.Remove(filepath.Join(tmpDir, "b.png"))
+	write("c.png", 30*time.Second)
+	write("d.png", time.Minute)
+
+	fake.errors <- fsnotify.ErrEventOverflow
+	rec.wait(t)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if want := []Health{HealthRescan, HealthOK}; !reflect.DeepEqual(rec.statuses, want) {
+		t.Errorf("saúde = %v, want %v", rec.statuses, want)
+	}
+	if len(rec.errs) != 1 || !errors.Is(rec.errs[0], fsnotify.ErrEventOverflow) {
+		t.Errorf("OnError = %v, want [ErrEventOverflow]", rec.errs)
+	}
+	if want := []string{"c.png"}; !reflect.DeepEqual(rec.images, want) {
+		t.Errorf("OnNewImage = %v, want %v", rec.images, want)
+	}
+	if got := w.CurrentImageRelative(); got != "c.png" {
+		t.Errorf("CurrentImageRelative() = %s, want c.png", got)
+	}
+	if got, want := w.RecentImagesRelative(), []string{"c.png", "d.png", "a.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RecentImagesRelative() = %v, want %v", got, want)
+	}
+}
+
+func TestImageWatcher_OverflowRescan_Settle(t *testing.T) {
+	tmpDir := t.TempDir()
+	const settle = 200 * time.Millisecond
+	w, err := NewWithOptions(tmpDir, Options{Settle: settle, Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := newFakeBackend()
+	w.backend = fake
+	defer w.Stop()
+
+	rec := watchHealth(w)
+	published := make(chan string, 10)
+	w.OnNewImage = func(path string) {
+		published <- path
+	}
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Arquivos que surgiram sem eventos: um completo e um pela metade
+	pngHeader := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+	os.WriteFile(filepath.Join(tmpDir, "done.png"), append(append([]byte{}, pngHeader...), pngTrailer...), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "partial.png"), pngHeader, 0644)
+
+	start := time.Now()
+	fake.errors <- fsnotify.ErrEventOverflow
+	rec.wait(t)
+
+	// A reconciliação não publica na hora: espera a janela, como os eventos
+	select {
+	case got := <-published:
+		if got != "done.png" {
+			t.Errorf("OnNewImage = %q, want done.png", got)
+		}
+		if elapsed := time.Since(start); elapsed < settle {
+			t.Errorf("publicado após %v, antes da janela de %v", elapsed, settle)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout aguardando done.png")
+	}
+	select {
+	case got := <-published:
+		t.Errorf("OnNewImage chamado para arquivo incompleto: %q", got)
+	case <-time.After(2 * settle):
+	}
+}
+
+func TestImageWatcher_OverflowRescan_CurrentDeleted(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"a.png", "b.png"} {
+		path := filepath.Join(tmpDir, name)
+		os.WriteFile(path, []byte("x"), 0644)
+		mt := now.Add(time.Duration(i-10) * time.Second)
+		os.Chtimes(path, mt, mt)
+	}
+
+	w, err := New(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := newFakeBackend()
+	w.backend = fake
+	defer w.Stop()
+
+	w.ScanExisting()
+	rec := watchHealth(w)
+	w.Start()
+
+	os.Remove(filepath.Join(tmpDir, "b.png"))
+	fake.errors <- fsnotify.ErrEventOverflow
+	rec.wait(t)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.images) != 0 {
+		t.Errorf("OnNewImage = %v, want nenhuma", rec.images)
+	}
+	if want := []string{"a.png"}; !reflect.DeepEqual(rec.deleted, want) {
+		t.Errorf("OnImageDeleted = %v, want %v", rec.deleted, want)
+	}
+}
+
+func TestImageWatcher_ErrorDegrades(t *testing.T) {
+	w, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := newFakeBackend()
+	w.backend = fake
+	defer w.Stop()
+
+	rec := watchHealth(w)
+	w.Start()
+
+	fake.errors <- errors.New("limite de watches")
+	rec.wait(t)
+
+	st := w.Status()
+	if st.Health != HealthDegraded || st.Message != "limite de watches" {
+		t.Errorf("Status() = %+v, want degraded", st)
+	}
+
+	// Uma reconciliação bem-sucedida restaura a saúde
+	fake.errors <- fsnotify.ErrEventOverflow
+	rec.wait(t)
+	if st := w.Status(); st.Health != HealthOK {
+		t.Errorf("Status() após rescan = %+v, want ok", st)
+	}
+}
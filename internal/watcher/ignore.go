@@ -0,0 +1,241 @@
+package watcher
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName é o arquivo com padrões (sintaxe gitignore) a ignorar no diretório monitorado
+const IgnoreFileName = ".sidelookignore"
+
+// DefaultIgnorePatterns são padrões de arquivos temporários sempre ignorados:
+// downloads em andamento, arquivos de editores e temporários de escrita
+// atômica. Arquivos ocultos comuns (ex: .cover.png) continuam sendo exibidos.
+var DefaultIgnorePatterns = []string{
+	".tmp-*",
+	".~*",
+	"~*",
+	"*~",
+	"*.part",
+	"*.partial",
+	"*.crdownload",
+	"*.download",
+	"*.tmp",
+	"*.temp",
+	"*.swp",
+	"*.swx",
+}
+
+// defaultIgnore aplica DefaultIgnorePatterns com as mesmas regras do
+// .sidelookignore
+var defaultIgnore = parseIgnore(DefaultIgnorePatterns)
+
+// ignoreRule é uma linha de um arquivo .sidelookignore
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher aplica regras no estilo gitignore a caminhos relativos
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// loadIgnoreFile lê as regras de path. Retorna nil se o arquivo não existir.
+func loadIgnoreFile(path string) (*ignoreMatcher, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return parseIgnore(lines), nil
+}
+
+// parseIgnore converte linhas no formato gitignore em regras
+func parseIgnore(lines []string) *ignoreMatcher {
+	m := &ignoreMatcher{}
+
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// Padrões com barra são relativos à raiz; sem barra, valem em qualquer nível
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := "^"
+		if !anchored {
+			expr += "(?:.*/)?"
+		}
+		expr += globToRegexp(line) + "$"
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		m.rules = append(m.rules, rule)
+	}
+
+	return m
+}
+
+// globToRegexp traduz um glob gitignore (*, ?, **, [...]) para expressão regular
+func globToRegexp(glob string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				// "**/" casa zero ou mais diretórios; "**" no fim casa tudo
+				if i+2 < len(glob) && glob[i+2] == '/' {
+					b.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					b.WriteString(".*")
+					i++
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return b.String()
+}
+
+// Match indica se o caminho relativo (com barras normais) deve ser ignorado.
+// Como no git, um diretório ignorado ignora todo o seu conteúdo.
+func (m *ignoreMatcher) Match(rel string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+
+	// Verificar diretórios ancestrais primeiro
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchOne(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchOne(rel, isDir)
+}
+
+// matchOne aplica as regras a um único caminho; a última regra que casar vence
+func (m *ignoreMatcher) matchOne(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// reloadIgnore (re)carrega o .sidelookignore de um diretório monitorado
+func (iw *ImageWatcher) reloadIgnore(src *source) error {
+	m, err := loadIgnoreFile(filepath.Join(src.dir(), IgnoreFileName))
+
+	iw.mu.Lock()
+	src.ignore = m
+	iw.mu.Unlock()
+
+	return err
+}
+
+// ignored indica se path deve ser ignorado pelos padrões padrão ou pelo
+// .sidelookignore do seu diretório de origem
+func (iw *ImageWatcher) ignored(path string, isDir bool) bool {
+	if iw.isRoot(path) {
+		return false
+	}
+	src, rel := iw.relIn(path)
+	if defaultIgnore.Match(rel, isDir) {
+		return true
+	}
+	if src == nil {
+		return false
+	}
+
+	iw.mu.RLock()
+	m := src.ignore
+	iw.mu.RUnlock()
+
+	return m.Match(rel, isDir)
+}
+
+// Accepts indica se path é uma imagem aceita por este watcher: extensão
+// suportada (incluindo extras) ou, com Sniff, conteúdo de imagem reconhecido,
+// não ignorada, dentro dos filtros --include/--exclude e contida no seu
+// diretório monitorado (ver Confined)
+func (iw *ImageWatcher) Accepts(path string) bool {
+	return iw.acceptsName(path) && iw.confined(path) && iw.hasImageContent(path)
+}
+
+// acceptsName aplica as regras de Accepts que dependem apenas do caminho.
+// Usado em deleções, quando o conteúdo não pode mais ser lido.
+func (iw *ImageWatcher) acceptsName(path string) bool {
+	return (iw.sniff || iw.hasImageExtension(path)) &&
+		!iw.ignored(path, false) &&
+		iw.passesFilters(path)
+}
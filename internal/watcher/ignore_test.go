@@ -0,0 +1,147 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	m := parseIgnore([]string{
+		"# comentário",
+		"",
+		"*_mask.png",
+		"!keep_mask.png",
+		"drafts/",
+		"/root-only.png",
+		"logs/**/*.jpg",
+		"frame_[0-4].png",
+	})
+
+	tests := []struct {
+		rel      string
+		isDir    bool
+		expected bool
+	}{
+		{"a_mask.png", false, true},
+		{"sub/a_mask.png", false, true},
+		{"keep_mask.png", false, false},
+		{"final.png", false, false},
+		{"drafts", true, true},
+		{"drafts/x.png", false, true},
+		{"sub/drafts/x.png", false, true},
+		{"drafts", false, false}, // "drafts/" só casa diretórios
+		{"root-only.png", false, true},
+		{"sub/root-only.png", false, false},
+		{"logs/a.jpg", false, true},
+		{"logs/x/y/a.jpg", false, true},
+		{"logs/a.png", false, false},
+		{"frame_3.png", false, true},
+		{"frame_7.png", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			if got := m.Match(tt.rel, tt.isDir); got != tt.expected {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.rel, tt.isDir, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScanExisting_IgnoreFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ignore := "*_mask.png\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, IgnoreFileName), []byte(ignore), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{".cover.png", "render.png", "render.png.part", ".tmp-1.png", "render_mask.png"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	w, err := New(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	count, mostRecent, err := w.ScanExisting()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("ScanExisting() count = %d, want 2 (.cover.png e render.png)", count)
+	}
+	if mostRecent == nil || filepath.Base(mostRecent.Path) != "render.png" {
+		t.Errorf("ScanExisting() mostRecent = %v, want render.png", mostRecent)
+	}
+
+	if w.Accepts(filepath.Join(tmpDir, "other_mask.png")) {
+		t.Error("Accepts() deveria rejeitar arquivo ignorado pelo .sidelookignore")
+	}
+}
+
+func TestImageWatcher_TempFileRenamed(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	w, err := New(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	detected := make(chan string, 10)
+	w.OnNewImage = func(path string) {
+		detected <- path
+	}
+	deleted := make(chan string, 10)
+	w.OnImageDeleted = func(path string) {
+		deleted <- path
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// Escrita atômica: grava no temporário e renomeia para o nome final
+	tmp := filepath.Join(tmpDir, ".tmp-123.png")
+	if err := os.WriteFile(tmp, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	final := filepath.Join(tmpDir, "final.png")
+	if err := os.Rename(tmp, final); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case path := <-detected:
+		if path != "final.png" {
+			t.Errorf("OnNewImage path = %q, want final.png", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout aguardando imagem final")
+	}
+
+	select {
+	case path := <-detected:
+		t.Errorf("OnNewImage chamado novamente com %q", path)
+	case path := <-deleted:
+		t.Errorf("OnImageDeleted chamado com %q", path)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
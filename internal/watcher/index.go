@@ -0,0 +1,206 @@
+package watcher
+
+import "container/heap"
+
+// imageIndex mantém em memória todas as imagens conhecidas, indexadas por
+// caminho, em um heap ordenado pelo critério do watcher (mais recente no topo).
+// Construção é O(n), inserção e remoção O(log n) e as k mais recentes saem em
+// O(k log k), sem reler o disco.
+type imageIndex struct {
+	order  Order
+	items  []*indexEntry
+	byPath map[string]*indexEntry
+}
+
+// indexEntry é uma imagem do índice com a sua posição no heap
+type indexEntry struct {
+	img *ImageInfo
+	pos int
+}
+
+// newImageIndex cria um índice vazio com o critério de ordenação informado
+func newImageIndex(order Order) *imageIndex {
+	return &imageIndex{
+		order:  order,
+		byPath: make(map[string]*indexEntry),
+	}
+}
+
+// heap.Interface (max-heap segundo order.newer)
+func (x *imageIndex) Len() int           { return len(x.items) }
+func (x *imageIndex) Less(i, j int) bool { return x.order.newer(x.items[i].img, x.items[j].img) }
+
+func (x *imageIndex) Swap(i, j int) {
+	x.items[i], x.items[j] = x.items[j], x.items[i]
+	x.items[i].pos = i
+	x.items[j].pos = j
+}
+
+func (x *imageIndex) Push(v any) {
+	e := v.(*indexEntry)
+	e.pos = len(x.items)
+	x.items = append(x.items, e)
+}
+
+func (x *imageIndex) Pop() any {
+	n := len(x.items)
+	e := x.items[n-1]
+	x.items[n-1] = nil
+	x.items = x.items[:n-1]
+	return e
+}
+
+// build substitui o conteúdo do índice pelas imagens informadas em O(n)
+func (x *imageIndex) build(images []*ImageInfo) {
+	previous := x.byPath
+	x.items = make([]*indexEntry, 0, len(images))
+	x.byPath = make(map[string]*indexEntry, len(images))
+
+	for _, img := range images {
+		if old, ok := previous[img.Path]; ok {
+			keepHeader(old.img, img)
+		}
+		if e, ok := x.byPath[img.Path]; ok {
+			e.img = img
+			continue
+		}
+		e := &indexEntry{img: img, pos: len(x.items)}
+		x.items = append(x.items, e)
+		x.byPath[img.Path] = e
+	}
+	heap.Init(x)
+}
+
+// upsert insere ou atualiza uma imagem
+func (x *imageIndex) upsert(img *ImageInfo) {
+	if e, ok := x.byPath[img.Path]; ok {
+		keepHeader(e.img, img)
+		e.img = img
+		heap.Fix(x, e.pos)
+		return
+	}
+	e := &indexEntry{img: img}
+	x.byPath[img.Path] = e
+	heap.Push(x, e)
+}
+
+// replace troca old por img (mesmo caminho e ordenação) se old ainda for a
+// imagem indexada. Retorna false se ela foi atualizada ou removida.
+func (x *imageIndex) replace(old, img *ImageInfo) bool {
+	e, ok := x.byPath[old.Path]
+	if !ok || e.img != old {
+		return false
+	}
+	e.img = img
+	return true
+}
+
+// keepHeader copia para img o cabeçalho já lido de old se o arquivo não
+// mudou (img ainda não foi publicado)
+func keepHeader(old, img *ImageInfo) {
+	if img.Header == nil && old.Header != nil && old.Size == img.Size && old.ModTime.Equal(img.ModTime) {
+		img.Header = old.Header
+	}
+}
+
+// get retorna a imagem indexada em path
+func (x *imageIndex) get(path string) (*ImageInfo, bool) {
+	e, ok := x.byPath[path]
+	if !ok {
+		return nil, false
+	}
+	return e.img, true
+}
+
+// paths retorna os caminhos de todas as imagens indexadas (sem ordem definida)
+func (x *imageIndex) paths() []string {
+	paths := make([]string, 0, len(x.byPath))
+	for path := range x.byPath {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// remove retira path do índice. Retorna true se estava presente.
+func (x *imageIndex) remove(path string) bool {
+	e, ok := x.byPath[path]
+	if !ok {
+		return false
+	}
+	heap.Remove(x, e.pos)
+	delete(x.byPath, path)
+	return true
+}
+
+// removeUnder retira todas as imagens dentro de dir e retorna quantas saíram
+func (x *imageIndex) removeUnder(dir string) int {
+	var paths []string
+	for path := range x.byPath {
+		if isUnder(path, dir) {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		x.remove(path)
+	}
+	return len(paths)
+}
+
+// top retorna a imagem mais recente (nil se vazio)
+func (x *imageIndex) top() *ImageInfo {
+	if len(x.items) == 0 {
+		return nil
+	}
+	return x.items[0].img
+}
+
+// topK retorna até k imagens, da mais recente para a mais antiga, pulando as
+// que skip rejeitar. Percorre o heap em ordem sem modificá-lo, usando um heap
+// auxiliar de candidatos (os filhos de cada nó visitado).
+func (x *imageIndex) topK(k int, skip func(path string) bool) []*ImageInfo {
+	if k <= 0 || len(x.items) == 0 {
+		return nil
+	}
+
+	result := make([]*ImageInfo, 0, k)
+	frontier := &candidateHeap{index: x, positions: []int{0}}
+
+	for frontier.Len() > 0 && len(result) < k {
+		pos := heap.Pop(frontier).(int)
+		img := x.items[pos].img
+		if skip == nil || !skip(img.Path) {
+			result = append(result, img)
+		}
+		for _, child := range []int{2*pos + 1, 2*pos + 2} {
+			if child < len(x.items) {
+				heap.Push(frontier, child)
+			}
+		}
+	}
+	return result
+}
+
+// candidateHeap ordena posições do heap principal pelas suas imagens
+type candidateHeap struct {
+	index     *imageIndex
+	positions []int
+}
+
+func (c *candidateHeap) Len() int { return len(c.positions) }
+
+func (c *candidateHeap) Less(i, j int) bool {
+	return c.index.Less(c.positions[i], c.positions[j])
+}
+
+func (c *candidateHeap) Swap(i, j int) {
+	c.positions[i], c.positions[j] = c.positions[j], c.positions[i]
+}
+
+func (c *candidateHeap) Push(v any) { c.positions = append(c.positions, v.(int)) }
+
+func (c *candidateHeap) Pop() any {
+	n := len(c.positions)
+	v := c.positions[n-1]
+	c.positions = c.positions[:n-1]
+	return v
+}
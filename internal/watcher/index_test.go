@@ -0,0 +1,255 @@
+package watcher
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/verseles/sidelook/internal/imageinfo"
+)
+
+func infoPaths(images []*ImageInfo) []string {
+	paths := make([]string, 0, len(images))
+	for _, img := range images {
+		paths = append(paths, img.Path)
+	}
+	return paths
+}
+
+func TestImageIndex(t *testing.T) {
+	x := newImageIndex(OrderMtime)
+	now := time.Now()
+
+	var images []*ImageInfo
+	for i, p := range []string{"a", "b", "c", "d", "e"} {
+		images = append(images, &ImageInfo{Path: p, ModTime: now.Add(time.Duration(i) * time.Second)})
+	}
+	x.build(images)
+
+	if got := x.top().Path; got != "e" {
+		t.Fatalf("top() = %s, want e", got)
+	}
+	if got, want := infoPaths(x.topK(3, nil)), []string{"e", "d", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("topK(3) = %v, want %v", got, want)
+	}
+
+	// skip pula sem contar no limite
+	skip := func(p string) bool { return p == "d" }
+	if got, want := infoPaths(x.topK(3, skip)), []string{"e", "c", "b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("topK(3, skip d) = %v, want %v", got, want)
+	}
+
+	// Atualização reposiciona no heap
+	x.upsert(&ImageInfo{Path: "a", ModTime: now.Add(time.Minute)})
+	if got := x.top().Path; got != "a" {
+		t.Fatalf("top() após upsert = %s, want a", got)
+	}
+	if x.Len() != 5 {
+		t.Fatalf("Len() = %d, want 5", x.Len())
+	}
+
+	if !x.remove("a") || x.remove("a") {
+		t.Fatal("remove(a) deveria retornar true e depois false")
+	}
+	if got := x.top().Path; got != "e" {
+		t.Fatalf("top() após remove = %s, want e", got)
+	}
+	if _, ok := x.get("a"); ok {
+		t.Error("a não deveria estar no índice")
+	}
+}
+
+func TestImageIndex_RemoveUnder(t *testing.T) {
+	x := newImageIndex(OrderName)
+	sep := string(filepath.Separator)
+	x.build([]*ImageInfo{
+		{Path: sep + "r" + sep + "a.png"},
+		{Path: sep + "r" + sep + "sub" + sep + "b.png"},
+		{Path: sep + "r" + sep + "sub" + sep + "c.png"},
+		{Path: sep + "r" + sep + "subx.png"},
+	})
+
+	if n := x.removeUnder(sep + "r" + sep + "sub"); n != 2 {
+		t.Fatalf("removeUnder = %d, want 2", n)
+	}
+	want := []string{sep + "r" + sep + "subx.png", sep + "r" + sep + "a.png"}
+	if got := infoPaths(x.topK(10, nil)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restantes = %v, want %v", got, want)
+	}
+}
+
+// topK deve coincidir com uma ordenação completa, inclusive após atualizações
+// e remoções aleatórias
+func TestImageIndex_TopKMatchesSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	base := time.Unix(1700000000, 0)
+
+	x := newImageIndex(OrderMtime)
+	all := make(map[string]*ImageInfo)
+	var images []*ImageInfo
+	for i := 0; i < 500; i++ {
+		img := &ImageInfo{
+			Path:    fmt.Sprintf("img%03d.png", i),
+			ModTime: base.Add(time.Duration(rng.Intn(200)) * time.Second),
+		}
+		images = append(images, img)
+		all[img.Path] = img
+	}
+	x.build(images)
+
+	for i := 0; i < 200; i++ {
+		path := fmt.Sprintf("img%03d.png", rng.Intn(500))
+		if rng.Intn(2) == 0 {
+			img := &ImageInfo{Path: path, ModTime: base.Add(time.Duration(rng.Intn(200)) * time.Second)}
+			x.upsert(img)
+			all[path] = img
+		} else {
+			x.remove(path)
+			delete(all, path)
+		}
+	}
+
+	var expected []*ImageInfo
+	for _, img := range all {
+		expected = append(expected, img)
+	}
+	sort.Slice(expected, func(i, j int) bool {
+		return OrderMtime.newer(expected[i], expected[j])
+	})
+
+	for _, k := range []int{1, 10, 50, len(expected), len(expected) + 10} {
+		want := infoPaths(expected[:min(k, len(expected))])
+		if got := infoPaths(x.topK(k, nil)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("topK(%d) diverge da ordenação completa", k)
+		}
+	}
+}
+
+func TestImageWatcher_DeletionUsesIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"a.png", "b.png", "c.png"} {
+		path := filepath.Join(tmpDir, name)
+		os.WriteFile(path, []byte("x"), 0644)
+		mt := now.Add(time.Duration(i-10) * time.Second)
+		os.Chtimes(path, mt, mt)
+	}
+
+	w, err := NewWithSlideshowCount(tmpDir, 2)
+	if err != nil {
+		t.Fatalf("NewWithSlideshowCount() error = %v", err)
+	}
+	defer w.Stop()
+	if _, _, err := w.ScanExisting(); err != nil {
+		t.Fatalf("ScanExisting() error = %v", err)
+	}
+
+	// Arquivo que aparece no disco sem evento não entra no índice
+	os.WriteFile(filepath.Join(tmpDir, "z.png"), []byte("x"), 0644)
+
+	w.removeImage(filepath.Join(tmpDir, "c.png"))
+
+	if got := w.CurrentImageRelative(); got != "b.png" {
+		t.Errorf("CurrentImageRelative() = %s, want b.png", got)
+	}
+	if got, want := w.RecentImagesRelative(), []string{"b.png", "a.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RecentImagesRelative() = %v, want %v", got, want)
+	}
+}
+
+// createImages cria n arquivos PNG vazios em dir com mtimes distintos
+func createImages(b *testing.B, dir string, n int) {
+	b.Helper()
+	base := time.Now().Add(-time.Duration(n) * time.Second)
+	for i := 0; i < n; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("img%06d.png", i))
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			b.Fatal(err)
+		}
+		mt := base.Add(time.Duration(i) * time.Second)
+		os.Chtimes(path, mt, mt)
+	}
+}
+
+func BenchmarkScanExisting100k(b *testing.B) {
+	dir := b.TempDir()
+	createImages(b, dir, 100000)
+
+	w, err := NewWithSlideshowCount(dir, 10)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer w.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if count, _, err := w.ScanExisting(); err != nil || count != 100000 {
+			b.Fatalf("ScanExisting() = %d, %v", count, err)
+		}
+	}
+}
+
+func BenchmarkImageIndex100k(b *testing.B) {
+	base := time.Unix(1700000000, 0)
+	images := make([]*ImageInfo, 100000)
+	for i := range images {
+		images[i] = &ImageInfo{
+			Path:    fmt.Sprintf("img%06d.png", i),
+			ModTime: base.Add(time.Duration(i) * time.Second),
+		}
+	}
+
+	b.Run("build", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			newImageIndex(OrderMtime).build(images)
+		}
+	})
+
+	b.Run("remove-top+topK", func(b *testing.B) {
+		x := newImageIndex(OrderMtime)
+		x.build(images)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			top := x.top()
+			x.remove(top.Path)
+			x.topK(10, nil)
+			x.upsert(top)
+		}
+	})
+}
+
+func TestImageIndex_KeepHeader(t *testing.T) {
+	x := newImageIndex(OrderMtime)
+	now := time.Now()
+	header := &imageinfo.Info{Width: 10}
+
+	old := &ImageInfo{Path: "a", ModTime: now, Size: 100}
+	x.build([]*ImageInfo{old})
+	described := *old
+	described.Header = header
+	if !x.replace(old, &described) {
+		t.Fatal("replace() = false")
+	}
+	if x.replace(old, &described) {
+		t.Error("replace() de uma imagem que não está mais no índice deveria falhar")
+	}
+
+	// Mesmo arquivo (rescan): cabeçalho preservado
+	same := &ImageInfo{Path: "a", ModTime: now, Size: 100}
+	x.build([]*ImageInfo{same})
+	if same.Header != header {
+		t.Error("build() deveria preservar o cabeçalho de arquivo inalterado")
+	}
+
+	// Arquivo modificado: cabeçalho descartado
+	changed := &ImageInfo{Path: "a", ModTime: now.Add(time.Second), Size: 200}
+	x.upsert(changed)
+	if changed.Header != nil {
+		t.Error("upsert() não deveria preservar o cabeçalho de arquivo modificado")
+	}
+}
@@ -0,0 +1,206 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/verseles/sidelook/internal/exif"
+)
+
+// Order define o critério de "mais recente" usado no scan inicial, na escolha
+// da próxima imagem após deleções e no preenchimento do slideshow
+type Order string
+
+const (
+	// OrderMtime ordena pela data de modificação
+	OrderMtime Order = "mtime"
+
+	// OrderCtime ordena pela mudança de status (criação no Windows). Útil para
+	// arquivos copiados com cp -p, rsync ou extraídos com unzip, que mantêm o mtime antigo
+	OrderCtime Order = "ctime"
+
+	// OrderArrival ordena pelo momento em que o watcher viu o arquivo pela primeira vez
+	OrderArrival Order = "arrival"
+
+	// OrderName ordena pelo caminho (o último em ordem alfabética é o mais recente)
+	OrderName Order = "name"
+
+	// OrderNatural ordena pelo caminho comparando números pelo valor (frame_2 < frame_10)
+	OrderNatural Order = "natural"
+
+	// OrderExifDate ordena pela data de captura EXIF, com fallback para o mtime
+	OrderExifDate Order = "exif-date"
+)
+
+// Orders lista as estratégias de ordenação válidas
+var Orders = []Order{OrderMtime, OrderCtime, OrderArrival, OrderName, OrderNatural, OrderExifDate}
+
+// ParseOrder valida o nome de uma estratégia de ordenação ("" = mtime)
+func ParseOrder(s string) (Order, error) {
+	if s == "" {
+		return OrderMtime, nil
+	}
+	for _, o := range Orders {
+		if string(o) == strings.ToLower(s) {
+			return o, nil
+		}
+	}
+
+	names := make([]string, len(Orders))
+	for i, o := range Orders {
+		names[i] = string(o)
+	}
+	return "", fmt.Errorf("ordenação inválida: %q. Use %s", s, strings.Join(names, ", "))
+}
+
+// newer indica se a é mais recente que b. Empates são desfeitos pelo mtime e
+// depois pelo caminho, para que todos os clientes concordem sobre a imagem atual.
+func (o Order) newer(a, b *ImageInfo) bool {
+	switch o {
+	case OrderCtime:
+		if !a.ChangeTime.Equal(b.ChangeTime) {
+			return a.ChangeTime.After(b.ChangeTime)
+		}
+	case OrderArrival:
+		if a.Arrival != b.Arrival {
+			return a.Arrival > b.Arrival
+		}
+	case OrderName:
+		if a.Path != b.Path {
+			return a.Path > b.Path
+		}
+	case OrderNatural:
+		if c := naturalCompare(a.Path, b.Path); c != 0 {
+			return c > 0
+		}
+	case OrderExifDate:
+		ta, tb := a.takenOrModified(), b.takenOrModified()
+		if !ta.Equal(tb) {
+			return ta.After(tb)
+		}
+	}
+
+	if !a.ModTime.Equal(b.ModTime) {
+		return a.ModTime.After(b.ModTime)
+	}
+	return a.Path > b.Path
+}
+
+// takenOrModified retorna a data EXIF, ou o mtime se ela não existir
+func (img *ImageInfo) takenOrModified() time.Time {
+	if img.TakenAt.IsZero() {
+		return img.ModTime
+	}
+	return img.TakenAt
+}
+
+// newImageInfo monta o ImageInfo de path com os campos exigidos pela ordenação
+func (iw *ImageWatcher) newImageInfo(path string, info os.FileInfo) *ImageInfo {
+	img := &ImageInfo{
+		Path:       path,
+		ModTime:    info.ModTime(),
+		ChangeTime: changeTime(info),
+		Arrival:    iw.arrival(path),
+		Size:       info.Size(),
+	}
+
+	// Ler EXIF custa uma abertura de arquivo: só quando necessário
+	if iw.order == OrderExifDate {
+		if e, err := exif.ReadFile(path); err == nil {
+			img.TakenAt, _ = e.DateTime()
+		}
+	}
+
+	return img
+}
+
+// arrival retorna o número de chegada de path, atribuindo um novo se o
+// arquivo ainda não estiver no índice
+func (iw *ImageWatcher) arrival(path string) uint64 {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	if img, ok := iw.index.get(path); ok {
+		return img.Arrival
+	}
+	iw.arrivalSeq++
+	return iw.arrivalSeq
+}
+
+// assignArrivalByMtime renumera a chegada das imagens do scan inicial do
+// mais antigo para o mais recente mtime
+func (iw *ImageWatcher) assignArrivalByMtime(images []*ImageInfo) {
+	sorted := make([]*ImageInfo, len(images))
+	copy(sorted, images)
+	sort.Slice(sorted, func(i, j int) bool {
+		return OrderMtime.newer(sorted[j], sorted[i])
+	})
+
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	for _, img := range sorted {
+		iw.arrivalSeq++
+		img.Arrival = iw.arrivalSeq
+	}
+}
+
+// naturalCompare compara strings tratando sequências de dígitos pelo valor
+// numérico. Retorna -1, 0 ou 1.
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		ca, cb := a[0], b[0]
+
+		if isDigit(ca) && isDigit(cb) {
+			na, ra := splitDigits(a)
+			nb, rb := splitDigits(b)
+
+			// Comparar pelo valor: sem zeros à esquerda, mais dígitos = maior
+			ta, tb := strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if len(ta) != len(tb) {
+				return compareInt(len(ta), len(tb))
+			}
+			if ta != tb {
+				return strings.Compare(ta, tb)
+			}
+			// Mesmo valor: menos zeros à esquerda primeiro (2 < 02)
+			if len(na) != len(nb) {
+				return compareInt(len(na), len(nb))
+			}
+			a, b = ra, rb
+			continue
+		}
+
+		if ca != cb {
+			return compareInt(int(ca), int(cb))
+		}
+		a, b = a[1:], b[1:]
+	}
+	return compareInt(len(a), len(b))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// splitDigits separa o prefixo numérico de s
+func splitDigits(s string) (digits, rest string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
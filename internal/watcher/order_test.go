@@ -0,0 +1,264 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseOrder(t *testing.T) {
+	for _, o := range Orders {
+		if got, err := ParseOrder(string(o)); err != nil || got != o {
+			t.Errorf("ParseOrder(%q) = %q, %v", o, got, err)
+		}
+	}
+	if got, err := ParseOrder(""); err != nil || got != OrderMtime {
+		t.Errorf("ParseOrder(\"\") = %q, %v, want mtime", got, err)
+	}
+	if _, err := ParseOrder("size"); err == nil {
+		t.Error("ParseOrder(size) deveria retornar erro")
+	}
+}
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"frame_2.png", "frame_10.png", -1},
+		{"frame_10.png", "frame_2.png", 1},
+		{"frame_2.png", "frame_2.png", 0},
+		{"frame_02.png", "frame_2.png", 1},
+		{"frame_9.png", "frame_009.png", -1},
+		{"a.png", "b.png", -1},
+		{"run-2/frame_1.png", "run-10/frame_1.png", -1},
+		{"img", "img1", -1},
+		{"frame_99999999999999999999.png", "frame_100000000000000000000.png", -1},
+	}
+
+	for _, tt := range tests {
+		if got := naturalCompare(tt.a, tt.b); got != tt.want {
+			t.Errorf("naturalCompare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestOrder_TieBreak(t *testing.T) {
+	now := time.Now()
+	a := &ImageInfo{Path: "/d/a.png", ModTime: now, ChangeTime: now, Arrival: 1}
+	b := &ImageInfo{Path: "/d/b.png", ModTime: now, ChangeTime: now, Arrival: 1}
+
+	// Com chave e mtime iguais, o caminho decide de forma estável
+	for _, o := range Orders {
+		if !o.newer(b, a) || o.newer(a, b) {
+			t.Errorf("%s: desempate inconsistente entre %s e %s", o, a.Path, b.Path)
+		}
+	}
+}
+
+func TestScanExisting_Orders(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	old := time.Now().Add(-24 * time.Hour)
+
+	// frame_10 é criado por último, mas com mtime antigo (como cp -p)
+	for _, name := range []string{"frame_2.png", "frame_9.png", "frame_10.png"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := os.Chtimes(filepath.Join(tmpDir, "frame_10.png"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		order Order
+		want  string
+	}{
+		{OrderMtime, "frame_9.png"},
+		{OrderCtime, "frame_10.png"},
+		{OrderArrival, "frame_9.png"},
+		{OrderName, "frame_9.png"},
+		{OrderNatural, "frame_10.png"},
+		{OrderExifDate, "frame_9.png"}, // sem EXIF: cai para o mtime
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			w, err := NewWithOptions(tmpDir, Options{Order: tt.order})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Stop()
+
+			if _, _, err := w.ScanExisting(); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.CurrentImageRelative(); got != tt.want {
+				t.Errorf("CurrentImageRelative() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageWatcher_ArrivalOrderAfterDeletion(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "a.png"), []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWithOptions(tmpDir, Options{Order: OrderArrival})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if _, _, err := w.ScanExisting(); err != nil {
+		t.Fatal(err)
+	}
+
+	detected := make(chan string, 10)
+	w.OnNewImage = func(path string) {
+		detected <- path
+	}
+	deleted := make(chan string, 10)
+	w.OnImageDeleted = func(path string) {
+		deleted <- path
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	wait := func(ch chan string, want string) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case got := <-ch:
+				if got == want {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("Timeout aguardando %q", want)
+			}
+		}
+	}
+
+	// "extraido.png" chega depois, mas com mtime antigo (como unzip)
+	extracted := filepath.Join(tmpDir, "extraido.png")
+	if err := os.WriteFile(extracted, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(extracted, old, old); err != nil {
+		t.Fatal(err)
+	}
+	wait(detected, "extraido.png")
+
+	last := filepath.Join(tmpDir, "ultima.png")
+	if err := os.WriteFile(last, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	wait(detected, "ultima.png")
+
+	// Ao deletar a atual, a próxima é a que chegou por último, não a de maior mtime
+	if err := os.Remove(last); err != nil {
+		t.Fatal(err)
+	}
+	wait(deleted, "extraido.png")
+}
+
+func TestImageWatcher_LiveUpdatesFollowOrder(t *testing.T) {
+	old := time.Now().Add(-24 * time.Hour)
+
+	tests := []struct {
+		name  string
+		order Order
+		older func(path string) // Cria uma imagem que não deve virar a atual
+	}{
+		{"name", OrderName, func(path string) {
+			os.WriteFile(path, []byte{0x89, 0x50, 0x4E, 0x47}, 0644)
+		}},
+		{"mtime", OrderMtime, func(path string) {
+			// Movida para dentro com mtime antigo (como unzip ou cp -p)
+			staging := filepath.Join(t.TempDir(), filepath.Base(path))
+			os.WriteFile(staging, []byte{0x89, 0x50, 0x4E, 0x47}, 0644)
+			os.Chtimes(staging, old, old)
+			os.Rename(staging, path)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			os.WriteFile(filepath.Join(tmpDir, "b.png"), []byte{0x89, 0x50, 0x4E, 0x47}, 0644)
+
+			w, err := NewWithOptions(tmpDir, Options{Order: tt.order, SlideshowCount: 3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Stop()
+			if _, _, err := w.ScanExisting(); err != nil {
+				t.Fatal(err)
+			}
+
+			detected := make(chan string, 10)
+			w.OnNewImage = func(path string) {
+				detected <- path
+			}
+			changes := make(chan []string, 10)
+			w.OnRecentChanged = func(paths []string) {
+				changes <- paths
+			}
+			if err := w.Start(); err != nil {
+				t.Fatal(err)
+			}
+
+			// a.png entra no slideshow atrás de b.png, sem trocar a atual
+			tt.older(filepath.Join(tmpDir, "a.png"))
+			select {
+			case got := <-changes:
+				if len(got) != 2 || got[0] != "b.png" || got[1] != "a.png" {
+					t.Errorf("recentes = %v, want [b.png a.png]", got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timeout aguardando a lista de recentes")
+			}
+			select {
+			case got := <-detected:
+				t.Errorf("OnNewImage(%q) para imagem mais antiga que a atual", got)
+			case <-time.After(300 * time.Millisecond):
+			}
+			if got := w.CurrentImageRelative(); got != "b.png" {
+				t.Errorf("CurrentImageRelative() = %q, want b.png", got)
+			}
+
+			// c.png é a mais recente pelos dois critérios
+			os.WriteFile(filepath.Join(tmpDir, "c.png"), []byte{0x89, 0x50, 0x4E, 0x47}, 0644)
+			select {
+			case got := <-detected:
+				if got != "c.png" {
+					t.Errorf("OnNewImage(%q), want c.png", got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timeout aguardando c.png")
+			}
+			if got := w.CurrentImageRelative(); got != "c.png" {
+				t.Errorf("CurrentImageRelative() = %q, want c.png", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultPollInterval é o intervalo usado quando o fsnotify não está disponível
+const DefaultPollInterval = 2 * time.Second
+
+// fileState é o estado de uma entrada de diretório entre duas varreduras
+type fileState struct {
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// pollBackend detecta mudanças comparando listagens periódicas dos diretórios.
+// Funciona em sistemas de arquivos que não geram notificações (NFS, SMB,
+// sshfs, alguns bind mounts do Docker).
+type pollBackend struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	dirs map[string]map[string]fileState // diretório -> nome -> estado
+
+	events    chan fsnotify.Event
+	errors    chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newPollBackend cria um backend de polling com o intervalo informado
+func newPollBackend(interval time.Duration) *pollBackend {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	b := &pollBackend{
+		interval: interval,
+		dirs:     make(map[string]map[string]fileState),
+		events:   make(chan fsnotify.Event, 256),
+		errors:   make(chan error, 16),
+		done:     make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// Add registra o diretório com o estado atual, para que arquivos já
+// existentes não sejam reportados como criados
+func (b *pollBackend) Add(path string) error {
+	snapshot, err := listDir(path)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.dirs[path] = snapshot
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *pollBackend) Remove(path string) error {
+	b.mu.Lock()
+	delete(b.dirs, path)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *pollBackend) Events() <-chan fsnotify.Event { return b.events }
+func (b *pollBackend) Errors() <-chan error          { return b.errors }
+
+func (b *pollBackend) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+	return nil
+}
+
+func (b *pollBackend) loop() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.poll()
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// poll varre todos os diretórios registrados e emite os eventos encontrados
+func (b *pollBackend) poll() {
+	b.mu.Lock()
+	dirs := make([]string, 0, len(b.dirs))
+	for dir := range b.dirs {
+		dirs = append(dirs, dir)
+	}
+	b.mu.Unlock()
+
+	// Pais antes dos filhos, para que a remoção de um subdiretório seja
+	// reportada antes dos seus arquivos
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		current, err := listDir(dir)
+
+		b.mu.Lock()
+		previous, ok := b.dirs[dir]
+		if !ok {
+			// Removido (Remove) durante a varredura
+			b.mu.Unlock()
+			continue
+		}
+
+		var events []fsnotify.Event
+		if err != nil {
+			if !os.IsNotExist(err) {
+				b.mu.Unlock()
+				b.sendError(err)
+				continue
+			}
+			// O próprio diretório sumiu: reportar os filhos e o diretório,
+			// como o inotify faz com IN_DELETE_SELF
+			for _, name := range sortedNames(previous) {
+				events = append(events, fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove})
+			}
+			events = append(events, fsnotify.Event{Name: dir, Op: fsnotify.Remove})
+			delete(b.dirs, dir)
+		} else {
+			events = diffDir(dir, previous, current)
+			b.dirs[dir] = current
+		}
+		b.mu.Unlock()
+
+		for _, event := range events {
+			select {
+			case b.events <- event:
+			case <-b.done:
+				return
+			}
+		}
+	}
+}
+
+func (b *pollBackend) sendError(err error) {
+	select {
+	case b.errors <- err:
+	case <-b.done:
+	}
+}
+
+// diffDir compara duas listagens e gera Create, Write e Remove
+func diffDir(dir string, previous, current map[string]fileState) []fsnotify.Event {
+	var events []fsnotify.Event
+
+	for _, name := range sortedNames(current) {
+		cur := current[name]
+		prev, existed := previous[name]
+		path := filepath.Join(dir, name)
+
+		switch {
+		case !existed || prev.isDir != cur.isDir:
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		case !cur.isDir && (prev.size != cur.size || !prev.modTime.Equal(cur.modTime)):
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+	}
+
+	for _, name := range sortedNames(previous) {
+		if _, ok := current[name]; !ok {
+			events = append(events, fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove})
+		}
+	}
+
+	return events
+}
+
+// listDir lê o estado (tamanho, mtime, tipo) das entradas de um diretório
+func listDir(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// Removido entre a listagem e o stat
+			continue
+		}
+		states[entry.Name()] = fileState{
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			isDir:   entry.IsDir(),
+		}
+	}
+	return states, nil
+}
+
+func sortedNames(states map[string]fileState) []string {
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
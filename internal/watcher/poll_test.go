@@ -0,0 +1,113 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestDiffDir(t *testing.T) {
+	t0 := time.Now()
+	previous := map[string]fileState{
+		"same.png":    {size: 10, modTime: t0},
+		"changed.png": {size: 10, modTime: t0},
+		"touched.png": {size: 10, modTime: t0},
+		"gone.png":    {size: 10, modTime: t0},
+		"sub":         {isDir: true, modTime: t0},
+	}
+	current := map[string]fileState{
+		"same.png":    {size: 10, modTime: t0},
+		"changed.png": {size: 20, modTime: t0},
+		"touched.png": {size: 10, modTime: t0.Add(time.Second)},
+		"new.png":     {size: 5, modTime: t0},
+		"sub":         {isDir: true, modTime: t0.Add(time.Second)}, // mtime de diretório não gera evento
+	}
+
+	got := diffDir("d", previous, current)
+	want := []fsnotify.Event{
+		{Name: filepath.Join("d", "changed.png"), Op: fsnotify.Write},
+		{Name: filepath.Join("d", "new.png"), Op: fsnotify.Create},
+		{Name: filepath.Join("d", "touched.png"), Op: fsnotify.Write},
+		{Name: filepath.Join("d", "gone.png"), Op: fsnotify.Remove},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffDir() = %v, want %v", got, want)
+	}
+}
+
+func TestImageWatcher_Polling(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	img1 := filepath.Join(tmpDir, "img1.png")
+	if err := os.WriteFile(img1, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWithOptions(tmpDir, Options{PollInterval: 50 * time.Millisecond, Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if !w.Polling() {
+		t.Fatal("Polling() = false, want true")
+	}
+
+	if _, _, err := w.ScanExisting(); err != nil {
+		t.Fatal(err)
+	}
+
+	detected := make(chan string, 10)
+	w.OnNewImage = func(path string) {
+		detected <- path
+	}
+	deleted := make(chan string, 10)
+	w.OnImageDeleted = func(path string) {
+		deleted <- path
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Imagem em subdiretório novo
+	sub := filepath.Join(tmpDir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	img2 := filepath.Join(sub, "img2.png")
+	if err := os.WriteFile(img2, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case path := <-detected:
+		if path != "sub/img2.png" {
+			t.Errorf("OnNewImage path = %q, want sub/img2.png", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout aguardando detecção por polling")
+	}
+
+	// Deleção da imagem atual
+	if err := os.Remove(img2); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case path := <-deleted:
+		if path != "img1.png" {
+			t.Errorf("OnImageDeleted path = %q, want img1.png", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout aguardando deleção por polling")
+	}
+}
@@ -0,0 +1,155 @@
+package watcher
+
+import "sort"
+
+// recentIndex mantém as N imagens mais recentes, ordenadas (mais recente
+// primeiro) e indexadas por caminho, sem duplicatas
+type recentIndex struct {
+	max    int
+	items  []*ImageInfo
+	byPath map[string]*ImageInfo
+}
+
+// newRecentIndex cria um índice com capacidade max (0 = desabilitado)
+func newRecentIndex(max int) *recentIndex {
+	return &recentIndex{
+		max:    max,
+		items:  make([]*ImageInfo, 0, max),
+		byPath: make(map[string]*ImageInfo, max),
+	}
+}
+
+// reset substitui o conteúdo pelas primeiras imagens de images (já ordenadas)
+func (r *recentIndex) reset(images []*ImageInfo) {
+	r.items = r.items[:0]
+	r.byPath = make(map[string]*ImageInfo, r.max)
+	for _, img := range images {
+		if len(r.items) >= r.max {
+			break
+		}
+		if _, dup := r.byPath[img.Path]; dup {
+			continue
+		}
+		r.items = append(r.items, img)
+		r.byPath[img.Path] = img
+	}
+}
+
+// insert coloca img (nova ou reescrita) na sua posição segundo newer e
+// descarta o excedente. Retorna true se a lista mudou; a reescrita da
+// primeira imagem que continua primeira não conta, pois chega como a atual.
+func (r *recentIndex) insert(img *ImageInfo, newer func(a, b *ImageInfo) bool) bool {
+	if r.max <= 0 {
+		return false
+	}
+
+	old := r.indexOf(img.Path)
+	r.removeAt(old)
+
+	i := sort.Search(len(r.items), func(i int) bool {
+		return newer(img, r.items[i])
+	})
+	if i >= r.max {
+		return false // Mais antiga que todas de uma lista cheia
+	}
+	r.items = append(r.items, nil)
+	copy(r.items[i+1:], r.items[i:])
+	r.items[i] = img
+	r.byPath[img.Path] = img
+	if old == 0 && i == 0 {
+		return false
+	}
+
+	for len(r.items) > r.max {
+		last := r.items[len(r.items)-1]
+		delete(r.byPath, last.Path)
+		r.items = r.items[:len(r.items)-1]
+	}
+	return true
+}
+
+// remove retira path do índice. Retorna true se estava presente.
+func (r *recentIndex) remove(path string) bool {
+	i := r.indexOf(path)
+	r.removeAt(i)
+	return i >= 0
+}
+
+// removeUnder retira todas as imagens dentro de dir. Retorna true se alguma saiu.
+func (r *recentIndex) removeUnder(dir string) bool {
+	kept := r.items[:0]
+	for _, img := range r.items {
+		if isUnder(img.Path, dir) {
+			delete(r.byPath, img.Path)
+			continue
+		}
+		kept = append(kept, img)
+	}
+	changed := len(kept) != len(r.items)
+	r.items = kept
+	return changed
+}
+
+// fill completa o índice (no fim) com candidatos que ainda não estão nele,
+// do mais recente para o mais antigo segundo newer. Retorna true se algo foi adicionado.
+func (r *recentIndex) fill(candidates []*ImageInfo, newer func(a, b *ImageInfo) bool) bool {
+	if len(r.items) >= r.max {
+		return false
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return newer(candidates[i], candidates[j])
+	})
+
+	added := false
+	for _, img := range candidates {
+		if len(r.items) >= r.max {
+			break
+		}
+		if _, ok := r.byPath[img.Path]; ok {
+			continue
+		}
+		r.items = append(r.items, img)
+		r.byPath[img.Path] = img
+		added = true
+	}
+	return added
+}
+
+// contains indica se path está no índice
+func (r *recentIndex) contains(path string) bool {
+	_, ok := r.byPath[path]
+	return ok
+}
+
+// full indica se o índice atingiu a capacidade
+func (r *recentIndex) full() bool {
+	return len(r.items) >= r.max
+}
+
+// list retorna uma cópia das imagens, da mais recente para a mais antiga
+func (r *recentIndex) list() []*ImageInfo {
+	result := make([]*ImageInfo, len(r.items))
+	copy(result, r.items)
+	return result
+}
+
+func (r *recentIndex) indexOf(path string) int {
+	if _, ok := r.byPath[path]; !ok {
+		return -1
+	}
+	for i, img := range r.items {
+		if img.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *recentIndex) removeAt(i int) {
+	if i < 0 {
+		return
+	}
+	delete(r.byPath, r.items[i].Path)
+	r.items = append(r.items[:i], r.items[i+1:]...)
+}
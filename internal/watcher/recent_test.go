@@ -0,0 +1,176 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func recentPaths(r *recentIndex) []string {
+	paths := make([]string, 0, len(r.items))
+	for _, img := range r.items {
+		paths = append(paths, img.Path)
+	}
+	return paths
+}
+
+func TestRecentIndex(t *testing.T) {
+	r := newRecentIndex(3)
+	now := time.Now()
+
+	at := func(path string, sec int) *ImageInfo {
+		return &ImageInfo{Path: path, ModTime: now.Add(time.Duration(sec) * time.Second)}
+	}
+	insert := func(img *ImageInfo) bool {
+		return r.insert(img, OrderMtime.newer)
+	}
+
+	for i, p := range []string{"a", "b", "c"} {
+		insert(at(p, i))
+	}
+	if got, want := recentPaths(r), []string{"c", "b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("após inserções = %v, want %v", got, want)
+	}
+
+	// Reescrita move para o início sem duplicar
+	if !insert(at("a", 5)) {
+		t.Error("insert(a) deveria reportar mudança")
+	}
+	if got, want := recentPaths(r), []string{"a", "c", "b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("após reescrita = %v, want %v", got, want)
+	}
+
+	// Reescrita da primeira não muda a lista
+	if insert(at("a", 6)) {
+		t.Error("insert(a) repetido não deveria reportar mudança")
+	}
+
+	// Imagem mais antiga que todas de uma lista cheia fica de fora
+	if insert(at("old", -10)) || r.contains("old") {
+		t.Errorf("insert(old) não deveria entrar na lista: %v", recentPaths(r))
+	}
+
+	// Imagem no meio da ordem entra na sua posição e descarta a mais antiga
+	if !insert(at("m", 3)) {
+		t.Error("insert(m) deveria reportar mudança")
+	}
+	if got, want := recentPaths(r), []string{"a", "m", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("após inserção no meio = %v, want %v", got, want)
+	}
+	if r.contains("b") {
+		t.Error("b deveria ter sido descartada")
+	}
+
+	// Nova imagem descarta a mais antiga
+	insert(at("d", 7))
+	if got, want := recentPaths(r), []string{"d", "a", "m"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("após exceder capacidade = %v, want %v", got, want)
+	}
+
+	// Remoção e preenchimento
+	if !r.remove("a") {
+		t.Error("remove(a) deveria retornar true")
+	}
+	if r.remove("x") {
+		t.Error("remove(x) deveria retornar false")
+	}
+	r.fill([]*ImageInfo{
+		{Path: "c", ModTime: now.Add(10 * time.Second)},
+		{Path: "old", ModTime: now.Add(-time.Hour)},
+		{Path: "new", ModTime: now.Add(time.Hour)},
+	}, OrderMtime.newer)
+	if got, want := recentPaths(r), []string{"d", "m", "new"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("após fill = %v, want %v", got, want)
+	}
+}
+
+func TestRecentIndex_Disabled(t *testing.T) {
+	r := newRecentIndex(0)
+	if r.insert(&ImageInfo{Path: "a"}, OrderMtime.newer) {
+		t.Error("insert() com slideshow desabilitado não deveria reportar mudança")
+	}
+	if len(r.list()) != 0 {
+		t.Error("list() deveria estar vazia")
+	}
+}
+
+func TestRecentImages_RewriteAndDelete(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// img1..img4, com limite de 3 recentes: img4, img3, img2
+	for i := 1; i <= 4; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("img%d.png", i))
+		if err := os.WriteFile(path, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	w, err := NewWithSlideshowCount(tmpDir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if _, _, err := w.ScanExisting(); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan []string, 10)
+	w.OnRecentChanged = func(paths []string) {
+		changes <- paths
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	waitChange := func(want []string) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case got := <-changes:
+				if reflect.DeepEqual(got, want) {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("Timeout aguardando lista %v (atual: %v)", want, w.RecentImagesRelative())
+			}
+		}
+	}
+
+	// Reescrever img2 várias vezes: vai para o início, sem duplicatas
+	img2 := filepath.Join(tmpDir, "img2.png")
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(img2, []byte{0x89, 0x50, 0x4E, 0x47, byte(i)}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitChange([]string{"img2.png", "img4.png", "img3.png"})
+
+	// Deletar img3 (não é a atual): sai da lista e img1 é recuperada do disco
+	if err := os.Remove(filepath.Join(tmpDir, "img3.png")); err != nil {
+		t.Fatal(err)
+	}
+	waitChange([]string{"img2.png", "img4.png", "img1.png"})
+
+	// Renomear img4 para fora do diretório também remove
+	dstDir, err := os.MkdirTemp("", "sidelook_dst_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dstDir)
+	if err := os.Rename(filepath.Join(tmpDir, "img4.png"), filepath.Join(dstDir, "img4.png")); err != nil {
+		t.Fatal(err)
+	}
+	waitChange([]string{"img2.png", "img1.png"})
+}
@@ -0,0 +1,146 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// rescan relê todos os diretórios e reconcilia o índice com o disco após a
+// perda de eventos. reason descreve o motivo, exibido enquanto a
+// reconciliação está em andamento.
+func (iw *ImageWatcher) rescan(reason string) {
+	iw.setStatus(Status{Health: HealthRescan, Message: reason})
+
+	if err := iw.reconcile(); err != nil {
+		iw.setStatus(Status{Health: HealthDegraded, Message: fmt.Sprintf("falha ao reescanear: %v", err)})
+		return
+	}
+	iw.setStatus(iw.idleStatus())
+}
+
+// reconcile compara o disco com o índice e emite os mesmos callbacks que os
+// eventos perdidos teriam gerado: imagens novas ou reescritas entram como
+// atuais (a mais recente por último) e imagens sumidas saem do índice.
+func (iw *ImageWatcher) reconcile() error {
+	for _, src := range iw.activeSources() {
+		if err := iw.reloadIgnore(src); err != nil {
+			return err
+		}
+	}
+
+	// Subdiretórios criados ou removidos enquanto os eventos se perdiam
+	if iw.recursive {
+		iw.pruneWatchedDirs()
+		if err := iw.addRoots(); err != nil {
+			return err
+		}
+	}
+
+	var found []*ImageInfo
+	if err := iw.walkAll(func(img *ImageInfo) {
+		found = append(found, img)
+	}); err != nil {
+		return err
+	}
+
+	// Diferença entre o disco e o índice
+	iw.mu.RLock()
+	onDisk := make(map[string]bool, len(found))
+	var added, fresh []*ImageInfo
+	for _, img := range found {
+		onDisk[img.Path] = true
+		old, ok := iw.index.get(img.Path)
+		if !ok {
+			fresh = append(fresh, img)
+		}
+		if !ok || !old.ModTime.Equal(img.ModTime) {
+			added = append(added, img)
+		}
+	}
+	var removed []string
+	for _, path := range iw.index.paths() {
+		if !onDisk[path] {
+			removed = append(removed, path)
+		}
+	}
+	iw.mu.RUnlock()
+
+	// Arquivos ainda sendo escritos aguardam estabilizar (ou chegarão pelos
+	// próximos eventos)
+	added = iw.stage(added)
+
+	if iw.order == OrderArrival {
+		iw.assignArrivalByMtime(fresh)
+	}
+	sort.Slice(added, func(i, j int) bool {
+		return iw.order.newer(added[j], added[i])
+	})
+
+	iw.mu.Lock()
+	recentRemoved := false
+	wasCurrent := false
+	for _, path := range removed {
+		iw.index.remove(path)
+		if iw.recent.remove(path) {
+			recentRemoved = true
+		}
+		if iw.currentImage != nil && iw.currentImage.Path == path {
+			wasCurrent = true
+		}
+	}
+	recentChanged := recentRemoved
+	addedPaths := make(map[string]bool, len(added))
+	for _, img := range added {
+		iw.index.upsert(img)
+		if iw.recent.insert(img, iw.order.newer) {
+			recentChanged = true
+		}
+		addedPaths[img.Path] = true
+	}
+	// A atual é a mais recente segundo --order, que pode não estar entre as novas
+	iw.currentImage = iw.index.top()
+	current := iw.currentImage
+	newCurrent := current != nil && addedPaths[current.Path]
+	iw.mu.Unlock()
+
+	if recentRemoved {
+		iw.backfillRecent()
+	}
+
+	if newCurrent {
+		if iw.OnNewImage != nil {
+			iw.OnNewImage(iw.rel(current.Path))
+		}
+	} else if wasCurrent && iw.OnImageDeleted != nil {
+		var relPath string
+		if current != nil {
+			relPath = iw.rel(current.Path)
+		}
+		iw.OnImageDeleted(relPath)
+	}
+
+	if recentChanged {
+		iw.notifyRecentChanged()
+	}
+	return nil
+}
+
+// pruneWatchedDirs remove os watches de diretórios que não existem mais
+func (iw *ImageWatcher) pruneWatchedDirs() {
+	iw.mu.RLock()
+	var gone []string
+	for dir := range iw.watched {
+		if iw.isRoot(dir) {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			gone = append(gone, dir)
+		}
+	}
+	iw.mu.RUnlock()
+
+	for _, dir := range gone {
+		iw.removeWatchTree(dir)
+	}
+}
@@ -0,0 +1,138 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultRootRetry é o intervalo entre verificações de um diretório
+// monitorado que foi removido ou movido
+const DefaultRootRetry = time.Second
+
+// rootFound é uma raiz que voltou a existir, em dir
+type rootFound struct {
+	src  *source
+	dir  string
+	info os.FileInfo
+}
+
+// activeSources retorna os diretórios monitorados cuja raiz existe
+func (iw *ImageWatcher) activeSources() []*source {
+	iw.mu.RLock()
+	defer iw.mu.RUnlock()
+
+	active := make([]*source, 0, len(iw.sources))
+	for _, src := range iw.sources {
+		if !src.lost {
+			active = append(active, src)
+		}
+	}
+	return active
+}
+
+// handleRootLost trata a remoção ou movimentação de um diretório monitorado:
+// suas imagens saem do índice, os clientes são avisados e o watcher passa a
+// verificar periodicamente se ele voltou
+func (iw *ImageWatcher) handleRootLost(src *source) {
+	iw.mu.Lock()
+	if src.lost {
+		iw.mu.Unlock()
+		return
+	}
+	src.lost = true
+	iw.mu.Unlock()
+
+	iw.handleDirRemoved(src.dir())
+	iw.setStatus(iw.idleStatus())
+
+	// rm -rf out && mkdir out costuma recriar a raiz antes do primeiro
+	// intervalo: verificar imediatamente
+	go iw.waitForRoot(src, true)
+}
+
+// waitForRoot verifica a cada rootRetry se a raiz de src voltou e entrega o
+// resultado ao loop de eventos
+func (iw *ImageWatcher) waitForRoot(src *source, immediate bool) {
+	ticker := time.NewTicker(iw.rootRetry)
+	defer ticker.Stop()
+
+	if !immediate {
+		select {
+		case <-ticker.C:
+		case <-iw.done:
+			return
+		}
+	}
+
+	for {
+		if dir, info, ok := iw.locateRoot(src); ok {
+			select {
+			case iw.rootFound <- rootFound{src: src, dir: dir, info: info}:
+			case <-iw.done:
+			}
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-iw.done:
+			return
+		}
+	}
+}
+
+// locateRoot procura a raiz de src: primeiro no caminho original e, com
+// FollowRoot, entre os diretórios do mesmo pai (a raiz renomeada mantém a
+// mesma identidade no sistema de arquivos)
+func (iw *ImageWatcher) locateRoot(src *source) (string, os.FileInfo, bool) {
+	dir := src.dir()
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir, info, true
+	}
+	if !iw.followRoot {
+		return "", nil, false
+	}
+
+	parent := filepath.Dir(dir)
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return "", nil, false
+	}
+	identity := src.identity()
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(parent, entry.Name())
+		info, err := os.Stat(path)
+		if err == nil && os.SameFile(identity, info) {
+			return path, info, true
+		}
+	}
+	return "", nil, false
+}
+
+// recoverRoot volta a monitorar src em dir e reconcilia as imagens com o disco
+func (iw *ImageWatcher) recoverRoot(src *source, dir string, info os.FileInfo) {
+	src.moveTo(dir, info)
+
+	if err := iw.addWatchTree(dir); err != nil {
+		// Sumiu de novo antes de o watch ser adicionado, ou o watch falhou
+		// (ex: limite de watches): tentar novamente no próximo intervalo
+		if !errors.Is(err, fs.ErrNotExist) {
+			iw.handleError(err)
+		}
+		go iw.waitForRoot(src, false)
+		return
+	}
+
+	iw.mu.Lock()
+	src.lost = false
+	iw.mu.Unlock()
+
+	iw.rescan(fmt.Sprintf("diretório %s voltou", dir))
+}
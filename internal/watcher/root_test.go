@@ -0,0 +1,131 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// rootRecorder registra os eventos de um watcher cuja raiz vai sumir
+type rootRecorder struct {
+	health  chan Health
+	deleted chan string
+	images  chan string
+}
+
+func watchRoot(w *ImageWatcher) *rootRecorder {
+	r := &rootRecorder{
+		health:  make(chan Health, 10),
+		deleted: make(chan string, 10),
+		images:  make(chan string, 10),
+	}
+	w.OnHealthChanged = func(st Status) { r.health <- st.Health }
+	w.OnImageDeleted = func(path string) { r.deleted <- path }
+	w.OnNewImage = func(path string) { r.images <- path }
+	return r
+}
+
+func expect[T comparable](t *testing.T, ch <-chan T, want T, what string) {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case got := <-ch:
+			if got == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timeout aguardando %s = %v", what, want)
+		}
+	}
+}
+
+func TestImageWatcher_RootRecreated(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		name := "fsnotify"
+		if poll {
+			name = "poll"
+		}
+		t.Run(name, func(t *testing.T) {
+			root := filepath.Join(t.TempDir(), "out")
+			os.Mkdir(root, 0755)
+			os.WriteFile(filepath.Join(root, "old.png"), []byte("x"), 0644)
+
+			opts := Options{}
+			if poll {
+				opts.PollInterval = 30 * time.Millisecond
+			}
+			w, err := NewWithOptions(root, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.rootRetry = 20 * time.Millisecond
+			defer w.Stop()
+
+			w.ScanExisting()
+			rec := watchRoot(w)
+			if err := w.Start(); err != nil {
+				t.Fatal(err)
+			}
+
+			// rm -rf out
+			os.RemoveAll(root)
+			expect(t, rec.deleted, "", "OnImageDeleted")
+			expect(t, rec.health, HealthWaiting, "saúde")
+			if got := w.CurrentImageRelative(); got != "" {
+				t.Errorf("CurrentImageRelative() = %q, want vazio", got)
+			}
+
+			// mkdir out com uma imagem nova
+			os.Mkdir(root+".tmp", 0755)
+			os.WriteFile(filepath.Join(root+".tmp", "new.png"), []byte("x"), 0644)
+			os.Rename(root+".tmp", root)
+
+			expect(t, rec.images, "new.png", "OnNewImage")
+			expect(t, rec.health, HealthOK, "saúde")
+
+			// O watch voltou a funcionar
+			os.WriteFile(filepath.Join(root, "newer.png"), []byte("x"), 0644)
+			expect(t, rec.images, "newer.png", "OnNewImage")
+		})
+	}
+}
+
+func TestImageWatcher_FollowRoot(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "out")
+	os.Mkdir(root, 0755)
+	os.WriteFile(filepath.Join(root, "a.png"), []byte("x"), 0644)
+
+	w, err := NewWithOptions(root, Options{FollowRoot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.rootRetry = 20 * time.Millisecond
+	defer w.Stop()
+
+	w.ScanExisting()
+	rec := watchRoot(w)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	moved := filepath.Join(parent, "out-renamed")
+	if err := os.Rename(root, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, rec.images, "a.png", "OnNewImage")
+	expect(t, rec.health, HealthOK, "saúde")
+
+	if got := w.Dir(); got != moved {
+		t.Errorf("Dir() = %s, want %s", got, moved)
+	}
+	if img := w.CurrentImage(); img == nil || img.Path != filepath.Join(moved, "a.png") {
+		t.Errorf("CurrentImage() = %v, want %s", img, filepath.Join(moved, "a.png"))
+	}
+
+	os.WriteFile(filepath.Join(moved, "b.png"), []byte("x"), 0644)
+	expect(t, rec.images, "b.png", "OnNewImage")
+}
@@ -0,0 +1,209 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pendingFile acompanha um arquivo que ainda pode estar sendo escrito
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	timer   *time.Timer
+}
+
+// schedulePending registra (ou renova) a janela de estabilização de path.
+// Eventos sucessivos para o mesmo arquivo são agrupados em uma única publicação.
+func (iw *ImageWatcher) schedulePending(path string, info os.FileInfo) {
+	iw.pendingMu.Lock()
+	defer iw.pendingMu.Unlock()
+
+	p, ok := iw.pending[path]
+	if !ok {
+		p = &pendingFile{}
+		p.timer = time.AfterFunc(iw.settle, func() {
+			iw.checkSettled(path, p)
+		})
+		iw.pending[path] = p
+	} else {
+		p.timer.Reset(iw.settle)
+	}
+
+	p.size = info.Size()
+	p.modTime = info.ModTime()
+}
+
+// checkSettled publica o arquivo se tamanho e mtime não mudaram durante a janela
+func (iw *ImageWatcher) checkSettled(path string, p *pendingFile) {
+	info, err := os.Stat(path)
+
+	iw.pendingMu.Lock()
+	if iw.pending[path] != p {
+		// Cancelado ou substituído por um novo ciclo
+		iw.pendingMu.Unlock()
+		return
+	}
+	if err != nil {
+		delete(iw.pending, path)
+		iw.pendingMu.Unlock()
+		return
+	}
+	if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+		// Ainda mudando: reiniciar a janela
+		p.size = info.Size()
+		p.modTime = info.ModTime()
+		p.timer.Reset(iw.settle)
+		iw.pendingMu.Unlock()
+		return
+	}
+	delete(iw.pending, path)
+	iw.pendingMu.Unlock()
+
+	// Estável mas incompleto: aguardar o próximo evento de escrita
+	if iw.verify && !iw.isComplete(path) {
+		return
+	}
+
+	iw.publish(path, info)
+}
+
+// stage aplica a janela de estabilização e a verificação de arquivo
+// completo a imagens encontradas de uma vez (diretório movido para dentro,
+// reconciliação após perda de eventos). Com Settle, cada arquivo segue o
+// mesmo caminho dos eventos de criação e é publicado quando estabilizar.
+// Retorna as imagens que podem ser publicadas imediatamente.
+func (iw *ImageWatcher) stage(imgs []*ImageInfo) []*ImageInfo {
+	if iw.settle > 0 {
+		for _, img := range imgs {
+			if info, err := os.Stat(img.Path); err == nil {
+				iw.schedulePending(img.Path, info)
+			}
+		}
+		return nil
+	}
+	if !iw.verify {
+		return imgs
+	}
+
+	// Arquivos ainda sendo escritos chegarão pelos próximos eventos
+	ready := imgs[:0]
+	for _, img := range imgs {
+		if iw.isComplete(img.Path) {
+			ready = append(ready, img)
+		}
+	}
+	return ready
+}
+
+// cancelPending descarta a janela de estabilização de path, se existir
+func (iw *ImageWatcher) cancelPending(path string) {
+	iw.pendingMu.Lock()
+	defer iw.pendingMu.Unlock()
+
+	if p, ok := iw.pending[path]; ok {
+		p.timer.Stop()
+		delete(iw.pending, path)
+	}
+}
+
+// cancelAllPending descarta todas as janelas de estabilização
+func (iw *ImageWatcher) cancelAllPending() {
+	iw.pendingMu.Lock()
+	defer iw.pendingMu.Unlock()
+
+	for path, p := range iw.pending {
+		p.timer.Stop()
+		delete(iw.pending, path)
+	}
+}
+
+// Trailers esperados no fim de arquivos completos
+var (
+	pngTrailer = []byte{0x00, 0x00, 0x00, 0x00, 'I', 'E', 'N', 'D', 0xAE, 0x42, 0x60, 0x82}
+	jpegEOI    = []byte{0xFF, 0xD9}
+)
+
+// tailSize é quantos bytes do fim do arquivo são lidos para verificar o trailer
+const tailSize = 64
+
+// isComplete verifica se o arquivo parece ter sido escrito por completo.
+// Formatos sem trailer ou tamanho declarado conhecidos são considerados completos.
+func isComplete(path string) bool {
+	return isCompleteAs(path, strings.ToLower(filepath.Ext(path)))
+}
+
+// isCompleteAs é isComplete tratando o arquivo como do tipo da extensão ext
+func isCompleteAs(path, ext string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return false
+	}
+	size := info.Size()
+
+	switch ext {
+	case ".png":
+		tail, err := readTail(f, size)
+		return err == nil && bytes.HasSuffix(tail, pngTrailer)
+
+	case ".jpg", ".jpeg":
+		tail, err := readTail(f, size)
+		if err != nil {
+			return false
+		}
+		// Alguns encoders adicionam bytes nulos após o EOI
+		return bytes.HasSuffix(bytes.TrimRight(tail, "\x00"), jpegEOI)
+
+	case ".gif":
+		tail, err := readTail(f, size)
+		return err == nil && tail[len(tail)-1] == 0x3B
+
+	case ".webp":
+		// RIFF: bytes 4-8 contêm o tamanho do restante do arquivo
+		header := make([]byte, 12)
+		if _, err := io.ReadFull(f, header); err != nil {
+			return false
+		}
+		if !bytes.Equal(header[0:4], []byte("RIFF")) {
+			return false
+		}
+		return size >= int64(binary.LittleEndian.Uint32(header[4:8]))+8
+
+	case ".bmp":
+		// Bytes 2-6 contêm o tamanho total do arquivo
+		header := make([]byte, 6)
+		if _, err := io.ReadFull(f, header); err != nil {
+			return false
+		}
+		return size >= int64(binary.LittleEndian.Uint32(header[2:6]))
+
+	case ".svg":
+		tail, err := readTail(f, size)
+		return err == nil && bytes.Contains(bytes.ToLower(tail), []byte("</svg>"))
+	}
+
+	return true
+}
+
+// readTail lê os últimos bytes do arquivo
+func readTail(f *os.File, size int64) ([]byte, error) {
+	n := int64(tailSize)
+	if size < n {
+		n = size
+	}
+	tail := make([]byte, n)
+	if _, err := f.ReadAt(tail, size-n); err != nil {
+		return nil, err
+	}
+	return tail, nil
+}
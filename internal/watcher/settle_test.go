@@ -0,0 +1,215 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIsComplete(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pngHeader := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected bool
+	}{
+		{"truncado.png", pngHeader, false},
+		{"completo.png", append(append([]byte{}, pngHeader...), pngTrailer...), true},
+		{"truncado.jpg", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00}, false},
+		{"completo.jpg", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0xFF, 0xD9}, true},
+		{"padding.jpeg", []byte{0xFF, 0xD8, 0xFF, 0xD9, 0x00, 0x00}, true},
+		{"truncado.gif", []byte("GIF89a\x01\x00"), false},
+		{"completo.gif", []byte("GIF89a\x01\x00\x3B"), true},
+		{"truncado.webp", []byte("RIFF\x10\x00\x00\x00WEBP"), false},
+		{"completo.webp", []byte("RIFF\x04\x00\x00\x00WEBP"), true},
+		{"truncado.svg", []byte("<svg><rect/>"), false},
+		{"completo.svg", []byte("<svg><rect/></svg>\n"), true},
+		{"vazio.png", []byte{}, false},
+		{"scan.tiff", []byte("II*\x00"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tt.name)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := isComplete(path); got != tt.expected {
+				t.Errorf("isComplete(%q) = %v, want %v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSettle_CoalescesWrites(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	w, err := NewWithOptions(tmpDir, Options{Settle: 300 * time.Millisecond, Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	detected := make(chan string, 10)
+	w.OnNewImage = func(path string) {
+		detected <- path
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// Escrever o PNG em blocos, simulando um render sendo gravado
+	path := filepath.Join(tmpDir, "render.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := [][]byte{
+		{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A},
+		make([]byte, 1024),
+		pngTrailer,
+	}
+	for _, chunk := range chunks {
+		if _, err := f.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	f.Close()
+
+	select {
+	case got := <-detected:
+		if got != "render.png" {
+			t.Errorf("OnNewImage path = %q, want render.png", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout aguardando imagem estabilizar")
+	}
+
+	// Apenas uma publicação para todos os blocos
+	select {
+	case got := <-detected:
+		t.Errorf("OnNewImage chamado novamente com %q", got)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestSettle_IncompleteFileNotPublished(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	w, err := NewWithOptions(tmpDir, Options{Settle: 100 * time.Millisecond, Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	detected := make(chan string, 10)
+	w.OnNewImage = func(path string) {
+		detected <- path
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// PNG sem IEND nunca deve ser publicado
+	path := filepath.Join(tmpDir, "truncado.png")
+	if err := os.WriteFile(path, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-detected:
+		t.Errorf("OnNewImage chamado para arquivo incompleto: %q", got)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	if got := w.CurrentImageRelative(); got != "" {
+		t.Errorf("CurrentImageRelative() = %q, want empty string", got)
+	}
+}
+
+func TestSettle_DirectoryMovedIn(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "out")
+	staging := filepath.Join(tmpDir, "staging")
+	os.Mkdir(root, 0755)
+	os.Mkdir(staging, 0755)
+
+	// Render ainda sendo gravado em um diretório fora da raiz
+	pngHeader := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
+	partial := filepath.Join(staging, "a.png")
+	if err := os.WriteFile(partial, pngHeader, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const settle = 300 * time.Millisecond
+	w, err := NewWithOptions(root, Options{Recursive: true, Settle: settle, Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	detected := make(chan string, 10)
+	w.OnNewImage = func(path string) {
+		detected <- path
+	}
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := os.Rename(staging, filepath.Join(root, "run-3")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Incompleto: nem na hora nem depois da janela
+	select {
+	case got := <-detected:
+		t.Fatalf("OnNewImage chamado para arquivo incompleto: %q", got)
+	case <-time.After(2 * settle):
+	}
+	if got := w.CurrentImageRelative(); got != "" {
+		t.Errorf("CurrentImageRelative() = %q, want empty string", got)
+	}
+
+	// Terminar de gravar: publicado depois de estabilizar
+	f, err := os.OpenFile(filepath.Join(root, "run-3", "a.png"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(pngTrailer)
+	f.Close()
+	written := time.Now()
+
+	select {
+	case got := <-detected:
+		if got != "run-3/a.png" {
+			t.Errorf("OnNewImage path = %q, want run-3/a.png", got)
+		}
+		if elapsed := time.Since(written); elapsed < settle {
+			t.Errorf("publicado após %v, antes da janela de %v", elapsed, settle)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout aguardando o arquivo completo")
+	}
+}
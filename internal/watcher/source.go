@@ -0,0 +1,241 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Source descreve um diretório monitorado
+type Source struct {
+	// Name é o prefixo das imagens deste diretório nas URLs (/image/<Name>/...)
+	// quando há mais de um diretório monitorado
+	Name string
+
+	// Dir é o caminho absoluto do diretório
+	Dir string
+}
+
+// source é o estado interno de um diretório monitorado
+type source struct {
+	Name string
+
+	mu   sync.RWMutex
+	path string      // Caminho absoluto atual (muda ao seguir a raiz movida)
+	info os.FileInfo // Identidade da raiz, para encontrá-la após ser movida
+
+	ignore *ignoreMatcher // Regras do .sidelookignore (nil = nenhuma), protegido por iw.mu
+	lost   bool           // Raiz removida ou movida, aguardando voltar; protegido por iw.mu
+}
+
+// dir retorna o caminho absoluto atual da raiz
+func (src *source) dir() string {
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	return src.path
+}
+
+// identity retorna o FileInfo da raiz usado para reconhecê-la após um rename
+func (src *source) identity() os.FileInfo {
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	return src.info
+}
+
+// moveTo passa a usar dir (com o FileInfo info) como raiz
+func (src *source) moveTo(dir string, info os.FileInfo) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	src.path = dir
+	src.info = info
+}
+
+// public retorna a descrição exportada do diretório
+func (src *source) public() Source {
+	return Source{Name: src.Name, Dir: src.dir()}
+}
+
+// newSources valida os diretórios e atribui nomes únicos a cada um
+func newSources(dirs []string) ([]*source, error) {
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("nenhum diretório informado")
+	}
+
+	var sources []*source
+	seenDirs := make(map[string]bool)
+	usedNames := make(map[string]bool)
+
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("diretório inválido: %s", dir)
+		}
+
+		// Verificar se diretório existe
+		info, err := os.Stat(abs)
+		if err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("diretório inválido: %s", dir)
+		}
+
+		if seenDirs[abs] {
+			continue
+		}
+		seenDirs[abs] = true
+
+		// Nome base do diretório; repetições ganham o primeiro sufixo livre
+		// (-2, -3...), que não pode coincidir com o nome de outra origem
+		base := filepath.Base(abs)
+		if base == string(filepath.Separator) || base == "." || base == "" {
+			base = "root"
+		}
+		name := base
+		for n := 2; usedNames[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		usedNames[name] = true
+
+		sources = append(sources, &source{Name: name, path: abs, info: info})
+	}
+
+	return sources, nil
+}
+
+// Sources retorna os diretórios monitorados
+func (iw *ImageWatcher) Sources() []Source {
+	result := make([]Source, len(iw.sources))
+	for i, src := range iw.sources {
+		result[i] = src.public()
+	}
+	return result
+}
+
+// multiSource indica se as URLs levam o prefixo do diretório de origem
+func (iw *ImageWatcher) multiSource() bool {
+	return len(iw.sources) > 1
+}
+
+// sourceOf retorna o diretório monitorado que contém path (o mais específico)
+func (iw *ImageWatcher) sourceOf(path string) *source {
+	var best *source
+	bestLen := 0
+	for _, src := range iw.sources {
+		if dir := src.dir(); isUnder(path, dir) && (best == nil || len(dir) > bestLen) {
+			best, bestLen = src, len(dir)
+		}
+	}
+	return best
+}
+
+// rootSource retorna o diretório monitorado cuja raiz é exatamente dir
+func (iw *ImageWatcher) rootSource(dir string) *source {
+	for _, src := range iw.sources {
+		if src.dir() == dir {
+			return src
+		}
+	}
+	return nil
+}
+
+// isRoot indica se path é a raiz de um dos diretórios monitorados
+func (iw *ImageWatcher) isRoot(path string) bool {
+	return iw.rootSource(path) != nil
+}
+
+// relIn retorna o diretório de origem de path e o caminho relativo (com
+// barras normais) dentro dele
+func (iw *ImageWatcher) relIn(path string) (*source, string) {
+	src := iw.sourceOf(path)
+	if src == nil {
+		return nil, filepath.Base(path)
+	}
+	rel, err := filepath.Rel(src.dir(), path)
+	if err != nil {
+		return src, filepath.Base(path)
+	}
+	return src, filepath.ToSlash(rel)
+}
+
+// SourceName retorna o nome do diretório de origem de um caminho relativo
+// retornado pelo watcher ("" quando há apenas um diretório)
+func (iw *ImageWatcher) SourceName(rel string) string {
+	if !iw.multiSource() {
+		return ""
+	}
+	name, _, _ := strings.Cut(rel, "/")
+	return name
+}
+
+// Resolve converte um caminho relativo (como usado nas URLs) no caminho
+// completo do arquivo e na raiz do diretório de origem, que deve ser usada na
+// verificação de contenção. Retorna ok=false se a origem não existir.
+func (iw *ImageWatcher) Resolve(rel string) (fullPath, root string, ok bool) {
+	rel = strings.TrimPrefix(rel, "/")
+
+	if !iw.multiSource() {
+		dir := iw.sources[0].dir()
+		return filepath.Join(dir, filepath.FromSlash(rel)), dir, true
+	}
+
+	name, rest, found := strings.Cut(rel, "/")
+	if !found || rest == "" {
+		return "", "", false
+	}
+	for _, src := range iw.sources {
+		if src.Name == name {
+			dir := src.dir()
+			return filepath.Join(dir, filepath.FromSlash(rest)), dir, true
+		}
+	}
+	return "", "", false
+}
+
+// Confined indica se path fica dentro do diretório monitorado root. A
+// comparação respeita o separador (/data/img-private não está em /data/img)
+// e, sem FollowSymlinks, é feita depois de resolvidos os links simbólicos,
+// de modo que um link (ou um subdiretório que seja um link) não leva para
+// fora da raiz.
+func (iw *ImageWatcher) Confined(path, root string) bool {
+	path, root = filepath.Clean(path), filepath.Clean(root)
+	if path == root || !isUnder(path, root) {
+		return false
+	}
+	if iw.followSymlinks {
+		return true
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+	realPath, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+	return realPath != realRoot && isUnder(realPath, realRoot)
+}
+
+// resolvePath resolve os links simbólicos de path. Um arquivo que ainda não
+// existe é resolvido pelo diretório pai; um link quebrado é um erro, já que
+// o destino pode ser criado depois.
+func resolvePath(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if !os.IsNotExist(err) {
+		return real, err
+	}
+	if _, lerr := os.Lstat(path); lerr == nil {
+		return "", err
+	}
+	dir, err := resolvePath(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(path)), nil
+}
+
+// confined aplica Confined com o diretório monitorado que contém path
+func (iw *ImageWatcher) confined(path string) bool {
+	src := iw.sourceOf(path)
+	return src != nil && iw.Confined(path, src.dir())
+}
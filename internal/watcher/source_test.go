@@ -0,0 +1,328 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNewSources_UniqueNames(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	a := filepath.Join(tmpDir, "a", "renders")
+	b := filepath.Join(tmpDir, "b", "renders")
+	for _, d := range []string{a, b} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources, err := newSources([]string{a, b, a})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, src := range sources {
+		names = append(names, src.Name)
+	}
+	if want := []string{"renders", "renders-2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("nomes = %v, want %v", names, want)
+	}
+
+	// O sufixo gerado não pode colidir com o nome de outro diretório
+	dirs := func(rel ...string) []string {
+		var out []string
+		for _, r := range rel {
+			d := filepath.Join(tmpDir, r)
+			if err := os.MkdirAll(d, 0755); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, d)
+		}
+		return out
+	}
+	for _, tt := range []struct {
+		dirs []string
+		want []string
+	}{
+		{dirs("a/out", "b/out", "c/out-2"), []string{"out", "out-2", "out-2-2"}},
+		{dirs("c/out-2", "a/out", "b/out"), []string{"out-2", "out", "out-3"}},
+		{dirs("a/out", "c/out-2", "b/out", "d/out"), []string{"out", "out-2", "out-3", "out-4"}},
+	} {
+		sources, err := newSources(tt.dirs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, src := range sources {
+			names = append(names, src.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("nomes = %v, want %v", names, tt.want)
+		}
+	}
+
+	if _, err := newSources([]string{a, "/caminho/que/nao/existe"}); err == nil {
+		t.Error("newSources() com diretório inválido deveria retornar erro")
+	}
+}
+
+func TestImageWatcher_MultipleSources(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	renders := filepath.Join(tmpDir, "renders")
+	shots := filepath.Join(tmpDir, "screenshots")
+	for _, d := range []string{renders, shots} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(renders, "r1.png"), []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(shots, "s1.png"), []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWithSources([]string{renders, shots}, Options{SlideshowCount: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	count, _, err := w.ScanExisting()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("ScanExisting() count = %d, want 2", count)
+	}
+
+	// Linha do tempo única, com prefixo de origem
+	if got, want := w.RecentImagesRelative(), []string{"screenshots/s1.png", "renders/r1.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RecentImagesRelative() = %v, want %v", got, want)
+	}
+	if got := w.SourceName("renders/r1.png"); got != "renders" {
+		t.Errorf("SourceName() = %q, want renders", got)
+	}
+
+	// Resolve mantém cada origem separada
+	full, root, ok := w.Resolve("renders/r1.png")
+	if !ok || full != filepath.Join(renders, "r1.png") || root != renders {
+		t.Errorf("Resolve(renders/r1.png) = %q, %q, %v", full, root, ok)
+	}
+	if _, _, ok := w.Resolve("desconhecido/x.png"); ok {
+		t.Error("Resolve() com origem desconhecida deveria falhar")
+	}
+
+	detected := make(chan string, 10)
+	w.OnNewImage = func(path string) {
+		detected <- path
+	}
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(renders, "r2.png"), []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case path := <-detected:
+		if path != "renders/r2.png" {
+			t.Errorf("OnNewImage path = %q, want renders/r2.png", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout aguardando imagem na segunda origem")
+	}
+}
+
+func TestImageWatcher_SingleSourceHasNoPrefix(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	w, err := New(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	full, root, ok := w.Resolve("sub/x.png")
+	if !ok || full != filepath.Join(tmpDir, "sub", "x.png") || root != tmpDir {
+		t.Errorf("Resolve(sub/x.png) = %q, %q, %v", full, root, ok)
+	}
+	if got := w.SourceName("sub/x.png"); got != "" {
+		t.Errorf("SourceName() = %q, want empty string", got)
+	}
+}
+
+func TestImageWatcher_Images(t *testing.T) {
+	tmpDir := t.TempDir()
+	renders := filepath.Join(tmpDir, "renders")
+	shots := filepath.Join(tmpDir, "screenshots")
+	os.Mkdir(renders, 0755)
+	os.Mkdir(shots, 0755)
+
+	base := time.Now().Add(-time.Hour)
+	for i, p := range []string{
+		filepath.Join(renders, "old.png"),
+		filepath.Join(shots, "mid.png"),
+		filepath.Join(renders, "sub", "new.png"),
+	} {
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(p, mtime, mtime)
+	}
+
+	w, err := NewWithSources([]string{renders, shots}, Options{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if _, _, err := w.ScanExisting(); err != nil {
+		t.Fatal(err)
+	}
+
+	var rels []string
+	for _, img := range w.Images() {
+		rels = append(rels, w.Relative(img.Path))
+	}
+	want := []string{"renders/sub/new.png", "screenshots/mid.png", "renders/old.png"}
+	if !reflect.DeepEqual(rels, want) {
+		t.Errorf("Images() = %v, want %v", rels, want)
+	}
+	if n := w.ImageCount(); n != 3 {
+		t.Errorf("ImageCount() = %d, want 3", n)
+	}
+
+	img, ok := w.Image("screenshots/mid.png")
+	if !ok || img.Path != filepath.Join(shots, "mid.png") {
+		t.Errorf("Image(screenshots/mid.png) = %v, %v", img, ok)
+	}
+	for _, rel := range []string{"renders/missing.png", "mid.png", "other/mid.png"} {
+		if _, ok := w.Image(rel); ok {
+			t.Errorf("Image(%q) deveria falhar", rel)
+		}
+	}
+}
+
+// symlinkFixture monta base/img (monitorado), base/img-private e base/outside,
+// com links simbólicos de dentro de img para fora dele
+func symlinkFixture(t *testing.T) (base, root string) {
+	t.Helper()
+	base = t.TempDir()
+	root = filepath.Join(base, "img")
+	png := []byte{0x89, 0x50, 0x4E, 0x47}
+	for _, p := range []string{"img/a.png", "img/sub/b.png", "img-private/x.png", "outside/secret.png"} {
+		path := filepath.Join(base, filepath.FromSlash(p))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, png, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"img/inner.png":    "a.png",
+		"img/link-out.png": filepath.Join(base, "outside", "secret.png"),
+		"img/linkdir":      filepath.Join("..", "outside"),
+		"img/dangling.png": filepath.Join(base, "outside", "missing.png"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(base, filepath.FromSlash(link))); err != nil {
+			t.Skipf("links simbólicos indisponíveis: %v", err)
+		}
+	}
+	return base, root
+}
+
+func TestImageWatcher_Confined(t *testing.T) {
+	base, root := symlinkFixture(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		want   bool // Sem FollowSymlinks
+		follow bool // Com FollowSymlinks
+	}{
+		{"arquivo", "img/a.png", true, true},
+		{"subdiretório", "img/sub/b.png", true, true},
+		{"link para dentro", "img/inner.png", true, true},
+		{"ainda não existe", "img/novo.png", true, true},
+		{"prefixo irmão", "img-private/x.png", false, false},
+		{"ponto-ponto", "img/../outside/secret.png", false, false},
+		{"a própria raiz", "img", false, false},
+		{"diretório pai", ".", false, false},
+		{"link para fora", "img/link-out.png", false, true},
+		{"subdiretório é link para fora", "img/linkdir/secret.png", false, true},
+		{"link quebrado para fora", "img/dangling.png", false, true},
+		{"ainda não existe em link para fora", "img/linkdir/novo.png", false, true},
+	}
+	for _, follow := range []bool{false, true} {
+		w, err := NewWithOptions(root, Options{FollowSymlinks: follow})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Stop()
+
+		for _, tt := range tests {
+			want := tt.want
+			if follow {
+				want = tt.follow
+			}
+			path := filepath.Join(base, filepath.FromSlash(tt.path))
+			if got := w.Confined(path, root); got != want {
+				t.Errorf("follow=%v: Confined(%s) = %v, want %v", follow, tt.name, got, want)
+			}
+		}
+	}
+}
+
+func TestImageWatcher_SkipsEscapingSymlinks(t *testing.T) {
+	_, root := symlinkFixture(t)
+
+	for _, tt := range []struct {
+		follow bool
+		want   []string
+	}{
+		{false, []string{"a.png", "inner.png", "sub/b.png"}},
+		{true, []string{"a.png", "inner.png", "link-out.png", "sub/b.png"}},
+	} {
+		w, err := NewWithOptions(root, Options{Recursive: true, FollowSymlinks: tt.follow})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Stop()
+		if _, _, err := w.ScanExisting(); err != nil {
+			t.Fatal(err)
+		}
+
+		var rels []string
+		for _, img := range w.Images() {
+			rels = append(rels, w.Relative(img.Path))
+		}
+		sort.Strings(rels)
+		if !reflect.DeepEqual(rels, tt.want) {
+			t.Errorf("follow=%v: Images() = %v, want %v", tt.follow, rels, tt.want)
+		}
+	}
+}
@@ -2,13 +2,17 @@
 package watcher
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/verseles/sidelook/internal/imageinfo"
 )
 
 // SupportedExtensions são as extensões de imagem suportadas
@@ -24,27 +28,121 @@ var SupportedExtensions = map[string]bool{
 	".tif":  true,
 }
 
-// IsImageFile verifica se um arquivo é uma imagem suportada
+// IsImageFile verifica se um arquivo é uma imagem suportada.
+// Arquivos temporários (ver DefaultIgnorePatterns) nunca são considerados imagens.
+// Não conhece o .sidelookignore: para arquivos de um diretório monitorado use
+// ImageWatcher.Accepts, que aplica os dois conjuntos de padrões.
 func IsImageFile(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
-	return SupportedExtensions[ext]
+	return SupportedExtensions[ext] && !defaultIgnore.Match(filepath.Base(path), false)
 }
 
 // ImageInfo contém informações sobre uma imagem
 type ImageInfo struct {
 	Path    string
 	ModTime time.Time
+
+	// ChangeTime é o ctime do arquivo (data de criação no Windows)
+	ChangeTime time.Time
+
+	// Arrival é a ordem em que o watcher viu o arquivo pela primeira vez
+	Arrival uint64
+
+	// TakenAt é a data de captura EXIF (preenchida apenas com OrderExifDate)
+	TakenAt time.Time
+
+	// Size é o tamanho do arquivo em bytes
+	Size int64
+
+	// Header traz formato, dimensões, modelo de cor e quadros, lidos do
+	// cabeçalho na primeira chamada a Describe (nil até lá)
+	Header *imageinfo.Info
 }
 
-// ImageWatcher monitora um diretório por novas imagens
+// Options configura o comportamento do ImageWatcher
+type Options struct {
+	// SlideshowCount é o número de imagens recentes a manter (0 = desabilitado)
+	SlideshowCount int
+
+	// Recursive habilita o monitoramento de subdiretórios
+	Recursive bool
+
+	// MaxDepth limita a profundidade dos subdiretórios no modo recursivo (0 = ilimitado)
+	MaxDepth int
+
+	// Settle é o tempo que tamanho e mtime devem ficar estáveis antes de a
+	// imagem ser publicada (0 = publicar no primeiro evento)
+	Settle time.Duration
+
+	// Verify exige que o arquivo esteja completo (ex: chunk IEND no PNG,
+	// marcador EOI no JPEG) antes de ser publicado
+	Verify bool
+
+	// Include restringe as imagens às que casam com algum destes globs
+	Include []string
+
+	// Exclude descarta as imagens que casam com algum destes globs
+	Exclude []string
+
+	// Extensions são extensões aceitas além de SupportedExtensions (ex: "avif", ".jxl")
+	Extensions []string
+
+	// PollInterval troca o fsnotify por polling periódico dos diretórios
+	// (0 = fsnotify, com fallback automático para DefaultPollInterval)
+	PollInterval time.Duration
+
+	// Order define o critério de "mais recente" ("" = OrderMtime)
+	Order Order
+
+	// Sniff identifica imagens pelo conteúdo (magic bytes) em vez da extensão:
+	// arquivos sem extensão são aceitos e o tipo detectado é usado no Content-Type
+	Sniff bool
+
+	// FollowRoot faz o watcher seguir um diretório monitorado renomeado ou
+	// movido dentro do mesmo diretório pai. Se o caminho original voltar a
+	// existir, ele tem prioridade.
+	FollowRoot bool
+
+	// FollowSymlinks aceita links simbólicos que apontam para fora do
+	// diretório monitorado. Por padrão eles são ignorados e não são servidos.
+	FollowSymlinks bool
+}
+
+// ImageWatcher monitora um ou mais diretórios por novas imagens, mesclando
+// todos em uma única linha do tempo
 type ImageWatcher struct {
-	dir     string
-	watcher *fsnotify.Watcher
+	sources []*source
+	backend backend
+	polling bool
+
+	followRoot     bool
+	followSymlinks bool
+	rootRetry      time.Duration  // Intervalo entre verificações de uma raiz removida
+	rootFound      chan rootFound // Raízes que voltaram, tratadas pelo loop
+
+	recursive bool
+	maxDepth  int
+	watched   map[string]bool // Diretórios com watch ativo
+
+	extensions map[string]bool
+	sniff      bool
+	include    globSet
+	exclude    globSet
+
+	order      Order
+	arrivalSeq uint64
+
+	settle    time.Duration
+	verify    bool
+	pendingMu sync.Mutex
+	pending   map[string]*pendingFile // Arquivos aguardando estabilizar
 
 	mu           sync.RWMutex
 	currentImage *ImageInfo
-	recentImages []*ImageInfo // N imagens mais recentes ordenadas (mais recente primeiro)
+	recent       *recentIndex // N imagens mais recentes ordenadas (mais recente primeiro)
+	index        *imageIndex  // Todas as imagens conhecidas, ordenadas pelo critério
 	maxRecent    int          // Número máximo de imagens recentes a manter
+	status       Status       // Saúde do monitoramento
 
 	// OnNewImage é chamado quando uma nova imagem é detectada
 	OnNewImage func(path string)
@@ -52,6 +150,18 @@ type ImageWatcher struct {
 	// OnImageDeleted é chamado quando a imagem atual é deletada
 	OnImageDeleted func(path string)
 
+	// OnRecentChanged é chamado com a lista completa (caminhos relativos, mais
+	// recente primeiro) sempre que as imagens recentes mudam. Só é chamado com
+	// slideshow ativado.
+	OnRecentChanged func(paths []string)
+
+	// OnError é chamado com cada erro reportado pelo backend de eventos
+	OnError func(err error)
+
+	// OnHealthChanged é chamado quando a saúde do watcher muda (ex: estouro da
+	// fila de eventos dispara uma reconciliação com o disco)
+	OnHealthChanged func(status Status)
+
 	done chan struct{}
 }
 
@@ -62,89 +172,212 @@ func New(dir string) (*ImageWatcher, error) {
 
 // NewWithSlideshowCount cria um novo ImageWatcher com suporte a slideshow
 func NewWithSlideshowCount(dir string, slideshowCount int) (*ImageWatcher, error) {
-	// Verificar se diretório existe
-	info, err := os.Stat(dir)
+	return NewWithOptions(dir, Options{SlideshowCount: slideshowCount})
+}
+
+// NewWithOptions cria um novo ImageWatcher com as opções informadas
+func NewWithOptions(dir string, opts Options) (*ImageWatcher, error) {
+	return NewWithSources([]string{dir}, opts)
+}
+
+// NewWithSources cria um ImageWatcher que monitora vários diretórios.
+// Com mais de um diretório, os caminhos relativos recebem o nome do
+// diretório de origem como prefixo (ver Source).
+func NewWithSources(dirs []string, opts Options) (*ImageWatcher, error) {
+	sources, err := newSources(dirs)
 	if err != nil {
 		return nil, err
 	}
-	if !info.IsDir() {
-		return nil, os.ErrNotExist
+
+	order, err := ParseOrder(string(opts.Order))
+	if err != nil {
+		return nil, err
 	}
 
-	w, err := fsnotify.NewWatcher()
+	include, err := compileGlobs(opts.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileGlobs(opts.Exclude)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ImageWatcher{
-		dir:          dir,
-		watcher:      w,
-		done:         make(chan struct{}),
-		maxRecent:    slideshowCount,
-		recentImages: make([]*ImageInfo, 0, slideshowCount),
-	}, nil
+	iw := &ImageWatcher{
+		sources:        sources,
+		followRoot:     opts.FollowRoot,
+		followSymlinks: opts.FollowSymlinks,
+		rootRetry:      DefaultRootRetry,
+		rootFound:      make(chan rootFound),
+		recursive:      opts.Recursive,
+		maxDepth:       opts.MaxDepth,
+		watched:        make(map[string]bool),
+		extensions:     buildExtensions(opts.Extensions),
+		sniff:          opts.Sniff,
+		include:        include,
+		exclude:        exclude,
+		order:          order,
+		settle:         opts.Settle,
+		verify:         opts.Verify,
+		pending:        make(map[string]*pendingFile),
+		done:           make(chan struct{}),
+		maxRecent:      opts.SlideshowCount,
+		recent:         newRecentIndex(opts.SlideshowCount),
+		index:          newImageIndex(order),
+		status:         Status{Health: HealthOK},
+	}
+
+	for _, src := range iw.sources {
+		if err := iw.reloadIgnore(src); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.PollInterval > 0 {
+		iw.usePolling(opts.PollInterval)
+	} else if b, err := newFsnotifyBackend(); err == nil {
+		iw.backend = b
+	} else {
+		// Sem inotify disponível (ex: limite de instâncias atingido)
+		iw.usePolling(DefaultPollInterval)
+	}
+
+	return iw, nil
 }
 
-// ScanExisting faz scan inicial e retorna a imagem mais recente
-func (iw *ImageWatcher) ScanExisting() (count int, mostRecent *ImageInfo, err error) {
-	entries, err := os.ReadDir(iw.dir)
-	if err != nil {
-		return 0, nil, err
+// depth retorna a profundidade de um diretório em relação ao seu diretório monitorado
+func (iw *ImageWatcher) depth(dir string) int {
+	_, rel := iw.relIn(dir)
+	if rel == "." {
+		return 0
 	}
+	return strings.Count(rel, "/") + 1
+}
 
-	var allImages []*ImageInfo
+// shouldDescend indica se um subdiretório deve ser percorrido e monitorado
+func (iw *ImageWatcher) shouldDescend(dir string) bool {
+	if !iw.recursive {
+		return false
+	}
+	return iw.maxDepth <= 0 || iw.depth(dir) <= iw.maxDepth
+}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+// skipDir indica se o subdiretório path de root fica fora do percurso e do
+// monitoramento (modo não recursivo, limite de profundidade ou ignorado)
+func (iw *ImageWatcher) skipDir(root, path string) bool {
+	return path != root && (!iw.shouldDescend(path) || iw.ignored(path, true))
+}
+
+// walkDirs percorre só os diretórios da árvore a partir de root que seriam
+// visitados por walk, chamando fn para cada um. Arquivos não são examinados.
+func (iw *ImageWatcher) walkDirs(root string, fn func(dir string)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
 		}
+		if iw.skipDir(root, path) {
+			return filepath.SkipDir
+		}
+		fn(path)
+		return nil
+	})
+}
 
-		path := filepath.Join(iw.dir, entry.Name())
-		if !IsImageFile(path) {
-			continue
+// walk percorre a árvore a partir de root, chamando fn para cada imagem encontrada.
+// Subdiretórios só são visitados no modo recursivo e dentro do limite de profundidade.
+func (iw *ImageWatcher) walk(root string, fn func(img *ImageInfo)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// Subdiretório ilegível: ignorar e continuar
+			return nil
+		}
+
+		if d.IsDir() {
+			if iw.skipDir(root, path) {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
-		count++
+		if !iw.Accepts(path) {
+			return nil
+		}
 
-		info, err := entry.Info()
+		// Links simbólicos aceitos usam tamanho e mtime do arquivo apontado
+		info, err := d.Info()
+		if d.Type()&fs.ModeSymlink != 0 {
+			info, err = os.Stat(path)
+		}
 		if err != nil {
-			continue
+			return nil
 		}
 
-		allImages = append(allImages, &ImageInfo{
-			Path:    path,
-			ModTime: info.ModTime(),
-		})
-	}
+		fn(iw.newImageInfo(path, info))
+		return nil
+	})
+}
 
-	// Ordenar por ModTime (mais recente primeiro)
-	for i := 0; i < len(allImages); i++ {
-		for j := i + 1; j < len(allImages); j++ {
-			if allImages[j].ModTime.After(allImages[i].ModTime) {
-				allImages[i], allImages[j] = allImages[j], allImages[i]
-			}
+// walkAll percorre todos os diretórios monitorados (exceto raízes removidas)
+func (iw *ImageWatcher) walkAll(fn func(img *ImageInfo)) error {
+	for _, src := range iw.activeSources() {
+		if err := iw.walk(src.dir(), fn); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	// Pegar as N mais recentes
-	iw.mu.Lock()
-	if len(allImages) > 0 {
-		iw.currentImage = allImages[0]
+// rel retorna o caminho relativo (com barras normais) de um arquivo monitorado,
+// prefixado pelo nome do diretório de origem quando há mais de um
+func (iw *ImageWatcher) rel(path string) string {
+	src, rel := iw.relIn(path)
+	if src == nil || !iw.multiSource() {
+		return rel
+	}
+	return src.Name + "/" + rel
+}
 
-		if iw.maxRecent > 0 {
-			maxIdx := iw.maxRecent
-			if maxIdx > len(allImages) {
-				maxIdx = len(allImages)
-			}
-			iw.recentImages = allImages[:maxIdx]
-		}
+// isUnder indica se path é igual a dir ou está dentro dele
+func isUnder(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// ScanExisting faz scan inicial e retorna a imagem mais recente.
+// As imagens encontradas formam o índice em memória usado depois para
+// remoções e para completar a lista de recentes sem reler o disco.
+func (iw *ImageWatcher) ScanExisting() (count int, mostRecent *ImageInfo, err error) {
+	var allImages []*ImageInfo
+
+	err = iw.walkAll(func(img *ImageInfo) {
+		allImages = append(allImages, img)
+	})
+	if err != nil {
+		return 0, nil, err
 	}
-	iw.mu.Unlock()
+	count = len(allImages)
 
-	if len(allImages) > 0 {
-		return count, allImages[0], nil
+	// Arquivos já existentes chegam na ordem do mtime
+	if iw.order == OrderArrival {
+		iw.assignArrivalByMtime(allImages)
 	}
-	return count, nil, nil
+
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	iw.index.build(allImages)
+	iw.currentImage = iw.index.top()
+	iw.recent.reset(iw.index.topK(iw.maxRecent, nil))
+
+	return count, iw.currentImage, nil
 }
 
 // CurrentImage retorna a imagem atual
@@ -160,11 +393,7 @@ func (iw *ImageWatcher) CurrentImageRelative() string {
 	if img == nil {
 		return ""
 	}
-	rel, err := filepath.Rel(iw.dir, img.Path)
-	if err != nil {
-		return filepath.Base(img.Path)
-	}
-	return rel
+	return iw.rel(img.Path)
 }
 
 // RecentImages retorna as N imagens mais recentes
@@ -173,9 +402,7 @@ func (iw *ImageWatcher) RecentImages() []*ImageInfo {
 	defer iw.mu.RUnlock()
 
 	// Retornar cópia para evitar problemas de concorrência
-	result := make([]*ImageInfo, len(iw.recentImages))
-	copy(result, iw.recentImages)
-	return result
+	return iw.recent.list()
 }
 
 // RecentImagesRelative retorna os caminhos relativos das N imagens mais recentes
@@ -184,77 +411,221 @@ func (iw *ImageWatcher) RecentImagesRelative() []string {
 	paths := make([]string, len(images))
 
 	for i, img := range images {
-		rel, err := filepath.Rel(iw.dir, img.Path)
-		if err != nil {
-			paths[i] = filepath.Base(img.Path)
-		} else {
-			paths[i] = rel
-		}
+		paths[i] = iw.rel(img.Path)
 	}
 
 	return paths
 }
 
-// findMostRecentImage procura a imagem mais recente no diretório
-func (iw *ImageWatcher) findMostRecentImage() *ImageInfo {
-	entries, err := os.ReadDir(iw.dir)
-	if err != nil {
-		return nil
+// Images retorna todas as imagens conhecidas, da mais recente para a mais
+// antiga segundo o critério de ordenação
+func (iw *ImageWatcher) Images() []*ImageInfo {
+	iw.mu.RLock()
+	images := make([]*ImageInfo, 0, len(iw.index.items))
+	for _, e := range iw.index.items {
+		images = append(images, e.img)
 	}
+	iw.mu.RUnlock()
 
-	var latestInfo *ImageInfo
+	sort.Slice(images, func(i, j int) bool {
+		return iw.order.newer(images[i], images[j])
+	})
+	return images
+}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+// ImageCount retorna quantas imagens são conhecidas
+func (iw *ImageWatcher) ImageCount() int {
+	iw.mu.RLock()
+	defer iw.mu.RUnlock()
+	return iw.index.Len()
+}
+
+// Image retorna a imagem conhecida no caminho relativo rel (como nas URLs)
+func (iw *ImageWatcher) Image(rel string) (*ImageInfo, bool) {
+	fullPath, _, ok := iw.Resolve(rel)
+	if !ok {
+		return nil, false
+	}
+
+	iw.mu.RLock()
+	defer iw.mu.RUnlock()
+	return iw.index.get(fullPath)
+}
+
+// Describe retorna a imagem no caminho relativo rel com Header preenchido.
+// O cabeçalho é lido do disco apenas na primeira consulta e fica guardado no
+// índice até o arquivo mudar.
+func (iw *ImageWatcher) Describe(rel string) (*ImageInfo, bool) {
+	img, ok := iw.Image(rel)
+	if !ok || img.Header != nil {
+		return img, ok
+	}
+
+	// Ler fora do lock; formato desconhecido ou cabeçalho truncado ainda
+	// rendem um Header (parcial) para não reler o arquivo a cada consulta
+	header, _ := imageinfo.ReadFile(img.Path)
+	described := *img
+	described.Header = &header
+
+	// ImageInfo já publicado não é modificado: o índice passa a guardar a
+	// cópia, se a imagem não tiver mudado enquanto o cabeçalho era lido
+	iw.mu.Lock()
+	iw.index.replace(img, &described)
+	iw.mu.Unlock()
+	return &described, true
+}
+
+// Relative retorna o caminho de uma imagem como usado nas URLs (relativo ao
+// diretório de origem, com o nome da origem como prefixo se houver vários)
+func (iw *ImageWatcher) Relative(path string) string {
+	return iw.rel(path)
+}
+
+// notifyRecentChanged envia a lista atual de recentes para OnRecentChanged
+func (iw *ImageWatcher) notifyRecentChanged() {
+	if iw.maxRecent <= 0 || iw.OnRecentChanged == nil {
+		return
+	}
+	iw.OnRecentChanged(iw.RecentImagesRelative())
+}
+
+// backfillRecent completa a lista de recentes com imagens do índice após remoções
+func (iw *ImageWatcher) backfillRecent() {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	if iw.maxRecent <= 0 || iw.recent.full() {
+		return
+	}
+	iw.recent.fill(iw.index.topK(iw.maxRecent, iw.recent.contains), iw.order.newer)
+}
+
+// findMostRecentImage retorna a imagem mais recente do índice
+func (iw *ImageWatcher) findMostRecentImage() *ImageInfo {
+	iw.mu.RLock()
+	defer iw.mu.RUnlock()
+	return iw.index.top()
+}
+
+// Start inicia o monitoramento
+func (iw *ImageWatcher) Start() error {
+	if err := iw.addRoots(); err != nil {
+		if iw.polling {
+			return err
 		}
 
-		path := filepath.Join(iw.dir, entry.Name())
-		if !IsImageFile(path) {
-			continue
+		// fsnotify não conseguiu monitorar (ex: limite de watches, FUSE):
+		// recomeçar com polling
+		for _, src := range iw.sources {
+			iw.removeWatchTree(src.dir())
+		}
+		iw.backend.Close()
+		iw.usePolling(DefaultPollInterval)
+		if err := iw.addRoots(); err != nil {
+			return err
 		}
+	}
+
+	go iw.loop()
+	return nil
+}
+
+// addRoots adiciona os watches de todos os diretórios monitorados (exceto
+// raízes removidas)
+func (iw *ImageWatcher) addRoots() error {
+	for _, src := range iw.activeSources() {
+		if err := iw.addWatchTree(src.dir()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usePolling troca o backend para polling com o intervalo informado
+func (iw *ImageWatcher) usePolling(interval time.Duration) {
+	iw.backend = newPollBackend(interval)
+	iw.polling = true
+}
+
+// Polling indica se o watcher está usando polling em vez de notificações do sistema
+func (iw *ImageWatcher) Polling() bool {
+	return iw.polling
+}
 
-		info, err := entry.Info()
+// addWatchTree adiciona watches para dir e, no modo recursivo, seus subdiretórios
+func (iw *ImageWatcher) addWatchTree(dir string) error {
+	var dirs []string
+	if iw.recursive {
+		err := iw.walkDirs(dir, func(d string) {
+			dirs = append(dirs, d)
+		})
 		if err != nil {
-			continue
+			return err
 		}
+	} else {
+		dirs = []string{dir}
+	}
 
-		if latestInfo == nil || info.ModTime().After(latestInfo.ModTime) {
-			latestInfo = &ImageInfo{
-				Path:    path,
-				ModTime: info.ModTime(),
+	for _, d := range dirs {
+		if err := iw.backend.Add(d); err != nil {
+			// Subdiretórios podem sumir durante o percurso; outros erros
+			// (ex: limite de watches) são reportados
+			if iw.isRoot(d) || !errors.Is(err, fs.ErrNotExist) {
+				return err
 			}
+			continue
 		}
+		iw.mu.Lock()
+		iw.watched[d] = true
+		iw.mu.Unlock()
 	}
-
-	return latestInfo
+	return nil
 }
 
-// Start inicia o monitoramento
-func (iw *ImageWatcher) Start() error {
-	if err := iw.watcher.Add(iw.dir); err != nil {
-		return err
+// removeWatchTree remove os watches de dir e de todos os seus subdiretórios.
+// Retorna false se dir não estava sendo monitorado.
+func (iw *ImageWatcher) removeWatchTree(dir string) bool {
+	iw.mu.Lock()
+	var dirs []string
+	for d := range iw.watched {
+		if isUnder(d, dir) {
+			dirs = append(dirs, d)
+			delete(iw.watched, d)
+		}
 	}
+	iw.mu.Unlock()
 
-	go iw.loop()
-	return nil
+	for _, d := range dirs {
+		// Erro é esperado quando o kernel já removeu o watch do diretório apagado
+		_ = iw.backend.Remove(d)
+	}
+	return len(dirs) > 0
+}
+
+// isWatchedDir indica se path é um diretório monitorado
+func (iw *ImageWatcher) isWatchedDir(path string) bool {
+	iw.mu.RLock()
+	defer iw.mu.RUnlock()
+	return iw.watched[path]
 }
 
 func (iw *ImageWatcher) loop() {
 	for {
 		select {
-		case event, ok := <-iw.watcher.Events:
+		case event, ok := <-iw.backend.Events():
 			if !ok {
 				return
 			}
 			iw.handleEvent(event)
 
-		case err, ok := <-iw.watcher.Errors:
+		case err, ok := <-iw.backend.Errors():
 			if !ok {
 				return
 			}
-			// Log error mas continua
-			_ = err
+			iw.handleError(err)
+
+		case found := <-iw.rootFound:
+			iw.recoverRoot(found.src, found.dir, found.info)
 
 		case <-iw.done:
 			return
@@ -265,12 +636,48 @@ func (iw *ImageWatcher) loop() {
 func (iw *ImageWatcher) handleEvent(event fsnotify.Event) {
 	path := event.Name
 
+	// Mudanças no .sidelookignore valem para os próximos eventos
+	if filepath.Base(path) == IgnoreFileName {
+		if src := iw.rootSource(filepath.Dir(path)); src != nil {
+			_ = iw.reloadIgnore(src)
+			return
+		}
+	}
+
+	// A própria raiz foi removida ou movida: o watch morreu com ela
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		if src := iw.rootSource(path); src != nil {
+			iw.handleRootLost(src)
+			return
+		}
+	}
+
+	// Subdiretórios (modo recursivo)
+	if iw.recursive {
+		if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && iw.isWatchedDir(path) && !iw.isRoot(path) {
+			iw.handleDirRemoved(path)
+			return
+		}
+		if event.Op&fsnotify.Create != 0 && !iw.ignored(path, true) {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				iw.handleDirCreated(path)
+				return
+			}
+		}
+	}
+
 	// Tratar deleção (Remove ou Rename para fora do diretório)
 	if event.Op&fsnotify.Remove != 0 || event.Op&fsnotify.Rename != 0 {
-		if !IsImageFile(path) {
+		// Temporários renomeados para o nome final não são deleções: o nome
+		// final chega como Create e é tratado abaixo. O conteúdo não existe
+		// mais, então só as regras de nome se aplicam.
+		if !iw.acceptsName(path) {
 			return
 		}
 
+		// Arquivo ainda sendo escrito não chegou a ser publicado
+		iw.cancelPending(path)
+
 		// Verificar se arquivo ainda existe (RENAME pode ser renomear dentro do mesmo diretório)
 		if event.Op&fsnotify.Rename != 0 {
 			if _, err := os.Stat(path); err == nil {
@@ -279,36 +686,7 @@ func (iw *ImageWatcher) handleEvent(event fsnotify.Event) {
 			}
 		}
 
-		// Verificar se a imagem deletada é a atual
-		iw.mu.RLock()
-		currentPath := ""
-		if iw.currentImage != nil {
-			currentPath = iw.currentImage.Path
-		}
-		iw.mu.RUnlock()
-
-		if currentPath == path {
-			// Encontrar próxima imagem mais recente
-			nextImage := iw.findMostRecentImage()
-
-			iw.mu.Lock()
-			iw.currentImage = nextImage
-			iw.mu.Unlock()
-
-			// Notificar callback de deleção
-			if iw.OnImageDeleted != nil {
-				var relPath string
-				if nextImage != nil {
-					rel, err := filepath.Rel(iw.dir, nextImage.Path)
-					if err != nil {
-						relPath = filepath.Base(nextImage.Path)
-					} else {
-						relPath = rel
-					}
-				}
-				iw.OnImageDeleted(relPath)
-			}
-		}
+		iw.removeImage(path)
 		return
 	}
 
@@ -317,7 +695,7 @@ func (iw *ImageWatcher) handleEvent(event fsnotify.Event) {
 		return
 	}
 
-	if !IsImageFile(path) {
+	if !iw.Accepts(path) {
 		return
 	}
 
@@ -327,44 +705,173 @@ func (iw *ImageWatcher) handleEvent(event fsnotify.Event) {
 		return
 	}
 
-	// Atualizar imagem atual
-	newImage := &ImageInfo{
-		Path:    path,
-		ModTime: info.ModTime(),
+	// Aguardar o arquivo estabilizar antes de publicar
+	if iw.settle > 0 {
+		iw.schedulePending(path, info)
+		return
+	}
+
+	if iw.verify && !iw.isComplete(path) {
+		return
 	}
 
+	iw.publish(path, info)
+}
+
+// publish torna path a imagem atual e notifica os callbacks
+func (iw *ImageWatcher) publish(path string, info os.FileInfo) {
+	isCurrent, recentChanged := iw.addImage(iw.newImageInfo(path, info))
+
+	// Notificar callback
+	if isCurrent && iw.OnNewImage != nil {
+		iw.OnNewImage(iw.rel(path))
+	}
+	if recentChanged {
+		iw.notifyRecentChanged()
+	}
+}
+
+// addImage coloca uma imagem nova ou reescrita no índice e na lista de
+// recentes. Ela só passa a ser a atual se for a mais recente segundo
+// --order, como seria após recarregar: um arquivo antigo extraído com unzip,
+// por exemplo, entra na ordem pelo mtime. Retorna se img é a imagem atual e
+// se a lista de recentes mudou.
+func (iw *ImageWatcher) addImage(img *ImageInfo) (isCurrent, recentChanged bool) {
 	iw.mu.Lock()
-	iw.currentImage = newImage
+	defer iw.mu.Unlock()
 
-	// Atualizar lista de imagens recentes se slideshow está ativado
-	if iw.maxRecent > 0 {
-		// Adicionar nova imagem no início
-		iw.recentImages = append([]*ImageInfo{newImage}, iw.recentImages...)
+	iw.index.upsert(img)
+	iw.currentImage = iw.index.top()
+	return iw.currentImage.Path == img.Path, iw.recent.insert(img, iw.order.newer)
+}
+
+// removeImage tira path do índice após deleção ou rename, completa a lista de
+// recentes e, se path era a imagem atual, troca para a próxima mais recente
+func (iw *ImageWatcher) removeImage(path string) {
+	iw.mu.Lock()
+	iw.index.remove(path)
+	wasCurrent := iw.currentImage != nil && iw.currentImage.Path == path
+	recentChanged := iw.recent.remove(path)
+	iw.mu.Unlock()
 
-		// Manter apenas maxRecent imagens
-		if len(iw.recentImages) > iw.maxRecent {
-			iw.recentImages = iw.recentImages[:iw.maxRecent]
+	if recentChanged {
+		iw.backfillRecent()
+	}
+
+	if wasCurrent {
+		// Encontrar próxima imagem mais recente
+		nextImage := iw.findMostRecentImage()
+
+		iw.mu.Lock()
+		iw.currentImage = nextImage
+		iw.mu.Unlock()
+
+		// Notificar callback de deleção
+		if iw.OnImageDeleted != nil {
+			var relPath string
+			if nextImage != nil {
+				relPath = iw.rel(nextImage.Path)
+			}
+			iw.OnImageDeleted(relPath)
 		}
 	}
+
+	if recentChanged {
+		iw.notifyRecentChanged()
+	}
+}
+
+// handleDirCreated passa a monitorar um novo subdiretório e publica a imagem
+// mais recente que já estiver dentro dele (ex: pasta movida para a árvore)
+func (iw *ImageWatcher) handleDirCreated(dir string) {
+	if !iw.shouldDescend(dir) {
+		return
+	}
+	if err := iw.addWatchTree(dir); err != nil {
+		// Pasta removida logo após criada não é problema; limite de watches é
+		if !errors.Is(err, fs.ErrNotExist) {
+			iw.handleError(err)
+		}
+		return
+	}
+
+	var found []*ImageInfo
+	iw.walk(dir, func(img *ImageInfo) {
+		found = append(found, img)
+	})
+
+	// Um diretório movido para dentro pode trazer arquivos ainda sendo escritos
+	found = iw.stage(found)
+	if len(found) == 0 {
+		return
+	}
+
+	// Adicionar da mais antiga para a mais recente: a atual, se alguma for, é a última
+	sort.Slice(found, func(i, j int) bool {
+		return iw.order.newer(found[j], found[i])
+	})
+	var current *ImageInfo
+	recentChanged := false
+	for _, img := range found {
+		isCurrent, changed := iw.addImage(img)
+		if isCurrent {
+			current = img
+		}
+		recentChanged = recentChanged || changed
+	}
+
+	if current != nil && iw.OnNewImage != nil {
+		iw.OnNewImage(iw.rel(current.Path))
+	}
+	if recentChanged {
+		iw.notifyRecentChanged()
+	}
+}
+
+// handleDirRemoved deixa de monitorar um subdiretório removido ou movido e
+// troca a imagem atual caso ela estivesse dentro dele
+func (iw *ImageWatcher) handleDirRemoved(dir string) {
+	iw.removeWatchTree(dir)
+
+	iw.mu.Lock()
+	iw.index.removeUnder(dir)
+	recentChanged := iw.recent.removeUnder(dir)
+	affected := iw.currentImage != nil && isUnder(iw.currentImage.Path, dir)
 	iw.mu.Unlock()
 
-	// Notificar callback
-	if iw.OnNewImage != nil {
-		rel, err := filepath.Rel(iw.dir, path)
-		if err != nil {
-			rel = filepath.Base(path)
+	if recentChanged {
+		iw.backfillRecent()
+	}
+
+	if affected {
+		nextImage := iw.findMostRecentImage()
+
+		iw.mu.Lock()
+		iw.currentImage = nextImage
+		iw.mu.Unlock()
+
+		if iw.OnImageDeleted != nil {
+			var relPath string
+			if nextImage != nil {
+				relPath = iw.rel(nextImage.Path)
+			}
+			iw.OnImageDeleted(relPath)
 		}
-		iw.OnNewImage(rel)
+	}
+
+	if recentChanged {
+		iw.notifyRecentChanged()
 	}
 }
 
 // Stop para o monitoramento
 func (iw *ImageWatcher) Stop() error {
 	close(iw.done)
-	return iw.watcher.Close()
+	iw.cancelAllPending()
+	return iw.backend.Close()
 }
 
-// Dir retorna o diretório monitorado
+// Dir retorna o (primeiro) diretório monitorado
 func (iw *ImageWatcher) Dir() string {
-	return iw.dir
+	return iw.sources[0].dir()
 }
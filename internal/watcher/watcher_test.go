@@ -25,6 +25,13 @@ func TestIsImageFile(t *testing.T) {
 		{"arquivo.txt", false},
 		{"doc.pdf", false},
 		{"arquivo", false},
+		{"foto.png.part", false},
+		{"foto.png.crdownload", false},
+		{".foto.png.swp", false},
+		{"~foto.png", false},
+		{".tmp-123.png", false},
+		{".~lock.png", false},
+		{".cover.png", true}, // Ocultos comuns não são temporários
 	}
 
 	for _, tt := range tests {
@@ -492,3 +499,209 @@ func TestImageWatcher_DetectLastImageDeletion(t *testing.T) {
 		t.Error("Timeout aguardando detecção de deleção de última imagem")
 	}
 }
+
+func TestScanExisting_Recursive(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// raiz/a.png, raiz/d1/b.png, raiz/d1/d2/c.png (mais recente)
+	deep := filepath.Join(tmpDir, "d1", "d2")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{
+		filepath.Join(tmpDir, "a.png"),
+		filepath.Join(tmpDir, "d1", "b.png"),
+		filepath.Join(deep, "c.png"),
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	tests := []struct {
+		name      string
+		opts      Options
+		wantCount int
+		wantRel   string
+	}{
+		{"não recursivo", Options{}, 1, "a.png"},
+		{"recursivo ilimitado", Options{Recursive: true}, 3, "d1/d2/c.png"},
+		{"profundidade 1", Options{Recursive: true, MaxDepth: 1}, 2, "d1/b.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewWithOptions(tmpDir, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Stop()
+
+			count, _, err := w.ScanExisting()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("ScanExisting() count = %d, want %d", count, tt.wantCount)
+			}
+			if got := w.CurrentImageRelative(); got != tt.wantRel {
+				t.Errorf("CurrentImageRelative() = %q, want %q", got, tt.wantRel)
+			}
+		})
+	}
+}
+
+func TestImageWatcher_Recursive_NewSubdirectory(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	w, err := NewWithOptions(tmpDir, Options{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	detected := make(chan string, 10)
+	w.OnNewImage = func(path string) {
+		detected <- path
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// Criar subpastas aninhadas e uma imagem dentro delas
+	sub := filepath.Join(tmpDir, "2026-10-16", "run-3")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(sub, "frame.png"), []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "2026-10-16/run-3/frame.png"
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case path := <-detected:
+			if path == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Timeout aguardando detecção de %q", want)
+		}
+	}
+}
+
+func TestImageWatcher_Recursive_SubdirectoryRemoved(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "sidelook_test_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	img1 := filepath.Join(tmpDir, "img1.png")
+	if err := os.WriteFile(img1, []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	sub := filepath.Join(tmpDir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "img2.png"), []byte{0x89, 0x50, 0x4E, 0x47}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWithOptions(tmpDir, Options{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if _, _, err := w.ScanExisting(); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.CurrentImageRelative(); got != "sub/img2.png" {
+		t.Fatalf("CurrentImageRelative() = %q, want sub/img2.png", got)
+	}
+
+	deleted := make(chan string, 10)
+	w.OnImageDeleted = func(path string) {
+		deleted <- path
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case path := <-deleted:
+		if path != "img1.png" {
+			t.Errorf("OnImageDeleted path = %q, want img1.png", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout aguardando remoção do subdiretório")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if w.isWatchedDir(sub) {
+		t.Error("subdiretório removido ainda está sendo monitorado")
+	}
+}
+
+func TestImageWatcher_Describe(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "render.gif")
+	// GIF 3x2 com tabela de cores global de 2 entradas e 1 quadro
+	gif := []byte("GIF89a\x03\x00\x02\x00\x80\x00\x00\x00\x00\x00\xff\xff\xff" +
+		",\x00\x00\x00\x00\x03\x00\x02\x00\x00\x02\x02\x44\x01\x00;")
+	os.WriteFile(path, gif, 0644)
+
+	w, err := New(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := w.ScanExisting(); err != nil {
+		t.Fatal(err)
+	}
+
+	img, ok := w.Describe("render.gif")
+	if !ok || img.Header == nil {
+		t.Fatalf("Describe() = %+v, %v", img, ok)
+	}
+	if h := img.Header; h.Format != "gif" || h.Width != 3 || h.Height != 2 || h.Frames != 1 || img.Size != int64(len(gif)) {
+		t.Errorf("Describe() = %+v, header %+v", img, h)
+	}
+
+	// Cabeçalho fica no índice; ImageInfo já publicado não muda
+	if again, _ := w.Describe("render.gif"); again.Header != img.Header {
+		t.Error("segundo Describe() deveria reutilizar o cabeçalho do índice")
+	}
+	if current := w.CurrentImage(); current.Header != nil {
+		t.Error("Describe() não deveria modificar o ImageInfo publicado")
+	}
+
+	if _, ok := w.Describe("missing.gif"); ok {
+		t.Error("Describe(inexistente) deveria retornar false")
+	}
+}